@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -24,29 +25,34 @@ import (
 	"os/signal"
 	"ruleGoProject/config"
 	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
 	"ruleGoProject/internal/router"
 	"ruleGoProject/internal/service"
+	"strings"
 	"syscall"
+	"time"
 
 	endpointApi "github.com/rulego/rulego/api/types/endpoint"
 	"github.com/rulego/rulego/endpoint/rest"
 	"gopkg.in/ini.v1"
 )
 
-const (
-	version = "1.0.0"
-)
-
 var (
 	//是否是查询版本
 	ver bool
 	//配置文件
 	configFile string
+	//把运行快照文件迁移到数据库后退出
+	migrateRuns bool
+	//迁移成功后删除运行快照文件
+	removeMigrated bool
 )
 
 func init() {
 	flag.StringVar(&configFile, "c", "", "配置文件")
 	flag.BoolVar(&ver, "v", false, "打印版本")
+	flag.BoolVar(&migrateRuns, "migrate_runs", false, "把运行快照文件迁移到数据库后退出")
+	flag.BoolVar(&removeMigrated, "remove_migrated", false, "迁移成功后删除运行快照文件，配合-migrate_runs使用")
 }
 
 func main() {
@@ -54,7 +60,7 @@ func main() {
 	flag.Parse()
 
 	if ver {
-		fmt.Printf("RuleGo-Ci Server v%s", version)
+		fmt.Printf("RuleGo-Ci Server v%s", constants.Version)
 		os.Exit(0)
 	}
 
@@ -70,12 +76,22 @@ func main() {
 		if section, err := cfg.GetSection("global"); err == nil {
 			c.Global = section.KeysHash()
 		}
+		if err := loadUserRetention(cfg, &c); err != nil {
+			log.Fatal("error:", err)
+		}
 	}
+	//环境变量覆盖配置
+	config.LoadEnv(&c)
 	config.Set(c)
 	logger.Set(initLogger(c))
 
 	log.Printf("use config file=%s \n", configFile)
 
+	if migrateRuns {
+		runMigrateRuns(c)
+		return
+	}
+
 	//初始化服务
 	if err := service.Setup(c); err != nil {
 		log.Fatal("error:", err)
@@ -89,7 +105,12 @@ func main() {
 	restEndpoint := router.NewRestServe(c)
 	restEndpoint.OnEvent = func(eventName string, params ...interface{}) {
 		if eventName == endpointApi.EventInitServer {
-			wsEndpoint := router.NewWebsocketServe(c, params[0].(*rest.Rest))
+			restServer := params[0].(*rest.Rest)
+			//记录http请求耗时
+			router.HttpMetrics(restServer)
+			//创建http请求span
+			router.HttpTracing(restServer)
+			wsEndpoint := router.NewWebsocketServe(c, restServer)
 			if err := wsEndpoint.Start(); err != nil {
 				log.Fatal("error:", err)
 			}
@@ -99,6 +120,8 @@ func main() {
 	if err := restEndpoint.Start(); err != nil {
 		log.Fatal("error:", err)
 	}
+	//单独监听的指标服务
+	metricsServer := router.MetricsServe(c)
 
 	sigs := make(chan os.Signal, 1)
 	// 监听系统信号，包括中断信号和终止信号
@@ -112,11 +135,50 @@ func main() {
 		if mqttEndpoint != nil {
 			mqttEndpoint.Destroy()
 		}
+		if metricsServer != nil {
+			_ = metricsServer.Close()
+		}
+		//写入剩余的节点调试数据
+		_ = service.DebugServiceImpl.Close()
+		//导出剩余的span
+		if service.TracingImpl != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = service.TracingImpl.Shutdown(ctx)
+			cancel()
+		}
 		log.Println("stopped server")
 		os.Exit(0)
 	}
 }
 
+// runMigrateRuns 把运行快照文件迁移到数据库
+func runMigrateRuns(c config.Config) {
+	if err := service.StartDB(c); err != nil {
+		log.Fatal("error:", err)
+	}
+	result, err := service.MigrateRunLogFiles(c, removeMigrated)
+	if err != nil {
+		log.Fatal("error:", err)
+	}
+	log.Printf("migrate run logs finished, migrated=%d skipped=%d failed=%d \n", result.Migrated, result.Skipped, result.Failed)
+}
+
+// loadUserRetention 加载[retention.{username}]用户运行快照保留策略，未配置的项继承[retention]
+func loadUserRetention(cfg *ini.File, c *config.Config) error {
+	const prefix = "retention."
+	for _, section := range cfg.Section("retention").ChildSections() {
+		var retention config.Retention
+		if err := section.MapTo(&retention); err != nil {
+			return err
+		}
+		if c.UserRetention == nil {
+			c.UserRetention = make(map[string]config.Retention)
+		}
+		c.UserRetention[strings.TrimPrefix(section.Name(), prefix)] = retention
+	}
+	return nil
+}
+
 // 初始化日志记录器
 func initLogger(c config.Config) *log.Logger {
 	if c.LogFile == "" {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"time"
 
 	"github.com/rulego/rulego/api/types"
@@ -29,14 +30,66 @@ type Config struct {
 	Server string `ini:"server"`
 	// DefaultUsername 你们访问时候，默认用户名
 	DefaultUsername string `ini:"default_username"`
+	// AdminPassword 默认用户初始密码，用户文件中没有默认用户时使用该密码创建，已存在则忽略
+	AdminPassword string `ini:"admin_password"`
+	// AllowAnonymous 是否允许匿名访问，允许时未携带token的请求使用默认用户名访问，不具有管理员权限
+	AllowAnonymous bool `ini:"allow_anonymous"`
+	// JwtSecretKey jwt签名秘钥，为空则启动时随机生成，重启后已签发的token失效
+	JwtSecretKey string `ini:"jwt_secret_key"`
+	// JwtExpireTime jwt token有效期，默认2h
+	JwtExpireTime time.Duration `ini:"jwt_expire_time"`
+	// JwtRefreshExpireTime jwt刷新token有效期，默认168h
+	JwtRefreshExpireTime time.Duration `ini:"jwt_refresh_expire_time"`
+	// JwtIssuer jwt签发者
+	JwtIssuer string `ini:"jwt_issuer"`
 	//是否把节点调试日志打印到日志文件
 	Debug bool `ini:"debug"`
 	//最大节点日志大小，默认40
 	MaxNodeLogSize int `ini:"max_node_log_size"`
+	// DebugQueueSize 每个websocket客户端调试数据推送队列大小，队列满丢弃新数据，默认1000
+	DebugQueueSize int `ini:"debug_queue_size"`
 	//静态文件路径映射，例如:/ui/*filepath=/home/demo/dist,/images/*filepath=/home/demo/dist/images
 	ResourceMapping string `ini:"resource_mapping"`
 	// Mqtt mqtt配置
 	Mqtt Mqtt `ini:"mqtt"`
+	// RuleStore 规则链存储方式 file/sql/memory，默认sql
+	RuleStore string `ini:"rule_store"`
+	// Database 数据库配置
+	Database Database `ini:"database"`
+	// SnapshotStore 运行快照存储方式 file/sql，默认sql
+	SnapshotStore string `ini:"snapshot_store"`
+	// DebugStore 节点调试数据存储方式 memory/bolt/sql，默认memory
+	DebugStore string `ini:"debug_store"`
+	// DedupeStore 消息去重存储方式 memory/sql，默认memory
+	DedupeStore string `ini:"dedupe_store"`
+	// DedupeLease 消息去重处理中记录的租约时间，超过后相同msgId可以再次处理，不超过幂等窗口，默认5m
+	DedupeLease time.Duration `ini:"dedupe_lease"`
+	// AsyncWorkers 异步执行并发数，默认10
+	AsyncWorkers int `ini:"async_workers"`
+	// AsyncQueueSize 异步执行等待队列大小，默认1000
+	AsyncQueueSize int `ini:"async_queue_size"`
+	// AsyncRunTtl 异步执行结果保留时间，默认1h
+	AsyncRunTtl time.Duration `ini:"async_run_ttl"`
+	// CallbackAllowPrivate 异步执行回调地址是否允许解析到本机、内网等地址，默认false
+	CallbackAllowPrivate bool `ini:"callback_allow_private"`
+	// CallbackAllowHosts 异步执行回调允许访问内网的主机白名单，多个与`,`号隔开
+	CallbackAllowHosts string `ini:"callback_allow_hosts"`
+	// Metrics 是否开启prometheus指标接口/metrics，标签包含用户名和规则链ID，默认关闭
+	Metrics bool `ini:"metrics"`
+	// MetricsServer 指标接口单独监听的地址，例如：127.0.0.1:9100，不配置则使用server地址
+	MetricsServer string `ini:"metrics_server"`
+	// MetricsMaxLabelValues 指标每个标签(用户、规则链、节点、消息类型、路由等)最多取值个数，超过后使用other，默认100
+	MetricsMaxLabelValues int `ini:"metrics_max_label_values"`
+	// TraceExporter opentelemetry链路追踪导出方式 none/stdout/otlp，默认none不开启
+	TraceExporter string `ini:"trace_exporter"`
+	// TraceEndpoint otlp http接收地址，没有路径则使用/v1/traces，默认http://127.0.0.1:4318
+	TraceEndpoint string `ini:"trace_endpoint"`
+	// TraceServiceName 链路追踪服务名称，默认rulego-server
+	TraceServiceName string `ini:"trace_service_name"`
+	// Retention 运行快照和节点调试数据保留策略，默认不限制
+	Retention Retention `ini:"retention"`
+	// UserRetention 用户运行快照保留策略，用户名->保留策略，对应配置文件[retention.{username}]
+	UserRetention map[string]Retention `ini:"-"`
 	// 全局自定义配置，组件可以通过${global.xxx}方式取值
 	Global types.Metadata `ini:"global"`
 }
@@ -64,18 +117,113 @@ type Mqtt struct {
 	ToChainId string `ini:"to_chain_id"`
 }
 
+// Retention 运行快照和节点调试数据保留策略，0表示不限制，用户策略中0表示继承全局策略，-1表示不限制
+type Retention struct {
+	//最长保留时间，例如：720h
+	MaxAge time.Duration `ini:"max_age"`
+	//每个规则链最多保留条数
+	MaxCount int `ini:"max_count"`
+	//每个规则链最多占用字节数
+	MaxBytes int64 `ini:"max_bytes"`
+	//清理任务执行间隔，默认10m，只在[retention]中生效
+	Interval time.Duration `ini:"interval"`
+}
+
+// Database 数据库配置
+type Database struct {
+	//数据库驱动 postgres/mysql/sqlite，默认postgres
+	Driver string `ini:"driver"`
+	//数据库地址
+	Host string `ini:"host"`
+	//数据库端口，默认5432
+	Port int32 `ini:"port"`
+	//用户名，可以通过环境变量RULEGO_DB_USER覆盖
+	User string `ini:"user"`
+	//密码，可以通过环境变量RULEGO_DB_PASSWORD覆盖
+	Password string `ini:"password"`
+	//数据库名称，sqlite为数据库文件路径，相对路径则存放在数据目录，默认rulego.db
+	DBName string `ini:"dbname"`
+	//ssl模式，默认disable
+	SSLMode string `ini:"sslmode"`
+	//时区，默认Asia/Shanghai
+	TimeZone string `ini:"timezone"`
+	//最大空闲连接数
+	MaxIdleConns int `ini:"max_idle_conns"`
+	//最大连接数
+	MaxOpenConns int `ini:"max_open_conns"`
+	//连接最大存活时间
+	ConnMaxLifeTime time.Duration `ini:"conn_max_life_time"`
+	//连接最大空闲时间
+	ConnMaxIdleTime time.Duration `ini:"conn_max_idle_time"`
+	//日志等级 silent/error/warn/info
+	LogMode string `ini:"log_mode"`
+}
+
+// 环境变量，用于覆盖配置文件中的敏感信息
+const (
+	EnvDBHost        = "RULEGO_DB_HOST"
+	EnvDBUser        = "RULEGO_DB_USER"
+	EnvDBPassword    = "RULEGO_DB_PASSWORD"
+	EnvJwtSecretKey  = "RULEGO_JWT_SECRET_KEY"
+	EnvAdminPassword = "RULEGO_ADMIN_PASSWORD"
+)
+
+// LoadEnv 使用环境变量覆盖配置
+func LoadEnv(c *Config) {
+	if v := os.Getenv(EnvDBHost); v != "" {
+		c.Database.Host = v
+	}
+	if v := os.Getenv(EnvDBUser); v != "" {
+		c.Database.User = v
+	}
+	if v := os.Getenv(EnvDBPassword); v != "" {
+		c.Database.Password = v
+	}
+	if v := os.Getenv(EnvJwtSecretKey); v != "" {
+		c.JwtSecretKey = v
+	}
+	if v := os.Getenv(EnvAdminPassword); v != "" {
+		c.AdminPassword = v
+	}
+}
+
 // DefaultConfig 默认配置
 var DefaultConfig = Config{
 	DataDir: "./data",
 	// LogFile:         "./rulego.log",
-	CmdWhiteList:    "cp,scp,mvn,npm,yarn,git,make,cmake,docker,kubectl,helm,ansible,puppet,pytest,python,python3,pip,go,java,dotnet,gcc,g++,ctest",
-	LoadLuaLibs:     "true",
-	Server:          ":1234",
-	DefaultUsername: "admin",
-	MaxNodeLogSize:  40,
+	CmdWhiteList:          "cp,scp,mvn,npm,yarn,git,make,cmake,docker,kubectl,helm,ansible,puppet,pytest,python,python3,pip,go,java,dotnet,gcc,g++,ctest",
+	LoadLuaLibs:           "true",
+	Server:                ":1234",
+	DefaultUsername:       "admin",
+	MaxNodeLogSize:        40,
+	DebugQueueSize:        1000,
+	JwtExpireTime:         2 * time.Hour,
+	JwtRefreshExpireTime:  7 * 24 * time.Hour,
+	JwtIssuer:             "rulego",
+	DedupeLease:           5 * time.Minute,
+	AsyncWorkers:          10,
+	AsyncQueueSize:        1000,
+	AsyncRunTtl:           time.Hour,
+	Metrics:               false,
+	MetricsMaxLabelValues: 100,
+	TraceExporter:         "none",
+	TraceEndpoint:         "http://127.0.0.1:4318",
+	TraceServiceName:      "rulego-server",
+	Retention: Retention{
+		Interval: 10 * time.Minute,
+	},
 	Mqtt: Mqtt{
 		Server:       "172.0.0.1:1883",
 		CleanSession: true,
 		ToChainId:    "chain_call_rest_api",
 	},
+	Database: Database{
+		//默认使用内嵌sqlite，不依赖外部服务
+		Driver:          "sqlite",
+		DBName:          "rulego.db",
+		MaxIdleConns:    1,
+		MaxOpenConns:    1,
+		ConnMaxLifeTime: time.Hour,
+		LogMode:         "warn",
+	},
 }
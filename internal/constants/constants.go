@@ -1,5 +1,8 @@
 package constants
 
+// Version 服务版本
+const Version = "1.0.0"
+
 const (
 	// DirWorkflows 工作流目录
 	DirWorkflows     = "workflows"
@@ -7,9 +10,11 @@ const (
 	DirWorkflowsRule = "rules"
 )
 const (
-	KeyChainId         = "chainId"
-	KeyNodeId          = "nodeId"
-	KeyUsername        = "username"
+	KeyChainId  = "chainId"
+	KeyNodeId   = "nodeId"
+	KeyUsername = "username"
+	// KeyUser 用户管理接口操作的目标用户
+	KeyUser            = "user"
 	KeyClientId        = "clientId"
 	KeyVarType         = "varType"
 	KeyPageSize        = "pageSize"
@@ -17,6 +22,37 @@ const (
 	KeyId              = "id"
 	KeyWebhookSecret   = "webhookSecret"
 	KeyIntegrationType = "integrationType"
+	// KeyMessage 保存规则链时的修改说明
+	KeyMessage = "message"
+	// KeyVersion 规则链历史版本号
+	KeyVersion = "version"
+	KeyFrom    = "from"
+	KeyTo      = "to"
+	// 运行快照查询条件
+	KeyStartTime = "startTime"
+	KeyEndTime   = "endTime"
+	KeyStatus    = "status"
+	KeyMsgType   = "msgType"
+	KeyMsgId     = "msgId"
+	// KeyRunId 异步执行ID或者运行快照ID
+	KeyRunId   = "runId"
+	KeyKeyword = "keyword"
+	KeySort    = "sort"
+	KeyOrder   = "order"
+	// KeyReplayOf 重放消息元数据中的原运行快照ID
+	KeyReplayOf = "replayOf"
+	// KeyName 测试用例名称，多个用逗号分隔
+	KeyName = "name"
+	// KeyMode 执行模式，test表示测试模式，模拟节点不执行原组件
+	KeyMode = "mode"
+	// KeyCallback 异步执行完成后回调地址
+	KeyCallback      = "callback"
+	KeyAuthorization = "Authorization"
+	KeyToken         = "token"
+	// KeyAnonymous 消息元数据中标记请求是否是匿名访问
+	KeyAnonymous = "anonymous"
+	// KeyBearerPrefix Authorization请求头token前缀
+	KeyBearerPrefix = "Bearer "
 	// KeyWorkDir 工作目录
 	KeyWorkDir = "workDir"
 )
@@ -24,3 +60,8 @@ const (
 const (
 	RuleChainFileSuffix = ".json"
 )
+
+const (
+	// ModeTest 测试模式
+	ModeTest = "test"
+)
@@ -3,7 +3,15 @@ package constants
 import "errors"
 
 var (
-	ErrNotFound        = errors.New("not found")
-	ErrUsernameEmpty   = errors.New("username cannot empty")
-	ErrWorkflowIdEmpty = errors.New("workflowId cannot empty")
+	ErrNotFound              = errors.New("not found")
+	ErrUsernameEmpty         = errors.New("username cannot empty")
+	ErrWorkflowIdEmpty       = errors.New("workflowId cannot empty")
+	ErrUnauthorized          = errors.New("unauthorized")
+	ErrUsernameOrPasswordErr = errors.New("username or password incorrect")
+	ErrPasswordEmpty         = errors.New("password cannot empty")
+	ErrUserExists            = errors.New("user already exists")
+	ErrForbidden             = errors.New("forbidden")
+	ErrRunQueueFull          = errors.New("run queue is full")
+	ErrMsgDuplicate          = errors.New("duplicate msgId")
+	ErrMsgProcessing         = errors.New("msgId is processing")
 )
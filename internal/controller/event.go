@@ -1,45 +1,76 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
 	"ruleGoProject/internal/service"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/rulego/rulego/api/types"
 	endpointApi "github.com/rulego/rulego/api/types/endpoint"
 	"github.com/rulego/rulego/endpoint"
 	"github.com/rulego/rulego/utils/json"
 )
 
+// maxDebugPageSize 节点调试数据每页最大条数
+const maxDebugPageSize = 1000
+
 // GetDebugDataRouter 创建获取节点调试数据路由
+// 支持按规则链、节点、消息ID以及时间范围查询，默认按时间降序，每页最多1000条
 func GetDebugDataRouter(url string) endpointApi.Router {
 	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
 		msg := exchange.In.GetMsg()
-		chainId := msg.Metadata.GetValue(constants.KeyChainId)
-		nodeId := msg.Metadata.GetValue(constants.KeyNodeId)
 		username := msg.Metadata.GetValue(constants.KeyUsername)
-		var current = 1
-		var pageSize = 20
+		var query = model.DebugDataQuery{
+			ChainId: msg.Metadata.GetValue(constants.KeyChainId),
+			NodeId:  msg.Metadata.GetValue(constants.KeyNodeId),
+			MsgId:   exchange.In.GetParam(constants.KeyMsgId),
+			Current: 1,
+			Size:    20,
+		}
 		currentStr := msg.Metadata.GetValue(constants.KeyCurrent)
-		if i, err := strconv.Atoi(currentStr); err == nil {
-			current = i
+		if i, err := strconv.Atoi(currentStr); err == nil && i > 0 {
+			query.Current = i
 		}
 		pageSizeStr := msg.Metadata.GetValue(constants.KeyPageSize)
-		if i, err := strconv.Atoi(pageSizeStr); err == nil {
-			pageSize = i
+		if i, err := strconv.Atoi(pageSizeStr); err == nil && i > 0 {
+			query.Size = i
 		}
-		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-			page := s.DebugData().GetToPage(chainId, nodeId, pageSize, current)
-			if v, err := json.Marshal(page); err != nil {
-				exchange.Out.SetStatusCode(http.StatusInternalServerError)
-				exchange.Out.SetBody([]byte(err.Error()))
-			} else {
-				exchange.Out.SetBody(v)
-			}
-		} else {
+		if query.Size > maxDebugPageSize {
+			query.Size = maxDebugPageSize
+		}
+		var err error
+		if query.StartTime, err = parseTime(exchange.In.GetParam(constants.KeyStartTime)); err == nil {
+			query.EndTime, err = parseTime(exchange.In.GetParam(constants.KeyEndTime))
+		}
+		if err == nil {
+			err = parseOrder(exchange, &query.Asc)
+		}
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if _, ok := service.UserRuleEngineServiceImpl.Get(username); !ok {
 			return userNotFound(username, exchange)
 		}
+		items, total, err := service.DebugServiceImpl.List(username, query)
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		page := model.DebugDataPage{
+			PageSize: query.Size,
+			Current:  query.Current,
+			Total:    total,
+			Items:    items,
+		}
+		writeJson(page, exchange)
 		return true
 	}).End()
 }
@@ -49,9 +80,15 @@ func WsNodeLogRouter(url string) endpointApi.Router {
 		msg := exchange.In.GetMsg()
 		username := msg.Metadata.GetValue(constants.KeyUsername)
 		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-			s.AddOnDebugObserver(exchange.In.GetParam(constants.KeyClientId), func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error) {
-				exchange.Out.SetBody([]byte(msg.Data))
-			})
+			clientId := exchange.In.GetParam(constants.KeyClientId)
+			//调试命令交给客户端的调试会话处理
+			var cmd service.DebugCommand
+			if json.Unmarshal([]byte(msg.Data), &cmd) == nil && cmd.Type != "" {
+				if session, ok := s.GetDebugSession(clientId); ok {
+					session.Handle(cmd)
+				}
+			}
+			//其他消息忽略，订阅者在连接建立时创建，通过subscribe命令修改过滤条件
 		}
 		return true
 	}).End()
@@ -65,17 +102,14 @@ func GetRunsRouter(url string) endpointApi.Router {
 		username := msg.Metadata.GetValue(constants.KeyUsername)
 		var result interface{}
 		if id == "" {
-			var current = 1
-			var pageSize = 20
-			currentStr := msg.Metadata.GetValue(constants.KeyCurrent)
-			if i, err := strconv.Atoi(currentStr); err == nil {
-				current = i
-			}
-			pageSizeStr := msg.Metadata.GetValue(constants.KeyPageSize)
-			if i, err := strconv.Atoi(pageSizeStr); err == nil {
-				pageSize = i
+			query, err := parseRunQuery(exchange)
+			if err != nil {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte(err.Error()))
+				return false
 			}
-			if v, total, err := service.EventServiceImpl.List(username, chainId, current, pageSize); err != nil {
+			query.ChainId = chainId
+			if v, total, err := service.EventServiceImpl.List(username, query); err != nil {
 				exchange.Out.SetStatusCode(http.StatusNotFound)
 				exchange.Out.SetBody([]byte(err.Error()))
 				return false
@@ -112,10 +146,172 @@ func DeleteRunsRouter(url string) endpointApi.Router {
 		id := msg.Metadata.GetValue(constants.KeyId)
 		username := msg.Metadata.GetValue(constants.KeyUsername)
 
-		if err := service.EventServiceImpl.Delete(username, chainId, id); err != nil {
+		if err := service.EventServiceImpl.Delete(username, chainId, id); errors.Is(err, constants.ErrNotFound) {
+			exchange.Out.SetStatusCode(http.StatusNotFound)
+			exchange.Out.SetBody([]byte(err.Error()))
+		} else if err != nil {
 			exchange.Out.SetStatusCode(http.StatusInternalServerError)
 			exchange.Out.SetBody([]byte(err.Error()))
 		}
 		return true
 	}).End()
 }
+
+// ReplayRunRouter 创建重放运行快照路由，使用快照的输入消息重新执行规则链，并等待执行结束
+// 参数chainId可选，version指定历史版本，nodeId指定开始节点
+func ReplayRunRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		id := msg.Metadata.GetValue(constants.KeyId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		opts := service.ReplayOptions{
+			ChainId: exchange.In.GetParam(constants.KeyChainId),
+			NodeId:  exchange.In.GetParam(constants.KeyNodeId),
+		}
+		if v := exchange.In.GetParam(constants.KeyVersion); v != "" {
+			version, err := strconv.Atoi(v)
+			if err != nil || version <= 0 {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte("version must be a positive integer"))
+				return false
+			}
+			opts.Version = version
+		}
+		s, ok := service.UserRuleEngineServiceImpl.Get(username)
+		if !ok {
+			return userNotFound(username, exchange)
+		}
+		if result, err := s.Replay(id, opts); err != nil {
+			return runError(err, exchange)
+		} else {
+			writeJson(result, exchange)
+		}
+		return true
+	}).End()
+}
+
+// RunDiffRouter 创建逐节点比较两次运行路由，参数from、to为运行快照ID，chainId可选
+func RunDiffRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		chainId := exchange.In.GetParam(constants.KeyChainId)
+		from := exchange.In.GetParam(constants.KeyFrom)
+		to := exchange.In.GetParam(constants.KeyTo)
+		if from == "" || to == "" {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte("from and to are required"))
+			return false
+		}
+		if result, err := service.EventServiceImpl.Diff(username, chainId, from, to); err != nil {
+			exchange.Out.SetStatusCode(http.StatusNotFound)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		} else {
+			writeJson(result, exchange)
+		}
+		return true
+	}).End()
+}
+
+// TraceRouter 创建消息执行轨迹路由，参数msgId为消息ID，或者runId为异步执行ID/运行快照ID
+func TraceRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		msgId := exchange.In.GetParam(constants.KeyMsgId)
+		runId := exchange.In.GetParam(constants.KeyRunId)
+		if msgId == "" && runId == "" {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte("msgId or runId is required"))
+			return false
+		}
+		s, ok := service.UserRuleEngineServiceImpl.Get(username)
+		if !ok {
+			return userNotFound(username, exchange)
+		}
+		var result service.Trace
+		var err error
+		if msgId != "" {
+			result, err = s.Trace(msgId)
+		} else {
+			result, err = s.TraceRun(runId)
+		}
+		if err != nil {
+			return runError(err, exchange)
+		}
+		writeJson(result, exchange)
+		return true
+	}).End()
+}
+
+// maxRunPageSize 运行快照每页最大条数
+const maxRunPageSize = 1000
+
+// parseRunQuery 解析运行快照查询条件
+// 时间支持毫秒时间戳、RFC3339格式和2006-01-02格式，sort支持startTs/endTs/duration，order支持asc/desc
+func parseRunQuery(exchange *endpointApi.Exchange) (model.RunSnapshotQuery, error) {
+	var query = model.RunSnapshotQuery{
+		Current:  1,
+		Size:     20,
+		Status:   exchange.In.GetParam(constants.KeyStatus),
+		MsgType:  exchange.In.GetParam(constants.KeyMsgType),
+		MsgId:    exchange.In.GetParam(constants.KeyMsgId),
+		NodeId:   exchange.In.GetParam(constants.KeyNodeId),
+		Keyword:  exchange.In.GetParam(constants.KeyKeyword),
+		ReplayOf: exchange.In.GetParam(constants.KeyReplayOf),
+		Sort:     exchange.In.GetParam(constants.KeySort),
+	}
+	if i, err := strconv.Atoi(exchange.In.GetParam(constants.KeyCurrent)); err == nil {
+		query.Current = i
+	}
+	if i, err := strconv.Atoi(exchange.In.GetParam(constants.KeyPageSize)); err == nil && i > 0 {
+		query.Size = i
+	}
+	if query.Size > maxRunPageSize {
+		query.Size = maxRunPageSize
+	}
+	var err error
+	if query.StartTime, err = parseTime(exchange.In.GetParam(constants.KeyStartTime)); err != nil {
+		return query, err
+	}
+	if query.EndTime, err = parseTime(exchange.In.GetParam(constants.KeyEndTime)); err != nil {
+		return query, err
+	}
+	if query.Status != "" && query.Status != model.RunStatusSucceeded && query.Status != model.RunStatusFailed {
+		return query, fmt.Errorf("status=%s not support", query.Status)
+	}
+	if query.Sort != "" && query.Sort != model.RunSortStartTs && query.Sort != model.RunSortEndTs && query.Sort != model.RunSortDuration {
+		return query, fmt.Errorf("sort=%s not support", query.Sort)
+	}
+	return query, parseOrder(exchange, &query.Asc)
+}
+
+// parseOrder 解析排序方向asc/desc，默认降序
+func parseOrder(exchange *endpointApi.Exchange, asc *bool) error {
+	switch strings.ToLower(exchange.In.GetParam(constants.KeyOrder)) {
+	case "", "desc":
+	case "asc":
+		*asc = true
+	default:
+		return fmt.Errorf("order=%s not support", exchange.In.GetParam(constants.KeyOrder))
+	}
+	return nil
+}
+
+// parseTime 解析时间，返回毫秒时间戳，空字符串返回0
+func parseTime(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t.UnixMilli(), nil
+	}
+	return 0, fmt.Errorf("time=%s format error", v)
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/service"
+
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+	"github.com/rulego/rulego/endpoint"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// StorageUsageRouter 创建查询运行快照存储占用路由，只有管理员可以访问
+// 参数user和chainId可选，用于过滤用户和规则链
+func StorageUsageRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := operator(msg)
+		chainId := exchange.In.GetParam(constants.KeyChainId)
+		usages, err := service.RetentionServiceImpl.Usage(username, exchange.In.GetParam(constants.KeyUser))
+		if err != nil {
+			return userError(err, exchange)
+		}
+		var count, bytes int64
+		var list = make([]model.SnapshotUsage, 0, len(usages))
+		for _, item := range usages {
+			if chainId != "" && item.ChainId != chainId {
+				continue
+			}
+			count += item.Count
+			bytes += item.Bytes
+			list = append(list, item)
+		}
+		if v, err := json.Marshal(map[string]interface{}{
+			"count": count,
+			"bytes": bytes,
+			"data":  list,
+		}); err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+		} else {
+			exchange.Out.SetBody(v)
+		}
+		return true
+	}).End()
+}
+
+// PurgeStorageRouter 创建手动清理运行快照路由，只有管理员可以访问
+// 请求体：{"user":"","chainId":"","maxAge":"72h","maxCount":100,"maxBytes":10485760}
+// user或者chainId为空则清理所有用户或者所有规则链，不指定maxAge/maxCount/maxBytes则使用各规则链生效的保留策略
+func PurgeStorageRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := operator(msg)
+		var req struct {
+			User    string `json:"user"`
+			ChainId string `json:"chainId"`
+		}
+		var policy model.RetentionPolicy
+		if msg.Data != "" {
+			if err := json.Unmarshal([]byte(msg.Data), &req); err != nil {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte(err.Error()))
+				return false
+			}
+			if err := json.Unmarshal([]byte(msg.Data), &policy); err != nil {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte(err.Error()))
+				return false
+			}
+		}
+		var custom *model.RetentionPolicy
+		if !policy.IsEmpty() {
+			custom = &policy
+		}
+		result, err := service.RetentionServiceImpl.Purge(username, req.User, req.ChainId, custom)
+		if err != nil {
+			return userError(err, exchange)
+		}
+		if v, err := json.Marshal(result); err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+		} else {
+			exchange.Out.SetBody(v)
+		}
+		return true
+	}).End()
+}
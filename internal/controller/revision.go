@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/service"
+	"strconv"
+
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+	"github.com/rulego/rulego/endpoint"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// ListRevisionRouter 创建获取规则链所有历史版本路由
+func ListRevisionRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			if list, err := s.ListRevisions(chainId); err != nil {
+				exchange.Out.SetStatusCode(http.StatusInternalServerError)
+				exchange.Out.SetBody([]byte(err.Error()))
+			} else {
+				writeJson(list, exchange)
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// GetRevisionRouter 创建获取规则链指定历史版本路由
+func GetRevisionRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		version, err := strconv.Atoi(msg.Metadata.GetValue(constants.KeyVersion))
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			if revision, err := s.GetRevision(chainId, version); err != nil {
+				return revisionError(err, exchange)
+			} else {
+				writeJson(revision, exchange)
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// DiffRevisionRouter 创建比较规则链两个历史版本路由
+func DiffRevisionRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		from, err := strconv.Atoi(msg.Metadata.GetValue(constants.KeyFrom))
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		to, err := strconv.Atoi(msg.Metadata.GetValue(constants.KeyTo))
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			if result, err := s.DiffRevisions(chainId, from, to); err != nil {
+				return revisionError(err, exchange)
+			} else {
+				writeJson(result, exchange)
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// RollbackRevisionRouter 创建回滚规则链到指定历史版本路由
+func RollbackRevisionRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		version, err := strconv.Atoi(msg.Metadata.GetValue(constants.KeyVersion))
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			if err := s.Rollback(chainId, version, username, msg.Metadata.GetValue(constants.KeyMessage)); err != nil {
+				return revisionError(err, exchange)
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// revisionError 历史版本错误响应
+func revisionError(err error, exchange *endpointApi.Exchange) bool {
+	if errors.Is(err, constants.ErrNotFound) {
+		exchange.Out.SetStatusCode(http.StatusNotFound)
+	} else {
+		exchange.Out.SetStatusCode(http.StatusBadRequest)
+	}
+	exchange.Out.SetBody([]byte(err.Error()))
+	return false
+}
+
+// writeJson 把结果序列化成json响应
+func writeJson(v interface{}, exchange *endpointApi.Exchange) {
+	if body, err := json.Marshal(v); err != nil {
+		exchange.Out.SetStatusCode(http.StatusInternalServerError)
+		exchange.Out.SetBody([]byte(err.Error()))
+	} else {
+		exchange.Out.SetBody(body)
+	}
+}
@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"path"
 	"ruleGoProject/config"
 	"ruleGoProject/config/logger"
 	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
 	"ruleGoProject/internal/service"
+	"strconv"
+	"strings"
 
 	"github.com/rulego/rulego"
 	"github.com/rulego/rulego/api/types"
@@ -14,20 +19,58 @@ import (
 	"github.com/rulego/rulego/builtin/processor"
 	"github.com/rulego/rulego/components/action"
 	"github.com/rulego/rulego/endpoint"
+	"github.com/rulego/rulego/endpoint/rest"
 	"github.com/rulego/rulego/utils/json"
 )
 
+// AuthProcess 校验jwt token，并把用户名放入消息元数据，校验不通过响应401
+// 匿名访问在消息元数据标记anonymous，不具有管理员权限
 var AuthProcess = func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
 	msg := exchange.In.GetMsg()
-	username := exchange.In.Headers().Get(constants.KeyUsername)
-	if username == "" {
-		username = config.C.DefaultUsername
+	username, anonymous, err := authenticate(exchange)
+	if err != nil {
+		return unauthorized(err, exchange)
 	}
 	msg.Metadata.PutValue(constants.KeyUsername, username)
-	//TODO JWT 权限校验
+	msg.Metadata.PutValue(constants.KeyAnonymous, strconv.FormatBool(anonymous))
 	return true
 }
 
+// Authenticate 从Authorization请求头或者token参数获取jwt token，校验通过返回用户名
+// 如果允许匿名访问，未携带token的请求使用默认用户名
+func Authenticate(exchange *endpointApi.Exchange) (string, error) {
+	username, _, err := authenticate(exchange)
+	return username, err
+}
+
+// authenticate 校验请求，返回用户名以及是否是匿名访问
+// 匿名访问忽略username请求头，始终使用默认用户名
+func authenticate(exchange *endpointApi.Exchange) (string, bool, error) {
+	var token string
+	if authorization := exchange.In.Headers().Get(constants.KeyAuthorization); strings.HasPrefix(authorization, constants.KeyBearerPrefix) {
+		token = strings.TrimPrefix(authorization, constants.KeyBearerPrefix)
+	} else {
+		//websocket 浏览器无法设置请求头，通过参数传递
+		token = exchange.In.GetParam(constants.KeyToken)
+	}
+	if token != "" {
+		username, err := service.UserServiceImpl.ParseToken(token)
+		return username, false, err
+	}
+	if !config.C.AllowAnonymous {
+		return "", false, constants.ErrUnauthorized
+	}
+	return config.C.DefaultUsername, true, nil
+}
+
+// operator 管理接口的操作人，匿名访问不具有管理员权限，返回空
+func operator(msg *types.RuleMsg) string {
+	if msg.Metadata.GetValue(constants.KeyAnonymous) == "true" {
+		return ""
+	}
+	return msg.Metadata.GetValue(constants.KeyUsername)
+}
+
 // ComponentsRouter 创建获取规则引擎节点组件列表路由
 func ComponentsRouter(url string) endpointApi.Router {
 	return endpoint.NewRouter().From(url).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
@@ -93,7 +136,7 @@ func SaveDslRouter(url string) endpointApi.Router {
 		nodeId := msg.Metadata.GetValue(constants.KeyNodeId)
 		username := msg.Metadata.GetValue(constants.KeyUsername)
 		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-			if err := s.SaveDsl(chainId, nodeId, exchange.In.Body()); err == nil {
+			if err := s.SaveDsl(chainId, nodeId, exchange.In.Body(), username, msg.Metadata.GetValue(constants.KeyMessage)); err == nil {
 				exchange.Out.SetStatusCode(http.StatusOK)
 			} else {
 				logger.Logger.Println(err)
@@ -107,6 +150,27 @@ func SaveDslRouter(url string) endpointApi.Router {
 	}).End()
 }
 
+// ValidateDslRouter 创建校验规则链DSL路由，不会影响正在运行的规则链
+func ValidateDslRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			result := s.ValidateDsl(chainId, exchange.In.Body())
+			if v, err := json.Marshal(result); err != nil {
+				exchange.Out.SetStatusCode(http.StatusInternalServerError)
+				exchange.Out.SetBody([]byte(err.Error()))
+			} else {
+				exchange.Out.SetBody(v)
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
 // ListDslRouter 创建获取所有规则链路由
 func ListDslRouter(url string) endpointApi.Router {
 	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
@@ -160,7 +224,7 @@ func SaveBaseInfo(url string) endpointApi.Router {
 			exchange.Out.SetBody([]byte(err.Error()))
 		} else {
 			if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-				if err := s.SaveBaseInfo(chainId, req); err != nil {
+				if err := s.SaveBaseInfo(chainId, req, username, msg.Metadata.GetValue(constants.KeyMessage)); err != nil {
 					exchange.Out.SetStatusCode(http.StatusBadRequest)
 					exchange.Out.SetBody([]byte(err.Error()))
 				}
@@ -186,7 +250,7 @@ func SaveConfiguration(url string) endpointApi.Router {
 			exchange.Out.SetBody([]byte(err.Error()))
 		} else {
 			if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-				if err := s.SaveConfiguration(chainId, varType, req); err != nil {
+				if err := s.SaveConfiguration(chainId, varType, req, username, msg.Metadata.GetValue(constants.KeyMessage)); err != nil {
 					exchange.Out.SetStatusCode(http.StatusBadRequest)
 					exchange.Out.SetBody([]byte(err.Error()))
 				}
@@ -200,29 +264,11 @@ func SaveConfiguration(url string) endpointApi.Router {
 
 // ExecuteRuleRouter 处理请求，并转发到规则引擎，同步等待规则链执行结果返回给调用方
 func ExecuteRuleRouter(url string) endpointApi.Router {
-	return endpoint.NewRouter(endpointApi.RouterOptions.WithRuleGoFunc(GetRuleGoFunc)).From(url).Process(AuthProcess).Transform(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
-		msg := exchange.In.GetMsg()
-		msgId := exchange.In.GetParam("msgId")
-		if msgId != "" {
-			msg.Id = msgId
-		}
-		msgType := msg.Metadata.GetValue("msgType")
-		//获取消息类型
-		msg.Type = msgType
-		//把http header放入消息元数据
-		headers := exchange.In.Headers()
-		for k := range headers {
-			msg.Metadata.PutValue(k, headers.Get(k))
-		}
-		username := msg.Metadata.GetValue(constants.KeyUsername)
-		//设置工作目录
-		var paths = []string{config.C.DataDir, constants.DirWorkflows, username, constants.DirWorkflowsRule}
-		msg.Metadata.PutValue(constants.KeyWorkDir, path.Join(paths...))
-		return true
-	}).To("chain:${chainId}").SetOpts(
+	return endpoint.NewRouter(endpointApi.RouterOptions.WithRuleGoFunc(GetRuleGoFunc)).From(url).Process(AuthProcess).Transform(prepareMsg).Process(testModeProcess).Process(dedupeProcess(false)).To("chain:${chainId}").SetOpts(
 		types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
 			service.EventServiceImpl.SaveRunLog(ctx, snapshot)
 		})).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		var result string
 		err := exchange.Out.GetError()
 		if err != nil {
 			//错误
@@ -231,38 +277,187 @@ func ExecuteRuleRouter(url string) endpointApi.Router {
 		} else {
 			//把处理结果响应给客户端，http endpoint 必须增加 Wait()，否则无法正常响应
 			outMsg := exchange.Out.GetMsg()
+			result = outMsg.Data
 			exchange.Out.Headers().Set("Content-Type", "application/json")
 			exchange.Out.SetBody([]byte(outMsg.Data))
 		}
+		//保存处理结果，幂等窗口内相同msgId直接返回该结果，处理失败则释放msgId允许重试
+		msg := exchange.In.GetMsg()
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		if e := service.DedupeServiceImpl.Complete(username, chainId, exchange.In.GetParam("msgId"), result, err); e != nil {
+			logger.Logger.Printf("controller/ExecuteRuleRouter save dedupe result error%s", e.Error())
+		}
 		return true
 	}).Wait().End()
 }
 
 // PostMsgRouter 处理请求，并转发到规则引擎
 func PostMsgRouter(url string) endpointApi.Router {
-	return endpoint.NewRouter(endpointApi.RouterOptions.WithRuleGoFunc(GetRuleGoFunc)).From(url).Process(AuthProcess).Transform(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+	return endpoint.NewRouter(endpointApi.RouterOptions.WithRuleGoFunc(GetRuleGoFunc)).From(url).Process(AuthProcess).Transform(prepareMsg).Process(dedupeProcess(true)).To("chain:${chainId}").SetOpts(
+		types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
+			service.EventServiceImpl.SaveRunLog(ctx, snapshot)
+		})).End()
+}
+
+// ExecuteAsyncRouter 异步执行规则链，立即返回执行ID，通过GetRunRouter查询执行结果
+func ExecuteAsyncRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Transform(prepareMsg).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
 		msg := exchange.In.GetMsg()
-		msgId := exchange.In.GetParam("msgId")
-		if msgId != "" {
-			msg.Id = msgId
-		}
-		//把http header放入消息元数据
-		headers := exchange.In.Headers()
-		for k := range headers {
-			msg.Metadata.PutValue(k, headers.Get(k))
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		callbackUrl := exchange.In.GetParam(constants.KeyCallback)
+		if run, err := service.RunServiceImpl.Submit(requestContext(exchange), username, chainId, *msg, callbackUrl); err != nil {
+			return runError(err, exchange)
+		} else {
+			exchange.Out.Headers().Set("Content-Type", "application/json")
+			exchange.Out.SetStatusCode(http.StatusAccepted)
+			v, _ := json.Marshal(run)
+			exchange.Out.SetBody(v)
 		}
-		msgType := msg.Metadata.GetValue("msgType")
-		//获取消息类型
-		msg.Type = msgType
+		return true
+	}).End()
+}
+
+// GetRunRouter 查询异步执行状态和结果
+func GetRunRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		id := msg.Metadata.GetValue(constants.KeyId)
 		username := msg.Metadata.GetValue(constants.KeyUsername)
-		//设置工作目录
-		var paths = []string{config.C.DataDir, constants.DirWorkflows, username, constants.DirWorkflowsRule}
-		msg.Metadata.PutValue(constants.KeyWorkDir, path.Join(paths...))
+		if run, err := service.RunServiceImpl.Get(username, id); err != nil {
+			return runError(err, exchange)
+		} else {
+			writeJson(run, exchange)
+		}
 		return true
-	}).To("chain:${chainId}").SetOpts(
-		types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
-			service.EventServiceImpl.SaveRunLog(ctx, snapshot)
-		})).End()
+	}).End()
+}
+
+// prepareMsg 设置消息ID、消息类型，把http header放入消息元数据，并设置工作目录
+func prepareMsg(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+	msg := exchange.In.GetMsg()
+	msgId := exchange.In.GetParam("msgId")
+	if msgId != "" {
+		msg.Id = msgId
+	}
+	//把http header放入消息元数据，token不传递给规则链
+	headers := exchange.In.Headers()
+	for k := range headers {
+		if k == constants.KeyAuthorization {
+			continue
+		}
+		msg.Metadata.PutValue(k, headers.Get(k))
+	}
+	msgType := msg.Metadata.GetValue("msgType")
+	//获取消息类型
+	msg.Type = msgType
+	username := msg.Metadata.GetValue(constants.KeyUsername)
+	//设置工作目录
+	var paths = []string{config.C.DataDir, constants.DirWorkflows, username, constants.DirWorkflowsRule}
+	msg.Metadata.PutValue(constants.KeyWorkDir, path.Join(paths...))
+	return true
+}
+
+// testModeProcess 参数mode=test时使用规则链保存的模拟配置测试模式执行，同步返回执行结果，不经过幂等处理
+func testModeProcess(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+	if exchange.In.GetParam(constants.KeyMode) != constants.ModeTest {
+		return true
+	}
+	msg := exchange.In.GetMsg()
+	username := msg.Metadata.GetValue(constants.KeyUsername)
+	chainId := msg.Metadata.GetValue(constants.KeyChainId)
+	s, ok := service.UserRuleEngineServiceImpl.Get(username)
+	if !ok {
+		return userNotFound(username, exchange)
+	}
+	result, err := s.TestRun(chainId, *msg, nil)
+	if err != nil {
+		return runError(err, exchange)
+	}
+	if result.Err != "" {
+		exchange.Out.SetStatusCode(http.StatusBadRequest)
+		exchange.Out.SetBody([]byte(result.Err))
+	} else if result.Msg != nil {
+		exchange.Out.Headers().Set("Content-Type", "application/json")
+		exchange.Out.SetBody([]byte(result.Msg.Data))
+	}
+	return false
+}
+
+// TraceContext 非http接入端点(例如：mqtt)的路由上下文，创建接收消息的span，规则链执行span以此为父span
+// http请求的span由router.HttpTracing保存在请求上下文中，不需要该函数
+func TraceContext(ctx context.Context, exchange *endpointApi.Exchange) context.Context {
+	if service.TracingImpl == nil {
+		return ctx
+	}
+	return service.TracingImpl.StartConsumer(ctx, "receive "+exchange.In.From(), exchange.In.GetMsg(), service.WithTopic(exchange.In.From()))
+}
+
+// requestContext http请求上下文，包含当前请求的span
+func requestContext(exchange *endpointApi.Exchange) context.Context {
+	if r, ok := exchange.In.(*rest.RequestMessage); ok && r.Request() != nil {
+		return r.Request().Context()
+	}
+	return context.Background()
+}
+
+// dedupeProcess 规则链配置了幂等窗口时，按msgId去重
+// 重复的msgId：notify响应409；execute处理中响应409，处理完成则直接返回保存的处理结果
+func dedupeProcess(notify bool) endpointApi.Process {
+	return func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		msgId := exchange.In.GetParam("msgId")
+		record, acquired, err := service.DedupeServiceImpl.Acquire(username, chainId, msgId)
+		if err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if acquired {
+			if notify {
+				//notify不关注处理结果
+				if err := service.DedupeServiceImpl.Complete(username, chainId, msgId, "", nil); err != nil {
+					logger.Logger.Printf("controller/PostMsgRouter save dedupe result error%s", err.Error())
+				}
+			}
+			return true
+		}
+		if notify {
+			exchange.Out.SetStatusCode(http.StatusConflict)
+			exchange.Out.SetBody([]byte(constants.ErrMsgDuplicate.Error()))
+		} else if record.Status != model.DedupeStatusDone {
+			exchange.Out.SetStatusCode(http.StatusConflict)
+			exchange.Out.SetBody([]byte(constants.ErrMsgProcessing.Error()))
+		} else {
+			exchange.Out.Headers().Set("Content-Type", "application/json")
+			exchange.Out.SetBody([]byte(record.Result))
+		}
+		return false
+	}
+}
+
+// runError 异步执行错误转换成http状态码
+func runError(err error, exchange *endpointApi.Exchange) bool {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		exchange.Out.SetStatusCode(http.StatusNotFound)
+	case errors.Is(err, constants.ErrRunQueueFull):
+		exchange.Out.SetStatusCode(http.StatusServiceUnavailable)
+	default:
+		exchange.Out.SetStatusCode(http.StatusBadRequest)
+	}
+	exchange.Out.SetBody([]byte(err.Error()))
+	return false
+}
+
+// unauthorized 未认证
+func unauthorized(err error, exchange *endpointApi.Exchange) bool {
+	exchange.Out.SetStatusCode(http.StatusUnauthorized)
+	exchange.Out.SetBody([]byte(err.Error()))
+	return false
 }
 
 // userNotFound 用户不存在
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/textproto"
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/service"
+	"testing"
+
+	"github.com/rulego/rulego/api/types"
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+)
+
+// testMessage 测试用请求和响应消息
+type testMessage struct {
+	headers    textproto.MIMEHeader
+	msg        *types.RuleMsg
+	statusCode int
+	err        error
+}
+
+func (m *testMessage) Body() []byte                  { return nil }
+func (m *testMessage) Headers() textproto.MIMEHeader { return m.headers }
+func (m *testMessage) From() string                  { return "" }
+func (m *testMessage) GetParam(key string) string    { return "" }
+func (m *testMessage) SetMsg(msg *types.RuleMsg)     { m.msg = msg }
+func (m *testMessage) GetMsg() *types.RuleMsg        { return m.msg }
+func (m *testMessage) SetStatusCode(statusCode int)  { m.statusCode = statusCode }
+func (m *testMessage) SetBody(body []byte)           {}
+func (m *testMessage) SetError(err error)            { m.err = err }
+func (m *testMessage) GetError() error               { return m.err }
+
+func newTestExchange(headers map[string]string) *endpointApi.Exchange {
+	msg := types.NewMsg(0, "TEST", types.JSON, types.NewMetadata(), "")
+	in := &testMessage{headers: textproto.MIMEHeader{}, msg: &msg}
+	for k, v := range headers {
+		in.headers.Set(k, v)
+	}
+	return &endpointApi.Exchange{In: in, Out: &testMessage{headers: textproto.MIMEHeader{}}}
+}
+
+func TestAuthProcess(t *testing.T) {
+	logger.Set(log.New(io.Discard, "", 0))
+	oldConfig, oldUserService := config.C, service.UserServiceImpl
+	t.Cleanup(func() { config.C, service.UserServiceImpl = oldConfig, oldUserService })
+	userService, err := service.NewUserService(config.Config{DataDir: t.TempDir(), DefaultUsername: "admin", AdminPassword: "admin123", JwtSecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.UserServiceImpl = userService
+	token, err := userService.Login("admin", "admin123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		allowAnonymous bool
+		headers        map[string]string
+		wantOk         bool
+		wantUsername   string
+		wantOperator   string
+	}{
+		{name: "token", headers: map[string]string{constants.KeyAuthorization: constants.KeyBearerPrefix + token.Token}, wantOk: true, wantUsername: "admin", wantOperator: "admin"},
+		{name: "invalid token", allowAnonymous: true, headers: map[string]string{constants.KeyAuthorization: constants.KeyBearerPrefix + "xxx"}},
+		{name: "anonymous not allowed", headers: map[string]string{constants.KeyUsername: "admin"}},
+		{name: "anonymous", allowAnonymous: true, wantOk: true, wantUsername: "admin", wantOperator: ""},
+		{name: "anonymous ignores username header", allowAnonymous: true, headers: map[string]string{constants.KeyUsername: "u1"}, wantOk: true, wantUsername: "admin", wantOperator: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.C = config.Config{DefaultUsername: "admin", AllowAnonymous: tt.allowAnonymous}
+			exchange := newTestExchange(tt.headers)
+			//请求元数据不能伪造非匿名标记
+			exchange.In.GetMsg().Metadata.PutValue(constants.KeyAnonymous, "false")
+			ok := AuthProcess(nil, exchange)
+			if ok != tt.wantOk {
+				t.Fatalf("AuthProcess() = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				if code := exchange.Out.(*testMessage).statusCode; code != http.StatusUnauthorized {
+					t.Errorf("status code = %d, want %d", code, http.StatusUnauthorized)
+				}
+				return
+			}
+			msg := exchange.In.GetMsg()
+			if got := msg.Metadata.GetValue(constants.KeyUsername); got != tt.wantUsername {
+				t.Errorf("username = %s, want %s", got, tt.wantUsername)
+			}
+			if got := operator(msg); got != tt.wantOperator {
+				t.Errorf("operator = %s, want %s", got, tt.wantOperator)
+			}
+		})
+	}
+}
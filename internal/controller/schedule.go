@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"net/http"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/service"
+	"strconv"
+
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+	"github.com/rulego/rulego/endpoint"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// maxScheduleListSize 定时任务触发时间、触发记录最大查询条数
+const maxScheduleListSize = 1000
+
+// ListScheduleRouter 创建获取规则链定时任务配置路由
+func ListScheduleRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			def, ok := s.Get(chainId)
+			if !ok {
+				exchange.Out.SetStatusCode(http.StatusNotFound)
+				exchange.Out.SetBody([]byte(constants.ErrNotFound.Error()))
+				return false
+			}
+			schedules, err := service.ParseSchedules(def.RuleChain.Configuration)
+			if err != nil {
+				exchange.Out.SetStatusCode(http.StatusInternalServerError)
+				exchange.Out.SetBody([]byte(err.Error()))
+				return false
+			}
+			if schedules == nil {
+				schedules = []model.Schedule{}
+			}
+			writeJson(schedules, exchange)
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// SaveScheduleRouter 创建保存规则链定时任务配置路由，覆盖原有配置，并生成历史版本
+func SaveScheduleRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		var schedules []model.Schedule
+		if err := json.Unmarshal([]byte(msg.Data), &schedules); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if err := service.ValidateSchedules(schedules); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			if _, ok := s.Get(chainId); !ok {
+				exchange.Out.SetStatusCode(http.StatusNotFound)
+				exchange.Out.SetBody([]byte(constants.ErrNotFound.Error()))
+				return false
+			}
+			if err := s.SaveConfiguration(chainId, service.KeySchedules, schedules, username, msg.Metadata.GetValue(constants.KeyMessage)); err != nil {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte(err.Error()))
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// UpcomingScheduleRouter 创建查询定时任务即将触发时间路由，不指定chainId则查询用户所有规则链
+func UpcomingScheduleRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		writeJson(service.ScheduleServiceImpl.Upcoming(username, chainId, scheduleListSize(exchange)), exchange)
+		return true
+	}).End()
+}
+
+// ScheduleHistoryRouter 创建查询定时任务触发记录路由，不指定chainId则查询用户所有规则链
+func ScheduleHistoryRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		if list, err := service.ScheduleServiceImpl.History(username, chainId, scheduleListSize(exchange)); err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+		} else {
+			writeJson(list, exchange)
+		}
+		return true
+	}).End()
+}
+
+// scheduleListSize 获取查询条数，默认20
+func scheduleListSize(exchange *endpointApi.Exchange) int {
+	var size = 20
+	if i, err := strconv.Atoi(exchange.In.GetMsg().Metadata.GetValue(constants.KeyPageSize)); err == nil && i > 0 {
+		size = i
+	}
+	if size > maxScheduleListSize {
+		size = maxScheduleListSize
+	}
+	return size
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/service"
+	"time"
+
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+	"github.com/rulego/rulego/endpoint"
+)
+
+// readyTimeout 就绪检查超时时间
+const readyTimeout = 3 * time.Second
+
+// HealthzRouter 创建存活检查路由，进程可以处理请求即返回200，不需要认证
+func HealthzRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		exchange.Out.SetBody([]byte(`{"status":"ok"}`))
+		return true
+	}).End()
+}
+
+// ReadyzRouter 创建就绪检查路由，数据库可以访问、用户规则引擎池都已初始化、开启mqtt时已连接返回200，否则返回503，不需要认证
+func ReadyzRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+		defer cancel()
+		readiness := service.StatusServiceImpl.Ready(ctx)
+		if !readiness.Ready {
+			exchange.Out.SetStatusCode(http.StatusServiceUnavailable)
+		}
+		writeJson(readiness, exchange)
+		return true
+	}).End()
+}
+
+// StatusRouter 创建服务运行状态路由：版本、运行时长、就绪检查、用户规则链加载情况和数据库连接池状态
+// 管理员返回所有用户的规则引擎池状态，否则只返回当前用户
+func StatusRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+		defer cancel()
+		writeJson(service.StatusServiceImpl.Status(ctx, username, operator(msg)), exchange)
+		return true
+	}).End()
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"net/http"
+	"path"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/service"
+	"strings"
+
+	"github.com/rulego/rulego/api/types"
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+	"github.com/rulego/rulego/endpoint"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// ListTestCaseRouter 创建获取规则链测试用例路由
+func ListTestCaseRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		s, ok := service.UserRuleEngineServiceImpl.Get(username)
+		if !ok {
+			return userNotFound(username, exchange)
+		}
+		if cases, err := s.ListTests(chainId); err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		} else {
+			writeJson(cases, exchange)
+		}
+		return true
+	}).End()
+}
+
+// SaveTestCaseRouter 创建保存规则链测试用例路由，覆盖原有用例
+func SaveTestCaseRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		var cases []model.RuleTestCase
+		if err := json.Unmarshal([]byte(msg.Data), &cases); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		s, ok := service.UserRuleEngineServiceImpl.Get(username)
+		if !ok {
+			return userNotFound(username, exchange)
+		}
+		if err := s.SaveTests(chainId, cases); err != nil {
+			return runError(err, exchange)
+		}
+		return true
+	}).End()
+}
+
+// RunTestCaseRouter 创建执行规则链测试用例路由，返回测试报告
+// 请求体为空则使用规则链当前DSL，否则使用请求体中的DSL，可以在保存前验证修改
+// 参数name指定执行的用例，多个用逗号分隔，为空则执行所有用例
+func RunTestCaseRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		var names []string
+		for _, name := range strings.Split(exchange.In.GetParam(constants.KeyName), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		s, ok := service.UserRuleEngineServiceImpl.Get(username)
+		if !ok {
+			return userNotFound(username, exchange)
+		}
+		if report, err := s.RunTests(chainId, []byte(strings.TrimSpace(msg.Data)), names...); err != nil {
+			return runError(err, exchange)
+		} else {
+			writeJson(report, exchange)
+		}
+		return true
+	}).End()
+}
+
+// ListMockRouter 创建获取规则链模拟节点配置路由
+func ListMockRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			def, ok := s.Get(chainId)
+			if !ok {
+				exchange.Out.SetStatusCode(http.StatusNotFound)
+				exchange.Out.SetBody([]byte(constants.ErrNotFound.Error()))
+				return false
+			}
+			mocks, err := service.ParseMocks(def.RuleChain.Configuration)
+			if err != nil {
+				exchange.Out.SetStatusCode(http.StatusInternalServerError)
+				exchange.Out.SetBody([]byte(err.Error()))
+				return false
+			}
+			if mocks == nil {
+				mocks = []model.NodeMock{}
+			}
+			writeJson(mocks, exchange)
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// SaveMockRouter 创建保存规则链模拟节点配置路由，覆盖原有配置，并生成历史版本
+func SaveMockRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		var mocks []model.NodeMock
+		if err := json.Unmarshal([]byte(msg.Data), &mocks); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if err := service.ValidateMocks(mocks); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
+			if _, ok := s.Get(chainId); !ok {
+				exchange.Out.SetStatusCode(http.StatusNotFound)
+				exchange.Out.SetBody([]byte(constants.ErrNotFound.Error()))
+				return false
+			}
+			if err := s.SaveConfiguration(chainId, service.KeyMocks, mocks, username, msg.Metadata.GetValue(constants.KeyMessage)); err != nil {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte(err.Error()))
+			}
+		} else {
+			return userNotFound(username, exchange)
+		}
+		return true
+	}).End()
+}
+
+// TestRunRouter 创建测试模式执行规则链路由，同步返回执行结果
+// 请求体：{"msgType":"","data":"","metadata":{},"mocks":[]}，mocks优先于规则链保存的模拟配置
+func TestRunRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		chainId := msg.Metadata.GetValue(constants.KeyChainId)
+		username := msg.Metadata.GetValue(constants.KeyUsername)
+		var req struct {
+			MsgType  string            `json:"msgType"`
+			Data     string            `json:"data"`
+			Metadata map[string]string `json:"metadata"`
+			Mocks    []model.NodeMock  `json:"mocks"`
+		}
+		if msg.Data != "" {
+			if err := json.Unmarshal([]byte(msg.Data), &req); err != nil {
+				exchange.Out.SetStatusCode(http.StatusBadRequest)
+				exchange.Out.SetBody([]byte(err.Error()))
+				return false
+			}
+		}
+		s, ok := service.UserRuleEngineServiceImpl.Get(username)
+		if !ok {
+			return userNotFound(username, exchange)
+		}
+		metadata := types.NewMetadata()
+		for k, v := range req.Metadata {
+			metadata.PutValue(k, v)
+		}
+		metadata.PutValue(constants.KeyWorkDir, path.Join(config.C.DataDir, constants.DirWorkflows, username, constants.DirWorkflowsRule))
+		dataType := types.TEXT
+		var v interface{}
+		if json.Unmarshal([]byte(req.Data), &v) == nil {
+			dataType = types.JSON
+		}
+		if result, err := s.TestRun(chainId, types.NewMsg(0, req.MsgType, dataType, metadata, req.Data), req.Mocks); err != nil {
+			return runError(err, exchange)
+		} else {
+			writeJson(result, exchange)
+		}
+		return true
+	}).End()
+}
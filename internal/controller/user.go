@@ -1 +1,143 @@
 package controller
+
+import (
+	"errors"
+	"net/http"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/service"
+
+	endpointApi "github.com/rulego/rulego/api/types/endpoint"
+	"github.com/rulego/rulego/endpoint"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// LoginRouter 创建登录路由，校验用户名密码并签发token
+func LoginRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		var req model.User
+		if err := json.Unmarshal([]byte(msg.Data), &req); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if token, err := service.UserServiceImpl.Login(req.Username, req.Password); err != nil {
+			return unauthorized(err, exchange)
+		} else {
+			return writeToken(token, exchange)
+		}
+	}).End()
+}
+
+// RefreshTokenRouter 创建刷新token路由
+func RefreshTokenRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		var req model.Token
+		if err := json.Unmarshal([]byte(msg.Data), &req); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if token, err := service.UserServiceImpl.RefreshToken(req.RefreshToken); err != nil {
+			return unauthorized(err, exchange)
+		} else {
+			return writeToken(token, exchange)
+		}
+	}).End()
+}
+
+// ListUserRouter 创建获取所有用户路由
+func ListUserRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := operator(msg)
+		if users, err := service.UserServiceImpl.ListUsers(username); err != nil {
+			return userError(err, exchange)
+		} else if v, err := json.Marshal(users); err != nil {
+			exchange.Out.SetStatusCode(http.StatusInternalServerError)
+			exchange.Out.SetBody([]byte(err.Error()))
+		} else {
+			exchange.Out.SetBody(v)
+		}
+		return true
+	}).End()
+}
+
+// CreateUserRouter 创建新增用户路由
+func CreateUserRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := operator(msg)
+		var req model.User
+		if err := json.Unmarshal([]byte(msg.Data), &req); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if err := service.UserServiceImpl.CreateUser(username, req); err != nil {
+			return userError(err, exchange)
+		}
+		return true
+	}).End()
+}
+
+// DeleteUserRouter 创建删除用户路由
+func DeleteUserRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := operator(msg)
+		user := msg.Metadata.GetValue(constants.KeyUser)
+		if err := service.UserServiceImpl.DeleteUser(username, user); err != nil {
+			return userError(err, exchange)
+		}
+		return true
+	}).End()
+}
+
+// ChangePasswordRouter 创建修改密码路由
+func ChangePasswordRouter(url string) endpointApi.Router {
+	return endpoint.NewRouter().From(url).Process(AuthProcess).Process(func(router endpointApi.Router, exchange *endpointApi.Exchange) bool {
+		msg := exchange.In.GetMsg()
+		username := operator(msg)
+		user := msg.Metadata.GetValue(constants.KeyUser)
+		var req model.ChangePassword
+		if err := json.Unmarshal([]byte(msg.Data), &req); err != nil {
+			exchange.Out.SetStatusCode(http.StatusBadRequest)
+			exchange.Out.SetBody([]byte(err.Error()))
+			return false
+		}
+		if err := service.UserServiceImpl.ChangePassword(username, user, req); err != nil {
+			return userError(err, exchange)
+		}
+		return true
+	}).End()
+}
+
+// userError 用户管理错误响应
+func userError(err error, exchange *endpointApi.Exchange) bool {
+	switch {
+	case errors.Is(err, constants.ErrForbidden):
+		exchange.Out.SetStatusCode(http.StatusForbidden)
+	case errors.Is(err, constants.ErrNotFound):
+		exchange.Out.SetStatusCode(http.StatusNotFound)
+	case errors.Is(err, constants.ErrUserExists):
+		exchange.Out.SetStatusCode(http.StatusConflict)
+	default:
+		exchange.Out.SetStatusCode(http.StatusBadRequest)
+	}
+	exchange.Out.SetBody([]byte(err.Error()))
+	return false
+}
+
+func writeToken(token model.Token, exchange *endpointApi.Exchange) bool {
+	if v, err := json.Marshal(token); err != nil {
+		exchange.Out.SetStatusCode(http.StatusInternalServerError)
+		exchange.Out.SetBody([]byte(err.Error()))
+		return false
+	} else {
+		exchange.Out.SetBody(v)
+	}
+	return true
+}
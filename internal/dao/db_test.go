@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"path/filepath"
+	"ruleGoProject/internal/model"
+	"testing"
+)
+
+// newTestDB 使用临时目录的sqlite数据库替换model.DBClient，测试结束后恢复
+func newTestDB(t *testing.T) {
+	t.Helper()
+	db, err := model.NewDBWithStruct(&model.ORMConfig{
+		Driver:  model.DriverSqlite,
+		DBname:  filepath.Join(t.TempDir(), "test.db"),
+		LogMode: model.LogModeSilent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldClient := model.DBClient
+	model.DBClient = db
+	t.Cleanup(func() {
+		model.DBClient = oldClient
+		db.Close()
+	})
+}
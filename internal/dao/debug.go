@@ -0,0 +1,125 @@
+package dao
+
+import (
+	"errors"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 节点调试数据存储类型
+const (
+	// DebugStoreMemory 内存存储，每个节点只保留最新的max_node_log_size条，重启后丢失
+	DebugStoreMemory = "memory"
+	// DebugStoreBolt 内嵌bbolt文件存储，不依赖外部服务
+	DebugStoreBolt = "bolt"
+	// DebugStoreSql 数据库存储，多实例部署时共享
+	DebugStoreSql = "sql"
+)
+
+var ErrDebugStoreNotSupport = errors.New("debug store not support")
+
+// DebugStore 节点调试数据存储，按用户隔离
+type DebugStore interface {
+	// Add 批量保存节点调试数据，data.ChainId为规则链ID
+	Add(username string, data ...model.DebugData) error
+	// List 按查询条件分页查询节点调试数据，默认按时间降序，规则链ID为空则查询用户所有规则链
+	List(username string, query model.DebugDataQuery) ([]model.DebugData, int, error)
+	// ChainIds 获取有调试数据的规则链ID列表，用户为空则查询所有用户，返回用户->规则链ID列表
+	ChainIds(username string) (map[string][]string, error)
+	// Purge 按保留策略清理规则链调试数据，从最新的数据开始保留，超过最长保留时间、条数或者字节数之后的数据都会被删除，返回删除的条数
+	// 字节数按存储的调试数据大小计算
+	Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (int, error)
+	// DeleteByChainId 删除规则链所有调试数据
+	DeleteByChainId(username, chainId string) error
+	// Close 关闭存储
+	Close() error
+}
+
+// NewDebugStore 根据配置创建节点调试数据存储，默认使用内存存储
+func NewDebugStore(config config.Config) (DebugStore, error) {
+	switch config.DebugStore {
+	case DebugStoreMemory, "":
+		maxNodeLogSize := config.MaxNodeLogSize
+		if maxNodeLogSize == 0 {
+			maxNodeLogSize = 40
+		}
+		return NewMemoryDebugStore(maxNodeLogSize), nil
+	case DebugStoreBolt:
+		return NewBoltDebugStore(config)
+	case DebugStoreSql:
+		return NewSqlDebugStore(), nil
+	default:
+		return nil, ErrDebugStoreNotSupport
+	}
+}
+
+// pageDebugData 按查询条件排序分页，items需要按写入顺序，会被排序
+func pageDebugData(items []model.DebugData, query model.DebugDataQuery) []model.DebugData {
+	if !query.Asc {
+		//时间相同的按写入顺序倒序
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if query.Asc {
+			return items[i].Ts < items[j].Ts
+		}
+		return items[i].Ts > items[j].Ts
+	})
+	if query.Size <= 0 {
+		return items
+	}
+	current := query.Current
+	if current < 1 {
+		current = 1
+	}
+	start := (current - 1) * query.Size
+	if start >= len(items) {
+		return []model.DebugData{}
+	}
+	end := start + query.Size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// matchDebugData 检查调试数据是否满足查询条件，不检查规则链ID
+func matchDebugData(item model.DebugData, query model.DebugDataQuery) bool {
+	if query.NodeId != "" && item.NodeId != query.NodeId {
+		return false
+	}
+	if query.MsgId != "" && item.Msg.Id != query.MsgId {
+		return false
+	}
+	if query.StartTime > 0 && item.Ts < query.StartTime {
+		return false
+	}
+	if query.EndTime > 0 && item.Ts >= query.EndTime {
+		return false
+	}
+	return true
+}
+
+// isAgeOnly 保留策略是否只限制最长保留时间，只需要删除早于截止时间的数据
+func isAgeOnly(policy model.RetentionPolicy) bool {
+	return policy.MaxCount <= 0 && policy.MaxBytes <= 0
+}
+
+// expiredDebugKeys 按保留策略计算需要清理的调试数据key，metas需要按时间降序，Id为调试数据在存储中的key
+func expiredDebugKeys(metas []model.SnapshotMeta, policy model.RetentionPolicy, now time.Time) map[string]bool {
+	var keys = make(map[string]bool)
+	for _, meta := range ExpiredSnapshots(metas, policy, now) {
+		keys[meta.Id] = true
+	}
+	return keys
+}
+
+// memoryDebugKey 内存存储调试数据key，节点ID+队列下标
+func memoryDebugKey(nodeId string, i int) string {
+	return nodeId + "\x00" + strconv.Itoa(i)
+}
@@ -0,0 +1,295 @@
+package dao
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/model"
+	"time"
+
+	"github.com/rulego/rulego/utils/fs"
+	"github.com/rulego/rulego/utils/json"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// bucketDebugChains 用户下按规则链保存调试数据的bucket，key为时间+序号
+	bucketDebugChains = []byte("chains")
+	// bucketDebugMsgs 用户下按消息ID索引调试数据的bucket，key为msgId+规则链ID+数据key
+	bucketDebugMsgs = []byte("msgs")
+)
+
+// BoltDebugStore 基于内嵌bbolt文件的节点调试数据存储，数据保存在{data_dir}/debug.db
+// 结构：用户bucket/chains/规则链ID/{ts}{seq}->调试数据json，用户bucket/msgs/{msgId}\x00{chainId}\x00{ts}{seq}->空
+type BoltDebugStore struct {
+	db *bolt.DB
+}
+
+func NewBoltDebugStore(config config.Config) (*BoltDebugStore, error) {
+	_ = fs.CreateDirs(config.DataDir)
+	db, err := bolt.Open(filepath.Join(config.DataDir, "debug.db"), 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltDebugStore{db: db}, nil
+}
+
+// Add 使用Batch合并并发写入
+func (s *BoltDebugStore) Add(username string, data ...model.DebugData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		userBucket, err := tx.CreateBucketIfNotExists([]byte(username))
+		if err != nil {
+			return err
+		}
+		chains, err := userBucket.CreateBucketIfNotExists(bucketDebugChains)
+		if err != nil {
+			return err
+		}
+		msgs, err := userBucket.CreateBucketIfNotExists(bucketDebugMsgs)
+		if err != nil {
+			return err
+		}
+		for _, item := range data {
+			chain, err := chains.CreateBucketIfNotExists([]byte(item.ChainId))
+			if err != nil {
+				return err
+			}
+			seq, _ := chain.NextSequence()
+			key := debugKey(item.Ts, seq)
+			value, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if err = chain.Put(key, value); err != nil {
+				return err
+			}
+			if item.Msg.Id != "" {
+				if err = msgs.Put(debugMsgKey(item.Msg.Id, item.ChainId, key), nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// List 指定消息ID时通过消息ID索引查询，否则按时间范围遍历规则链数据
+func (s *BoltDebugStore) List(username string, query model.DebugDataQuery) ([]model.DebugData, int, error) {
+	var items = make([]model.DebugData, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(username))
+		if userBucket == nil {
+			return nil
+		}
+		chains := userBucket.Bucket(bucketDebugChains)
+		if chains == nil {
+			return nil
+		}
+		appendItem := func(value []byte) error {
+			var item model.DebugData
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			if matchDebugData(item, query) {
+				items = append(items, item)
+			}
+			return nil
+		}
+		if query.MsgId != "" {
+			msgs := userBucket.Bucket(bucketDebugMsgs)
+			if msgs == nil {
+				return nil
+			}
+			prefix := append([]byte(query.MsgId), 0)
+			c := msgs.Cursor()
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				chainId, key, ok := splitDebugMsgKey(k[len(prefix):])
+				if !ok || (query.ChainId != "" && query.ChainId != chainId) {
+					continue
+				}
+				if chain := chains.Bucket([]byte(chainId)); chain != nil {
+					if value := chain.Get(key); value != nil {
+						if err := appendItem(value); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		}
+		return chains.ForEach(func(chainId, _ []byte) error {
+			if query.ChainId != "" && query.ChainId != string(chainId) {
+				return nil
+			}
+			chain := chains.Bucket(chainId)
+			if chain == nil {
+				return nil
+			}
+			c := chain.Cursor()
+			for k, v := c.Seek(debugKey(query.StartTime, 0)); k != nil; k, v = c.Next() {
+				if query.EndTime > 0 && int64(binary.BigEndian.Uint64(k[:8])) >= query.EndTime {
+					break
+				}
+				if err := appendItem(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return pageDebugData(items, query), len(items), nil
+}
+
+func (s *BoltDebugStore) ChainIds(username string) (map[string][]string, error) {
+	var result = make(map[string][]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(user []byte, userBucket *bolt.Bucket) error {
+			if username != "" && username != string(user) {
+				return nil
+			}
+			chains := userBucket.Bucket(bucketDebugChains)
+			if chains == nil {
+				return nil
+			}
+			return chains.ForEach(func(chainId, _ []byte) error {
+				result[string(user)] = append(result[string(user)], string(chainId))
+				return nil
+			})
+		})
+	})
+	return result, err
+}
+
+func (s *BoltDebugStore) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (int, error) {
+	if policy.IsEmpty() {
+		return 0, nil
+	}
+	var count int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(username))
+		if userBucket == nil {
+			return nil
+		}
+		chains := userBucket.Bucket(bucketDebugChains)
+		if chains == nil {
+			return nil
+		}
+		chain := chains.Bucket([]byte(chainId))
+		if chain == nil {
+			return nil
+		}
+		//遍历时删除会跳过元素，先收集再删除
+		var keys [][]byte
+		c := chain.Cursor()
+		if isAgeOnly(policy) {
+			end := debugKey(now.Add(-policy.MaxAge).UnixMilli(), 0)
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+				keys = append(keys, append([]byte{}, k...))
+			}
+		} else {
+			//key按时间和序号排序，倒序遍历即按时间降序
+			var metas []model.SnapshotMeta
+			for k, v := c.Last(); k != nil; k, v = c.Prev() {
+				metas = append(metas, model.SnapshotMeta{Id: string(k), StartTs: int64(binary.BigEndian.Uint64(k[:8])), Size: int64(len(v))})
+			}
+			for _, meta := range ExpiredSnapshots(metas, policy, now) {
+				keys = append(keys, []byte(meta.Id))
+			}
+		}
+		var msgKeys [][]byte
+		for _, k := range keys {
+			var item model.DebugData
+			if json.Unmarshal(chain.Get(k), &item) == nil && item.Msg.Id != "" {
+				msgKeys = append(msgKeys, debugMsgKey(item.Msg.Id, chainId, k))
+			}
+		}
+		for _, k := range keys {
+			if err := chain.Delete(k); err != nil {
+				return err
+			}
+		}
+		if msgs := userBucket.Bucket(bucketDebugMsgs); msgs != nil {
+			for _, k := range msgKeys {
+				if err := msgs.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		count = len(keys)
+		return nil
+	})
+	return count, err
+}
+
+func (s *BoltDebugStore) DeleteByChainId(username, chainId string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(username))
+		if userBucket == nil {
+			return nil
+		}
+		if chains := userBucket.Bucket(bucketDebugChains); chains != nil && chains.Bucket([]byte(chainId)) != nil {
+			if err := chains.DeleteBucket([]byte(chainId)); err != nil {
+				return err
+			}
+		}
+		msgs := userBucket.Bucket(bucketDebugMsgs)
+		if msgs == nil {
+			return nil
+		}
+		var keys [][]byte
+		_ = msgs.ForEach(func(k, _ []byte) error {
+			if i := bytes.IndexByte(k, 0); i >= 0 {
+				if id, _, ok := splitDebugMsgKey(k[i+1:]); ok && id == chainId {
+					keys = append(keys, append([]byte{}, k...))
+				}
+			}
+			return nil
+		})
+		for _, k := range keys {
+			if err := msgs.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltDebugStore) Close() error {
+	return s.db.Close()
+}
+
+// debugKey 调试数据key，时间在前保证按时间排序
+func debugKey(ts int64, seq uint64) []byte {
+	if ts < 0 {
+		ts = 0
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(ts))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func debugMsgKey(msgId, chainId string, key []byte) []byte {
+	k := make([]byte, 0, len(msgId)+len(chainId)+2+len(key))
+	k = append(k, msgId...)
+	k = append(k, 0)
+	k = append(k, chainId...)
+	k = append(k, 0)
+	return append(k, key...)
+}
+
+// splitDebugMsgKey 从去掉msgId前缀的索引key中解析规则链ID和数据key
+func splitDebugMsgKey(k []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(k, 0)
+	if i < 0 || len(k)-i-1 != 16 {
+		return "", nil, false
+	}
+	return string(k[:i]), k[i+1:], true
+}
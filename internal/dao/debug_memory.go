@@ -0,0 +1,380 @@
+/*
+ * Copyright 2023 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rulego/rulego/utils/json"
+)
+
+//基于内存的日志存储，用于查询节点调试数据
+//每个节点只保留一定的条数，最旧的数据会被自动删除
+//如果需要查询历史数据，请使用bolt或者sql存储
+
+// MemoryDebugStore 基于内存的节点调试数据存储，重启后丢失
+type MemoryDebugStore struct {
+	// 用户->规则链调试数据
+	data    map[string]*RuleChainDebugData
+	maxSize int
+	lock    sync.RWMutex
+}
+
+func NewMemoryDebugStore(maxSize int) *MemoryDebugStore {
+	return &MemoryDebugStore{
+		data:    make(map[string]*RuleChainDebugData),
+		maxSize: maxSize,
+	}
+}
+
+func (s *MemoryDebugStore) Add(username string, data ...model.DebugData) error {
+	s.lock.Lock()
+	ruleChainData, ok := s.data[username]
+	if !ok {
+		ruleChainData = NewRuleChainDebugData(s.maxSize)
+		s.data[username] = ruleChainData
+	}
+	s.lock.Unlock()
+	for _, item := range data {
+		ruleChainData.Add(item.ChainId, item.NodeId, item)
+	}
+	return nil
+}
+
+func (s *MemoryDebugStore) List(username string, query model.DebugDataQuery) ([]model.DebugData, int, error) {
+	ruleChainData, ok := s.get(username)
+	if !ok {
+		return []model.DebugData{}, 0, nil
+	}
+	items := ruleChainData.List(query)
+	return pageDebugData(items, query), len(items), nil
+}
+
+func (s *MemoryDebugStore) ChainIds(username string) (map[string][]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var result = make(map[string][]string)
+	for user, ruleChainData := range s.data {
+		if username == "" || username == user {
+			result[user] = ruleChainData.ChainIds()
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryDebugStore) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (int, error) {
+	if ruleChainData, ok := s.get(username); ok {
+		return ruleChainData.Purge(chainId, policy, now), nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryDebugStore) DeleteByChainId(username, chainId string) error {
+	if ruleChainData, ok := s.get(username); ok {
+		ruleChainData.Clear(chainId)
+	}
+	return nil
+}
+
+func (s *MemoryDebugStore) Close() error {
+	return nil
+}
+
+func (s *MemoryDebugStore) get(username string) (*RuleChainDebugData, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	ruleChainData, ok := s.data[username]
+	return ruleChainData, ok
+}
+
+// RuleChainDebugData 规则链下节点调试数据
+type RuleChainDebugData struct {
+	//Data 规则链ID->节点列表调试数据
+	Data map[string]*NodeDebugData
+	// MaxSize 每个节点允许的最大数量
+	MaxSize int
+	mu      sync.RWMutex
+}
+
+// NewRuleChainDebugData 创建一个新的规则链调试数据列表数据
+func NewRuleChainDebugData(maxSize int) *RuleChainDebugData {
+	if maxSize <= 0 {
+		maxSize = 60
+	}
+	return &RuleChainDebugData{
+		Data:    make(map[string]*NodeDebugData),
+		MaxSize: maxSize,
+	}
+}
+
+func (d *RuleChainDebugData) Add(chainId string, nodeId string, data model.DebugData) {
+	d.mu.Lock()
+	ruleChainData, ok := d.Data[chainId]
+	if !ok {
+		ruleChainData = NewNodeDebugData(d.MaxSize)
+		d.Data[chainId] = ruleChainData
+	}
+	defer d.mu.Unlock()
+
+	ruleChainData.Add(nodeId, data)
+}
+
+// Get 获取指定规则链的节点调试数据列表
+func (d *RuleChainDebugData) Get(chainId string, nodeId string) *FixedQueue {
+	d.mu.RLock()
+	ruleChainData, ok := d.Data[chainId]
+	defer d.mu.RUnlock()
+	if ok {
+		return ruleChainData.Get(nodeId)
+	} else {
+		return nil
+	}
+}
+
+// List 获取满足查询条件的调试数据，规则链ID为空则查询所有规则链
+func (d *RuleChainDebugData) List(query model.DebugDataQuery) []model.DebugData {
+	d.mu.RLock()
+	var chains = make(map[string]*NodeDebugData)
+	for chainId, ruleChainData := range d.Data {
+		if query.ChainId == "" || query.ChainId == chainId {
+			chains[chainId] = ruleChainData
+		}
+	}
+	d.mu.RUnlock()
+	var items = make([]model.DebugData, 0)
+	for _, ruleChainData := range chains {
+		ruleChainData.mu.RLock()
+		for nodeId, list := range ruleChainData.Data {
+			if query.NodeId != "" && query.NodeId != nodeId {
+				continue
+			}
+			list.mu.RLock()
+			for _, item := range list.Items {
+				if matchDebugData(item, query) {
+					items = append(items, item)
+				}
+			}
+			list.mu.RUnlock()
+		}
+		ruleChainData.mu.RUnlock()
+	}
+	return items
+}
+func (d *RuleChainDebugData) Clear(chainId string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.Data, chainId)
+}
+
+// ChainIds 获取有调试数据的规则链ID列表
+func (d *RuleChainDebugData) ChainIds() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var chainIds []string
+	for chainId := range d.Data {
+		chainIds = append(chainIds, chainId)
+	}
+	return chainIds
+}
+
+// Purge 按保留策略清理规则链调试数据，所有节点的数据按时间统一计算，返回删除的条数
+func (d *RuleChainDebugData) Purge(chainId string, policy model.RetentionPolicy, now time.Time) int {
+	if policy.IsEmpty() {
+		return 0
+	}
+	d.mu.RLock()
+	ruleChainData, ok := d.Data[chainId]
+	d.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	ruleChainData.mu.RLock()
+	defer ruleChainData.mu.RUnlock()
+	if isAgeOnly(policy) {
+		var count int
+		ts := now.Add(-policy.MaxAge).UnixMilli()
+		for _, list := range ruleChainData.Data {
+			count += list.RemoveBefore(ts)
+		}
+		return count
+	}
+	//锁定所有节点队列，避免计算和删除期间写入
+	var metas []model.SnapshotMeta
+	for nodeId, list := range ruleChainData.Data {
+		list.mu.Lock()
+		defer list.mu.Unlock()
+		//同一时间的数据，队列中靠后的更新
+		for i := len(list.Items) - 1; i >= 0; i-- {
+			var size int
+			if policy.MaxBytes > 0 {
+				v, _ := json.Marshal(list.Items[i])
+				size = len(v)
+			}
+			metas = append(metas, model.SnapshotMeta{Id: memoryDebugKey(nodeId, i), StartTs: list.Items[i].Ts, Size: int64(size)})
+		}
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		return metas[i].StartTs > metas[j].StartTs
+	})
+	expired := expiredDebugKeys(metas, policy, now)
+	if len(expired) == 0 {
+		return 0
+	}
+	for nodeId, list := range ruleChainData.Data {
+		var items = make([]model.DebugData, 0, list.MaxSize)
+		for i, item := range list.Items {
+			if !expired[memoryDebugKey(nodeId, i)] {
+				items = append(items, item)
+			}
+		}
+		list.Items = items
+	}
+	return len(expired)
+}
+
+// NodeDebugData 节点调试数据
+type NodeDebugData struct {
+	Data map[string]*FixedQueue
+	// MaxSize 每个节点允许的最大数量
+	MaxSize int
+	mu      sync.RWMutex
+}
+
+// NewNodeDebugData 创建一个新的节点调试数据列表数据
+func NewNodeDebugData(maxSize int) *NodeDebugData {
+	if maxSize <= 0 {
+		maxSize = 60
+	}
+	return &NodeDebugData{
+		Data:    make(map[string]*FixedQueue),
+		MaxSize: maxSize,
+	}
+}
+
+func (d *NodeDebugData) Add(nodeId string, data model.DebugData) {
+	d.mu.Lock()
+	list, ok := d.Data[nodeId]
+	if !ok {
+		list = NewFixedQueue(d.MaxSize)
+		d.Data[nodeId] = list
+	}
+	defer d.mu.Unlock()
+
+	list.Push(data)
+}
+
+// Get 获取自定节点列表数据
+func (d *NodeDebugData) Get(nodeId string) *FixedQueue {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if list, ok := d.Data[nodeId]; ok {
+		return list
+	} else {
+		return nil
+	}
+
+}
+
+func (d *NodeDebugData) Clear(nodeId string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.Data, nodeId)
+}
+
+// FixedQueue 固定大小的队列，如果超过会自动清除最旧的数据
+type FixedQueue struct {
+	// Items 数据列表
+	Items []model.DebugData
+	// MaxSize 最大允许的条数
+	MaxSize int
+	mu      sync.RWMutex
+}
+
+// NewFixedQueue 创建一个新的固定大小的队列
+func NewFixedQueue(maxSize int) *FixedQueue {
+	return &FixedQueue{
+		Items:   make([]model.DebugData, 0, maxSize),
+		MaxSize: maxSize,
+	}
+}
+
+// Push 向队列中添加一个元素，如果超过最大大小，会删除最旧的元素
+func (q *FixedQueue) Push(item model.DebugData) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.Items) == q.MaxSize {
+		q.Items = q.Items[1:]
+	}
+	q.Items = append(q.Items, item)
+}
+
+// Pop 从队列中弹出一个元素，如果队列为空，返回false
+func (q *FixedQueue) Pop() (model.DebugData, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.Items) == 0 {
+		return model.DebugData{}, false
+	}
+	item := q.Items[0]
+	q.Items = q.Items[1:]
+	return item, true
+}
+
+// Len 返回队列中的元素个数
+func (q *FixedQueue) Len() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return len(q.Items)
+}
+
+// Peek 返回队列中的第一个元素，但不删除它，如果队列为空，返回false
+func (q *FixedQueue) Peek() (model.DebugData, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if len(q.Items) == 0 {
+		return model.DebugData{}, false
+	}
+	return q.Items[0], true
+}
+
+// RemoveBefore 删除早于指定时间的元素，返回删除的条数
+func (q *FixedQueue) RemoveBefore(ts int64) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var items = make([]model.DebugData, 0, q.MaxSize)
+	for _, item := range q.Items {
+		if item.Ts >= ts {
+			items = append(items, item)
+		}
+	}
+	count := len(q.Items) - len(items)
+	if count > 0 {
+		q.Items = items
+	}
+	return count
+}
+
+// Clear 清空队列中的所有元素
+func (q *FixedQueue) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.Items = make([]model.DebugData, 0, q.MaxSize)
+}
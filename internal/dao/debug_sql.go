@@ -0,0 +1,161 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"strconv"
+	"time"
+)
+
+// SqlDebugStore 基于数据库node_debug_log表的节点调试数据存储
+type SqlDebugStore struct {
+}
+
+func NewSqlDebugStore() *SqlDebugStore {
+	return &SqlDebugStore{}
+}
+
+func (s *SqlDebugStore) Add(username string, data ...model.DebugData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var records = make([]model.NodeDebugLog, 0, len(data))
+	for _, item := range data {
+		records = append(records, model.NodeDebugLog{
+			Owner:        username,
+			RuleChainId:  item.ChainId,
+			NodeId:       item.NodeId,
+			MsgId:        item.Msg.Id,
+			FlowType:     item.FlowType,
+			Ts:           item.Ts,
+			RelationType: item.RelationType,
+			Err:          item.Err,
+			Mocked:       item.Mocked,
+			Msg:          item.Msg,
+		})
+	}
+	return model.DBClient.Client.Create(&records).Error
+}
+
+func (s *SqlDebugStore) List(username string, query model.DebugDataQuery) ([]model.DebugData, int, error) {
+	db := model.DBClient.Client.Model(&model.NodeDebugLog{}).Where("owner = ?", username)
+	if query.ChainId != "" {
+		db = db.Where("rule_chain_id = ?", query.ChainId)
+	}
+	if query.NodeId != "" {
+		db = db.Where("node_id = ?", query.NodeId)
+	}
+	if query.MsgId != "" {
+		db = db.Where("msg_id = ?", query.MsgId)
+	}
+	if query.StartTime > 0 {
+		db = db.Where("ts >= ?", query.StartTime)
+	}
+	if query.EndTime > 0 {
+		db = db.Where("ts < ?", query.EndTime)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if query.Asc {
+		db = db.Order("ts, id")
+	} else {
+		db = db.Order("ts DESC, id DESC")
+	}
+	if query.Size > 0 {
+		current := query.Current
+		if current < 1 {
+			current = 1
+		}
+		db = db.Offset((current - 1) * query.Size).Limit(query.Size)
+	}
+	var records []model.NodeDebugLog
+	if err := db.Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	var items = make([]model.DebugData, 0, len(records))
+	for _, record := range records {
+		items = append(items, model.DebugData{
+			ChainId:      record.RuleChainId,
+			Ts:           record.Ts,
+			NodeId:       record.NodeId,
+			FlowType:     record.FlowType,
+			Msg:          record.Msg,
+			RelationType: record.RelationType,
+			Err:          record.Err,
+			Mocked:       record.Mocked,
+		})
+	}
+	return items, int(total), nil
+}
+
+func (s *SqlDebugStore) ChainIds(username string) (map[string][]string, error) {
+	var rows []struct {
+		Owner       string
+		RuleChainId string
+	}
+	db := model.DBClient.Client.Model(&model.NodeDebugLog{}).Distinct("owner", "rule_chain_id")
+	if username != "" {
+		db = db.Where("owner = ?", username)
+	}
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var result = make(map[string][]string)
+	for _, row := range rows {
+		result[row.Owner] = append(result[row.Owner], row.RuleChainId)
+	}
+	return result, nil
+}
+
+// Purge 只限制最长保留时间时直接按时间删除，否则只查询调试数据的时间和消息大小计算需要清理的数据，然后分批删除
+func (s *SqlDebugStore) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (int, error) {
+	if policy.IsEmpty() {
+		return 0, nil
+	}
+	if isAgeOnly(policy) {
+		db := model.DBClient.Client.Where("owner = ? AND rule_chain_id = ? AND ts < ?", username, chainId, now.Add(-policy.MaxAge).UnixMilli()).
+			Delete(&model.NodeDebugLog{})
+		return int(db.RowsAffected), db.Error
+	}
+	var rows []struct {
+		ID   uint
+		Ts   int64
+		Size int64
+	}
+	if err := model.DBClient.Client.Model(&model.NodeDebugLog{}).Select("id, ts, LENGTH(msg) AS size").
+		Where("owner = ? AND rule_chain_id = ?", username, chainId).
+		Order("ts DESC, id DESC").Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+	var metas = make([]model.SnapshotMeta, 0, len(rows))
+	for _, row := range rows {
+		metas = append(metas, model.SnapshotMeta{Id: strconv.FormatUint(uint64(row.ID), 10), StartTs: row.Ts, Size: row.Size})
+	}
+	expired := ExpiredSnapshots(metas, policy, now)
+	var count int
+	for start := 0; start < len(expired); start += purgeBatchSize {
+		end := start + purgeBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+		var ids []string
+		for _, meta := range expired[start:end] {
+			ids = append(ids, meta.Id)
+		}
+		db := model.DBClient.Client.Where("owner = ? AND rule_chain_id = ? AND id IN ?", username, chainId, ids).Delete(&model.NodeDebugLog{})
+		count += int(db.RowsAffected)
+		if db.Error != nil {
+			return count, db.Error
+		}
+	}
+	return count, nil
+}
+
+func (s *SqlDebugStore) DeleteByChainId(username, chainId string) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ?", username, chainId).Delete(&model.NodeDebugLog{}).Error
+}
+
+func (s *SqlDebugStore) Close() error {
+	return nil
+}
@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"fmt"
+	"reflect"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/model"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+func TestDebugStorePurge(t *testing.T) {
+	now := time.UnixMilli(100000)
+	stores := map[string]func(t *testing.T) DebugStore{
+		DebugStoreMemory: func(t *testing.T) DebugStore { return NewMemoryDebugStore(100) },
+		DebugStoreBolt: func(t *testing.T) DebugStore {
+			store, err := NewBoltDebugStore(config.Config{DataDir: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+		DebugStoreSql: func(t *testing.T) DebugStore {
+			newTestDB(t)
+			return NewSqlDebugStore()
+		},
+	}
+	tests := []struct {
+		name   string
+		policy model.RetentionPolicy
+		// 保留的消息ID，按时间降序
+		want []string
+	}{
+		{name: "empty policy", policy: model.RetentionPolicy{}, want: []string{"m6", "m5", "m4", "m3", "m2", "m1"}},
+		{name: "unlimited", policy: model.RetentionPolicy{MaxAge: model.RetentionUnlimited, MaxCount: model.RetentionUnlimited}, want: []string{"m6", "m5", "m4", "m3", "m2", "m1"}},
+		{name: "max age", policy: model.RetentionPolicy{MaxAge: 3 * time.Second}, want: []string{"m6", "m5", "m4", "m3"}},
+		{name: "max count across nodes", policy: model.RetentionPolicy{MaxCount: 2}, want: []string{"m6", "m5"}},
+		{name: "max count with unlimited age", policy: model.RetentionPolicy{MaxAge: model.RetentionUnlimited, MaxCount: 3}, want: []string{"m6", "m5", "m4"}},
+		{name: "max age and count", policy: model.RetentionPolicy{MaxAge: 4 * time.Second, MaxCount: 3}, want: []string{"m6", "m5", "m4"}},
+	}
+	for name, newStore := range stores {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				store := newStore(t)
+				//两个节点交替写入m1~m6，时间间隔1秒，另一个规则链的数据不受影响
+				for i := 1; i <= 6; i++ {
+					data := model.DebugData{
+						ChainId: "c1",
+						NodeId:  fmt.Sprintf("n%d", i%2),
+						Ts:      int64(94000 + i*1000),
+						Msg:     types.RuleMsg{Id: fmt.Sprintf("m%d", i), Data: "{}"},
+					}
+					other := data
+					other.ChainId = "c2"
+					if err := store.Add("u1", data, other); err != nil {
+						t.Fatal(err)
+					}
+				}
+				deleted, err := store.Purge("u1", "c1", tt.policy, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if deleted != 6-len(tt.want) {
+					t.Errorf("Purge() = %d, want %d", deleted, 6-len(tt.want))
+				}
+				if got := debugMsgIds(t, store, "c1"); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("c1 = %v, want %v", got, tt.want)
+				}
+				if got := debugMsgIds(t, store, "c2"); len(got) != 6 {
+					t.Errorf("c2 = %v, want not purged", got)
+				}
+				//消息ID索引同时删除
+				for i := 1; i <= 6; i++ {
+					msgId := fmt.Sprintf("m%d", i)
+					items, _, err := store.List("u1", model.DebugDataQuery{ChainId: "c1", MsgId: msgId})
+					if err != nil {
+						t.Fatal(err)
+					}
+					if want := contains(tt.want, msgId); (len(items) == 1) != want {
+						t.Errorf("list by msgId %s = %d items, want kept %v", msgId, len(items), want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDebugStorePurgeMaxBytes(t *testing.T) {
+	stores := map[string]func(t *testing.T) DebugStore{
+		DebugStoreMemory: func(t *testing.T) DebugStore { return NewMemoryDebugStore(100) },
+		DebugStoreSql: func(t *testing.T) DebugStore {
+			newTestDB(t)
+			return NewSqlDebugStore()
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			for i := 1; i <= 5; i++ {
+				data := model.DebugData{ChainId: "c1", NodeId: "n1", Ts: int64(i), Msg: types.RuleMsg{Id: fmt.Sprintf("m%d", i), Data: "0123456789"}}
+				if err := store.Add("u1", data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			items, _, _ := store.List("u1", model.DebugDataQuery{ChainId: "c1", Size: 1})
+			size := debugDataSize(name, items[0])
+			//最多保留两条半的大小，保留最新的两条
+			deleted, err := store.Purge("u1", "c1", model.RetentionPolicy{MaxBytes: size*2 + size/2}, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := debugMsgIds(t, store, "c1"), []string{"m5", "m4"}; deleted != 3 || !reflect.DeepEqual(got, want) {
+				t.Errorf("Purge() = %d, remaining %v, want 3, %v", deleted, got, want)
+			}
+		})
+	}
+}
+
+// debugDataSize 和存储计算字节数的方式一致
+func debugDataSize(store string, item model.DebugData) int64 {
+	if store == DebugStoreSql {
+		v, _ := json.Marshal(item.Msg)
+		return int64(len(v))
+	}
+	v, _ := json.Marshal(item)
+	return int64(len(v))
+}
+
+func debugMsgIds(t *testing.T, store DebugStore, chainId string) []string {
+	t.Helper()
+	items, _, err := store.List("u1", model.DebugDataQuery{ChainId: chainId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids = []string{}
+	for _, item := range items {
+		ids = append(ids, item.Msg.Id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids
+}
+
+func contains(items []string, v string) bool {
+	for _, item := range items {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
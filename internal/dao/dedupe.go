@@ -0,0 +1,45 @@
+package dao
+
+import (
+	"errors"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/model"
+	"time"
+)
+
+// 消息去重存储类型
+const (
+	// DedupeStoreMemory 内存存储，重启后丢失
+	DedupeStoreMemory = "memory"
+	// DedupeStoreSql 数据库存储，多实例部署时共享
+	DedupeStoreSql = "sql"
+)
+
+var ErrDedupeStoreNotSupport = errors.New("dedupe store not support")
+
+// DedupeStore 消息去重存储，按用户、规则链、msgId隔离
+type DedupeStore interface {
+	// Acquire 占用msgId，首次处理或者已过期返回true，否则返回已存在的记录
+	// 处理中的记录lease后过期，处理完成的记录window后过期
+	Acquire(owner, chainId, msgId string, window, lease time.Duration) (*model.MsgDedupe, bool, error)
+	// Complete 保存处理结果
+	Complete(owner, chainId, msgId, result string) error
+	// Release 释放处理中的msgId，相同msgId可以再次处理
+	Release(owner, chainId, msgId string) error
+	// DeleteByChainId 删除规则链所有去重记录
+	DeleteByChainId(owner, chainId string) error
+	// DeleteExpired 清除过期记录
+	DeleteExpired(now time.Time) error
+}
+
+// NewDedupeStore 根据配置创建消息去重存储，默认使用内存存储
+func NewDedupeStore(config config.Config) (DedupeStore, error) {
+	switch config.DedupeStore {
+	case DedupeStoreMemory, "":
+		return NewMemoryDedupeStore(), nil
+	case DedupeStoreSql:
+		return NewSqlDedupeStore(), nil
+	default:
+		return nil, ErrDedupeStoreNotSupport
+	}
+}
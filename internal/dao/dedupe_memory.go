@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"sync"
+	"time"
+)
+
+// MemoryDedupeStore 基于内存的消息去重存储，重启后丢失
+type MemoryDedupeStore struct {
+	// 用户/规则链ID/msgId->去重记录
+	data map[string]*model.MsgDedupe
+	lock sync.Mutex
+}
+
+func NewMemoryDedupeStore() *MemoryDedupeStore {
+	return &MemoryDedupeStore{
+		data: make(map[string]*model.MsgDedupe),
+	}
+}
+
+func (d *MemoryDedupeStore) Acquire(owner, chainId, msgId string, window, lease time.Duration) (*model.MsgDedupe, bool, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	now := time.Now()
+	key := d.key(owner, chainId, msgId)
+	if v, ok := d.data[key]; ok && !v.IsExpired(now) {
+		record := *v
+		return &record, false, nil
+	}
+	d.data[key] = &model.MsgDedupe{
+		Owner:         owner,
+		RuleChainId:   chainId,
+		MsgId:         msgId,
+		Status:        model.DedupeStatusProcessing,
+		ExpireAt:      now.Add(window),
+		LeaseExpireAt: now.Add(lease),
+		CreatedAt:     now,
+	}
+	return nil, true, nil
+}
+
+func (d *MemoryDedupeStore) Complete(owner, chainId, msgId, result string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if v, ok := d.data[d.key(owner, chainId, msgId)]; ok {
+		v.Status = model.DedupeStatusDone
+		v.Result = result
+	}
+	return nil
+}
+
+func (d *MemoryDedupeStore) Release(owner, chainId, msgId string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	key := d.key(owner, chainId, msgId)
+	if v, ok := d.data[key]; ok && v.Status == model.DedupeStatusProcessing {
+		delete(d.data, key)
+	}
+	return nil
+}
+
+func (d *MemoryDedupeStore) DeleteByChainId(owner, chainId string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for k, v := range d.data {
+		if v.Owner == owner && v.RuleChainId == chainId {
+			delete(d.data, k)
+		}
+	}
+	return nil
+}
+
+func (d *MemoryDedupeStore) DeleteExpired(now time.Time) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for k, v := range d.data {
+		if v.IsExpired(now) {
+			delete(d.data, k)
+		}
+	}
+	return nil
+}
+
+func (d *MemoryDedupeStore) key(owner, chainId, msgId string) string {
+	return owner + "/" + chainId + "/" + msgId
+}
@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SqlDedupeStore 基于数据库msg_dedupe表的消息去重存储，依赖唯一索引保证并发时只有一个请求占用成功
+type SqlDedupeStore struct {
+}
+
+func NewSqlDedupeStore() *SqlDedupeStore {
+	return &SqlDedupeStore{}
+}
+
+func (d *SqlDedupeStore) Acquire(owner, chainId, msgId string, window, lease time.Duration) (*model.MsgDedupe, bool, error) {
+	now := time.Now()
+	var existing model.MsgDedupe
+	var acquired bool
+	err := model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		//先删除已过期或者租约已过期的记录
+		if err := tx.Where("owner = ? AND rule_chain_id = ? AND msg_id = ?", owner, chainId, msgId).Where(expiredCondition(tx, now)).
+			Delete(&model.MsgDedupe{}).Error; err != nil {
+			return err
+		}
+		record := model.MsgDedupe{
+			Owner:         owner,
+			RuleChainId:   chainId,
+			MsgId:         msgId,
+			Status:        model.DedupeStatusProcessing,
+			ExpireAt:      now.Add(window),
+			LeaseExpireAt: now.Add(lease),
+			CreatedAt:     now,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			acquired = true
+			return nil
+		}
+		return tx.Where("owner = ? AND rule_chain_id = ? AND msg_id = ?", owner, chainId, msgId).Limit(1).Find(&existing).Error
+	})
+	if err != nil || acquired {
+		return nil, acquired, err
+	}
+	return &existing, false, nil
+}
+
+func (d *SqlDedupeStore) Complete(owner, chainId, msgId, result string) error {
+	return model.DBClient.Client.Model(&model.MsgDedupe{}).Where("owner = ? AND rule_chain_id = ? AND msg_id = ?", owner, chainId, msgId).
+		Updates(map[string]interface{}{"status": model.DedupeStatusDone, "result": result}).Error
+}
+
+func (d *SqlDedupeStore) Release(owner, chainId, msgId string) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ? AND msg_id = ? AND status = ?", owner, chainId, msgId, model.DedupeStatusProcessing).
+		Delete(&model.MsgDedupe{}).Error
+}
+
+func (d *SqlDedupeStore) DeleteByChainId(owner, chainId string) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ?", owner, chainId).Delete(&model.MsgDedupe{}).Error
+}
+
+func (d *SqlDedupeStore) DeleteExpired(now time.Time) error {
+	return model.DBClient.Client.Where(expiredCondition(model.DBClient.Client, now)).Delete(&model.MsgDedupe{}).Error
+}
+
+// expiredCondition 已过期或者处理中租约已过期，和model.MsgDedupe.IsExpired一致
+func expiredCondition(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Where("expire_at <= ?", now).
+		Or("status = ? AND lease_expire_at <= ?", model.DedupeStatusProcessing, now)
+}
@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"testing"
+	"time"
+)
+
+func TestDedupeStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) DedupeStore{
+		DedupeStoreMemory: func(t *testing.T) DedupeStore { return NewMemoryDedupeStore() },
+		DedupeStoreSql: func(t *testing.T) DedupeStore {
+			newTestDB(t)
+			return NewSqlDedupeStore()
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("acquire and complete", func(t *testing.T) {
+				store := newStore(t)
+				if _, ok, err := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); err != nil || !ok {
+					t.Fatalf("first Acquire() = %v, %v, want acquired", ok, err)
+				}
+				existing, ok, err := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute)
+				if err != nil || ok || existing == nil || existing.Status != model.DedupeStatusProcessing {
+					t.Fatalf("processing Acquire() = %+v, %v, %v", existing, ok, err)
+				}
+				if err := store.Complete("u1", "c1", "m1", "result"); err != nil {
+					t.Fatal(err)
+				}
+				existing, ok, err = store.Acquire("u1", "c1", "m1", time.Hour, time.Minute)
+				if err != nil || ok || existing == nil || existing.Status != model.DedupeStatusDone || existing.Result != "result" {
+					t.Fatalf("done Acquire() = %+v, %v, %v", existing, ok, err)
+				}
+				//其他规则链和用户相互隔离
+				if _, ok, _ := store.Acquire("u1", "c2", "m1", time.Hour, time.Minute); !ok {
+					t.Error("other chain is not acquired")
+				}
+				if _, ok, _ := store.Acquire("u2", "c1", "m1", time.Hour, time.Minute); !ok {
+					t.Error("other user is not acquired")
+				}
+			})
+			t.Run("release", func(t *testing.T) {
+				store := newStore(t)
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); !ok {
+					t.Fatal("not acquired")
+				}
+				if err := store.Release("u1", "c1", "m1"); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); !ok {
+					t.Error("released msgId is not acquired")
+				}
+				//处理完成的记录不会被释放
+				if err := store.Complete("u1", "c1", "m1", "result"); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Release("u1", "c1", "m1"); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); ok {
+					t.Error("done record is released")
+				}
+			})
+			t.Run("lease expired", func(t *testing.T) {
+				store := newStore(t)
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, 50*time.Millisecond); !ok {
+					t.Fatal("not acquired")
+				}
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, 50*time.Millisecond); ok {
+					t.Fatal("acquired before lease expired")
+				}
+				time.Sleep(100 * time.Millisecond)
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); !ok {
+					t.Error("not acquired after lease expired")
+				}
+			})
+			t.Run("window expired", func(t *testing.T) {
+				store := newStore(t)
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", 50*time.Millisecond, time.Minute); !ok {
+					t.Fatal("not acquired")
+				}
+				if err := store.Complete("u1", "c1", "m1", "result"); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(100 * time.Millisecond)
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); !ok {
+					t.Error("not acquired after window expired")
+				}
+			})
+			t.Run("delete expired", func(t *testing.T) {
+				store := newStore(t)
+				store.Acquire("u1", "c1", "m1", time.Hour, time.Minute)
+				store.Acquire("u1", "c1", "m2", time.Hour, time.Minute)
+				store.Complete("u1", "c1", "m2", "result")
+				//租约过期的处理中记录被删除，处理完成的记录在窗口内保留
+				if err := store.DeleteExpired(time.Now().Add(2 * time.Minute)); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok, _ := store.Acquire("u1", "c1", "m1", time.Hour, time.Minute); !ok {
+					t.Error("lease expired record is not deleted")
+				}
+				if existing, ok, _ := store.Acquire("u1", "c1", "m2", time.Hour, time.Minute); ok || existing == nil {
+					t.Error("done record is deleted")
+				}
+				if err := store.DeleteByChainId("u1", "c1"); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok, _ := store.Acquire("u1", "c1", "m2", time.Hour, time.Minute); !ok {
+					t.Error("record is not deleted by chain id")
+				}
+			})
+		})
+	}
+}
@@ -2,15 +2,24 @@ package dao
 
 import (
 	"ruleGoProject/internal/model"
+
+	"gorm.io/gorm"
 )
 
-// 查询所有需要加载的规则链
-func GetAllLoadRegulation() ([]model.Regulation, error) {
+// 查询用户所有需要加载的规则链
+func GetAllLoadRegulation(owner string) ([]model.Regulation, error) {
 	re := make([]model.Regulation, 0)
-	err := model.DBClient.Client.Model(&model.Regulation{}).Find(&re).Error
+	err := model.DBClient.Client.Model(&model.Regulation{}).Where("owner = ?", owner).Find(&re).Error
 	return re, err
 }
 
+// 查询所有拥有规则链的用户
+func GetAllRegulationOwner() ([]string, error) {
+	owners := make([]string, 0)
+	err := model.DBClient.Client.Model(&model.Regulation{}).Distinct("owner").Pluck("owner", &owners).Error
+	return owners, err
+}
+
 // 创建规则链
 func CreateRegulation(r model.Regulation) error {
 	err := model.DBClient.Client.Create(&r).Error
@@ -18,26 +27,52 @@ func CreateRegulation(r model.Regulation) error {
 }
 
 // 根据ID更新规则链
-func UpdateRegulationByRuleChainId(ruleChainId string, ruleConfig string) error {
-	return model.DBClient.Client.Model(&model.Regulation{}).Where("rule_chain_id = ?", ruleChainId).Update("rule_config", ruleConfig).Error
+func UpdateRegulationByRuleChainId(owner, ruleChainId string, ruleConfig string) error {
+	return updateRegulation(model.DBClient.Client, owner, ruleChainId, ruleConfig)
+}
+
+func updateRegulation(db *gorm.DB, owner, ruleChainId string, ruleConfig string) error {
+	return db.Model(&model.Regulation{}).Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Update("rule_config", ruleConfig).Error
 }
 
 // 根据规则链ID查询规则链信息
-func FindRegulationByRuleChainId(ruleChainId string) (*model.Regulation, error) {
+func FindRegulationByRuleChainId(owner, ruleChainId string) (*model.Regulation, error) {
+	return findRegulation(model.DBClient.Client, owner, ruleChainId)
+}
+
+func findRegulation(db *gorm.DB, owner, ruleChainId string) (*model.Regulation, error) {
 	r := model.Regulation{}
-	err := model.DBClient.Client.Model(&model.Regulation{}).Where("rule_chain_id = ?", ruleChainId).Limit(1).Find(&r).Error
+	err := db.Model(&model.Regulation{}).Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Limit(1).Find(&r).Error
 	return &r, err
 }
 
-// 创建规则链
+// 保存规则链，不存在则创建，否则更新
 func SaveRegulation(r model.Regulation) error {
-	err := model.DBClient.Client.Save(&r).Error
-	return err
+	return saveRegulation(model.DBClient.Client, r)
 }
 
-// 根据规则链ID查询规则链信息
-func DeleteRegulationByRuleChainId(ruleChainId string) error {
+// saveRegulation 使用指定连接保存规则链，用于和其他操作在同一个事务中执行
+func saveRegulation(db *gorm.DB, r model.Regulation) error {
+	old, err := findRegulation(db, r.Owner, r.RuleChainId)
+	if err != nil {
+		return err
+	}
+	if old.ID == 0 {
+		return db.Create(&r).Error
+	}
+	return updateRegulation(db, r.Owner, r.RuleChainId, r.RuleConfig)
+}
+
+// 根据规则链ID删除规则链
+// 使用物理删除，否则软删除的记录会占用(owner, rule_chain_id)唯一索引
+func DeleteRegulationByRuleChainId(owner, ruleChainId string) error {
 	r := model.Regulation{}
-	err := model.DBClient.Client.Model(&model.Regulation{}).Where("rule_chain_id = ?", ruleChainId).Delete(&r).Error
+	err := model.DBClient.Client.Unscoped().Model(&model.Regulation{}).Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Delete(&r).Error
 	return err
 }
+
+// 把没有所属用户的历史规则链迁移到指定用户
+func MigrateRegulationOwner(owner string) (int64, error) {
+	result := model.DBClient.Client.Model(&model.Regulation{}).Where("owner = '' OR owner IS NULL").Update("owner", owner)
+	return result.RowsAffected, result.Error
+}
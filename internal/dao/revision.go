@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 创建规则链历史版本，版本号为当前最大版本号+1
+func CreateRuleRevision(r *model.RuleRevision) error {
+	return model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		return createRuleRevision(tx, r)
+	})
+}
+
+// createRuleRevision 在指定事务中创建规则链历史版本
+func createRuleRevision(tx *gorm.DB, r *model.RuleRevision) error {
+	var maxVersion int
+	if err := tx.Model(&model.RuleRevision{}).Where("owner = ? AND rule_chain_id = ?", r.Owner, r.RuleChainId).
+		Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+		return err
+	}
+	r.Version = maxVersion + 1
+	return tx.Create(r).Error
+}
+
+// 查询规则链所有历史版本，不返回规则链DSL，按版本号降序
+func ListRuleRevision(owner, ruleChainId string) ([]model.RuleRevision, error) {
+	re := make([]model.RuleRevision, 0)
+	err := model.DBClient.Client.Model(&model.RuleRevision{}).Omit("rule_config").
+		Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Order("version DESC").Find(&re).Error
+	return re, err
+}
+
+// 查询规则链指定历史版本
+func FindRuleRevision(owner, ruleChainId string, version int) (*model.RuleRevision, error) {
+	r := model.RuleRevision{}
+	err := model.DBClient.Client.Model(&model.RuleRevision{}).Where("owner = ? AND rule_chain_id = ? AND version = ?", owner, ruleChainId, version).Limit(1).Find(&r).Error
+	return &r, err
+}
+
+// 删除规则链所有历史版本
+func DeleteRuleRevisionByRuleChainId(owner, ruleChainId string) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Delete(&model.RuleRevision{}).Error
+}
+
+// 删除规则链指定历史版本，用于规则链保存失败时撤销已创建的版本
+func DeleteRuleRevision(owner, ruleChainId string, version int) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ? AND version = ?", owner, ruleChainId, version).Delete(&model.RuleRevision{}).Error
+}
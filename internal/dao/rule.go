@@ -1,58 +1,61 @@
 package dao
 
 import (
-	"os"
-	"path"
-	"path/filepath"
+	"errors"
 	"ruleGoProject/config"
-	"ruleGoProject/internal/constants"
 	"ruleGoProject/internal/model"
+)
 
-	"github.com/rulego/rulego/utils/fs"
-	"github.com/rulego/rulego/utils/json"
+// 规则链存储类型
+const (
+	// RuleStoreFile 文件存储
+	RuleStoreFile = "file"
+	// RuleStoreSql 数据库存储
+	RuleStoreSql = "sql"
+	// RuleStoreMemory 内存存储，重启后丢失，一般用于测试
+	RuleStoreMemory = "memory"
 )
 
-type RuleDao struct {
-	config config.Config
-}
+var ErrRuleStoreNotSupport = errors.New("rule store not support")
 
-func NewRuleDao(config config.Config) (*RuleDao, error) {
-	return &RuleDao{
-		config: config,
-	}, nil
+// RuleDef 规则链定义
+type RuleDef struct {
+	// ChainId 规则链ID
+	ChainId string
+	// Def 规则链DSL
+	Def []byte
 }
 
-func (d *RuleDao) Save(username, chainId string, def []byte) error {
-	var paths = []string{d.config.DataDir, constants.DirWorkflows}
-	paths = append(paths, username, constants.DirWorkflowsRule)
-	pathStr := path.Join(paths...)
-	//创建文件夹
-	_ = fs.CreateDirs(pathStr)
-	//保存到文件
-	v, _ := json.Format(def)
-	//保存规则链到文件
-	return fs.SaveFile(filepath.Join(pathStr, chainId+constants.RuleChainFileSuffix), v)
+// RuleStore 规则链持久化存储，按用户隔离
+type RuleStore interface {
+	// Load 加载用户指定规则链DSL
+	Load(username, chainId string) ([]byte, error)
+	// Save 保存或者更新用户规则链DSL
+	Save(username, chainId string, def []byte) error
+	// Delete 删除用户规则链
+	Delete(username, chainId string) error
+	// List 获取用户所有规则链
+	List(username string) ([]RuleDef, error)
+	// ListUsers 获取所有拥有规则链的用户
+	ListUsers() ([]string, error)
 }
 
-func (d *RuleDao) Delete(username, chainId string) error {
-	var paths = []string{d.config.DataDir, constants.DirWorkflows}
-	paths = append(paths, username, constants.DirWorkflowsRule)
-	pathStr := path.Join(paths...)
-	file := filepath.Join(pathStr, chainId+constants.RuleChainFileSuffix)
-	return os.RemoveAll(file)
+// RevisionRuleStore 可以在同一个事务中保存规则链和历史版本的规则链存储
+type RevisionRuleStore interface {
+	// SaveWithRevision 保存或者更新用户规则链DSL，并创建历史版本，任意一个失败则都不保存
+	SaveWithRevision(username, chainId string, def []byte, revision *model.RuleRevision) error
 }
 
-// 保存或更新到数据库
-func (d *RuleDao) SaveToDataBase(chainId string, def []byte) error {
-	v, _ := json.Format(def)
-	createInfo := model.Regulation{
-		RuleChainId: chainId,
-		RuleConfig:  string(v),
+// NewRuleStore 根据配置创建规则链存储，默认使用数据库存储
+func NewRuleStore(config config.Config) (RuleStore, error) {
+	switch config.RuleStore {
+	case RuleStoreFile:
+		return NewFileRuleStore(config), nil
+	case RuleStoreSql, "":
+		return NewSqlRuleStore(), nil
+	case RuleStoreMemory:
+		return NewMemoryRuleStore(), nil
+	default:
+		return nil, ErrRuleStoreNotSupport
 	}
-	return SaveRegulation(createInfo)
-}
-
-// 从数据库删除规则链
-func (d *RuleDao) DeleteToDataBase(chainId string) error {
-	return DeleteRegulationByRuleChainId(chainId)
 }
@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/constants"
+	"strings"
+
+	"github.com/rulego/rulego/utils/fs"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// FileRuleStore 基于文件的规则链存储
+// 规则链保存在 {DataDir}/workflows/{username}/rules/{chainId}.json
+type FileRuleStore struct {
+	config config.Config
+}
+
+func NewFileRuleStore(config config.Config) *FileRuleStore {
+	return &FileRuleStore{
+		config: config,
+	}
+}
+
+func (d *FileRuleStore) getRulesPath(username string) string {
+	var paths = []string{d.config.DataDir, constants.DirWorkflows}
+	paths = append(paths, username, constants.DirWorkflowsRule)
+	return path.Join(paths...)
+}
+
+func (d *FileRuleStore) Load(username, chainId string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.getRulesPath(username), chainId+constants.RuleChainFileSuffix))
+}
+
+func (d *FileRuleStore) Save(username, chainId string, def []byte) error {
+	pathStr := d.getRulesPath(username)
+	//创建文件夹
+	_ = fs.CreateDirs(pathStr)
+	//保存到文件
+	v, _ := json.Format(def)
+	//保存规则链到文件
+	return fs.SaveFile(filepath.Join(pathStr, chainId+constants.RuleChainFileSuffix), v)
+}
+
+func (d *FileRuleStore) Delete(username, chainId string) error {
+	file := filepath.Join(d.getRulesPath(username), chainId+constants.RuleChainFileSuffix)
+	return os.RemoveAll(file)
+}
+
+func (d *FileRuleStore) List(username string) ([]RuleDef, error) {
+	var list []RuleDef
+	entries, err := os.ReadDir(d.getRulesPath(username))
+	if os.IsNotExist(err) {
+		return list, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), constants.RuleChainFileSuffix) {
+			continue
+		}
+		chainId := strings.TrimSuffix(entry.Name(), constants.RuleChainFileSuffix)
+		if def, err := d.Load(username, chainId); err != nil {
+			return nil, err
+		} else {
+			list = append(list, RuleDef{ChainId: chainId, Def: def})
+		}
+	}
+	return list, nil
+}
+
+func (d *FileRuleStore) ListUsers() ([]string, error) {
+	var users []string
+	entries, err := os.ReadDir(path.Join(d.config.DataDir, constants.DirWorkflows))
+	if os.IsNotExist(err) {
+		return users, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			users = append(users, entry.Name())
+		}
+	}
+	return users, nil
+}
@@ -0,0 +1,74 @@
+package dao
+
+import (
+	"ruleGoProject/internal/constants"
+	"sort"
+	"sync"
+)
+
+// MemoryRuleStore 基于内存的规则链存储，重启后丢失，一般用于测试
+type MemoryRuleStore struct {
+	// 用户->规则链ID->规则链DSL
+	data map[string]map[string][]byte
+	lock sync.RWMutex
+}
+
+func NewMemoryRuleStore() *MemoryRuleStore {
+	return &MemoryRuleStore{
+		data: make(map[string]map[string][]byte),
+	}
+}
+
+func (d *MemoryRuleStore) Load(username, chainId string) ([]byte, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if def, ok := d.data[username][chainId]; ok {
+		return def, nil
+	}
+	return nil, constants.ErrNotFound
+}
+
+func (d *MemoryRuleStore) Save(username, chainId string, def []byte) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	rules, ok := d.data[username]
+	if !ok {
+		rules = make(map[string][]byte)
+		d.data[username] = rules
+	}
+	v := make([]byte, len(def))
+	copy(v, def)
+	rules[chainId] = v
+	return nil
+}
+
+func (d *MemoryRuleStore) Delete(username, chainId string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.data[username], chainId)
+	return nil
+}
+
+func (d *MemoryRuleStore) List(username string) ([]RuleDef, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	var list []RuleDef
+	for chainId, def := range d.data[username] {
+		list = append(list, RuleDef{ChainId: chainId, Def: def})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ChainId < list[j].ChainId
+	})
+	return list, nil
+}
+
+func (d *MemoryRuleStore) ListUsers() ([]string, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	var users []string
+	for username := range d.data {
+		users = append(users, username)
+	}
+	sort.Strings(users)
+	return users, nil
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+
+	"github.com/rulego/rulego/utils/json"
+	"gorm.io/gorm"
+)
+
+// SqlRuleStore 基于数据库regulation表的规则链存储
+type SqlRuleStore struct {
+}
+
+func NewSqlRuleStore() *SqlRuleStore {
+	return &SqlRuleStore{}
+}
+
+func (d *SqlRuleStore) Load(username, chainId string) ([]byte, error) {
+	r, err := FindRegulationByRuleChainId(username, chainId)
+	if err != nil {
+		return nil, err
+	}
+	if r.ID == 0 {
+		return nil, constants.ErrNotFound
+	}
+	return []byte(r.RuleConfig), nil
+}
+
+// Save 保存或更新到数据库
+func (d *SqlRuleStore) Save(username, chainId string, def []byte) error {
+	v, _ := json.Format(def)
+	createInfo := model.Regulation{
+		Owner:       username,
+		RuleChainId: chainId,
+		RuleConfig:  string(v),
+	}
+	return SaveRegulation(createInfo)
+}
+
+// SaveWithRevision 在同一个事务中保存规则链和创建历史版本
+func (d *SqlRuleStore) SaveWithRevision(username, chainId string, def []byte, revision *model.RuleRevision) error {
+	v, _ := json.Format(def)
+	return model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		if err := saveRegulation(tx, model.Regulation{
+			Owner:       username,
+			RuleChainId: chainId,
+			RuleConfig:  string(v),
+		}); err != nil {
+			return err
+		}
+		return createRuleRevision(tx, revision)
+	})
+}
+
+// Delete 从数据库删除规则链
+func (d *SqlRuleStore) Delete(username, chainId string) error {
+	return DeleteRegulationByRuleChainId(username, chainId)
+}
+
+func (d *SqlRuleStore) List(username string) ([]RuleDef, error) {
+	regulations, err := GetAllLoadRegulation(username)
+	if err != nil {
+		return nil, err
+	}
+	var list []RuleDef
+	for _, item := range regulations {
+		list = append(list, RuleDef{ChainId: item.RuleChainId, Def: []byte(item.RuleConfig)})
+	}
+	return list, nil
+}
+
+func (d *SqlRuleStore) ListUsers() ([]string, error) {
+	return GetAllRegulationOwner()
+}
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"strings"
+	"testing"
+)
+
+// compact 去掉格式化DSL中的空白
+func compact(def []byte) string {
+	return strings.Join(strings.Fields(string(def)), "")
+}
+
+func TestSqlRuleStoreSaveWithRevision(t *testing.T) {
+	newTestDB(t)
+	store := NewSqlRuleStore()
+	for i, def := range []string{`{"v":1}`, `{"v":2}`} {
+		revision := &model.RuleRevision{Owner: "u1", RuleChainId: "c1", RuleConfig: def}
+		if err := store.SaveWithRevision("u1", "c1", []byte(def), revision); err != nil {
+			t.Fatal(err)
+		}
+		if revision.Version != i+1 {
+			t.Errorf("version = %d, want %d", revision.Version, i+1)
+		}
+	}
+	if def, err := store.Load("u1", "c1"); err != nil || compact(def) != `{"v":2}` {
+		t.Errorf("Load() = %s, %v", def, err)
+	}
+
+	//创建历史版本失败，规则链不保存
+	if err := model.DBClient.Client.Migrator().DropTable(&model.RuleRevision{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveWithRevision("u1", "c1", []byte(`{"v":3}`), &model.RuleRevision{Owner: "u1", RuleChainId: "c1"}); err == nil {
+		t.Fatal("SaveWithRevision() error = nil")
+	}
+	if def, _ := store.Load("u1", "c1"); compact(def) != `{"v":2}` {
+		t.Errorf("rule chain = %s, want not updated", def)
+	}
+	if err := store.SaveWithRevision("u1", "c2", []byte(`{"v":1}`), &model.RuleRevision{Owner: "u1", RuleChainId: "c2"}); err == nil {
+		t.Fatal("SaveWithRevision() error = nil")
+	}
+	if _, err := store.Load("u1", "c2"); err == nil {
+		t.Error("rule chain is created")
+	}
+}
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+	"time"
+)
+
+// 创建定时任务触发记录
+func CreateScheduleFiring(r *model.ScheduleFiring) error {
+	return model.DBClient.Client.Create(r).Error
+}
+
+// 更新定时任务触发记录执行结果
+func UpdateScheduleFiringResult(id uint, status, errStr string, endTime time.Time) error {
+	return model.DBClient.Client.Model(&model.ScheduleFiring{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "err": errStr, "end_time": endTime}).Error
+}
+
+// 查询定时任务触发记录，按触发时间降序，ruleChainId为空则查询用户所有规则链
+func ListScheduleFiring(owner, ruleChainId string, size int) ([]model.ScheduleFiring, error) {
+	re := make([]model.ScheduleFiring, 0)
+	db := model.DBClient.Client.Model(&model.ScheduleFiring{}).Where("owner = ?", owner)
+	if ruleChainId != "" {
+		db = db.Where("rule_chain_id = ?", ruleChainId)
+	}
+	err := db.Order("id DESC").Limit(size).Find(&re).Error
+	return re, err
+}
+
+// 只保留规则链最新的keep条定时任务触发记录
+func TrimScheduleFiring(owner, ruleChainId string, keep int) error {
+	var ids []uint
+	if err := model.DBClient.Client.Model(&model.ScheduleFiring{}).Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).
+		Order("id DESC").Offset(keep).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ? AND id <= ?", owner, ruleChainId, ids[0]).Delete(&model.ScheduleFiring{}).Error
+}
+
+// 删除规则链所有定时任务触发记录
+func DeleteScheduleFiringByRuleChainId(owner, ruleChainId string) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Delete(&model.ScheduleFiring{}).Error
+}
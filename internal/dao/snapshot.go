@@ -0,0 +1,226 @@
+package dao
+
+import (
+	"errors"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// 运行快照存储类型
+const (
+	// SnapshotStoreFile 文件存储，每次运行保存一个json文件
+	SnapshotStoreFile = "file"
+	// SnapshotStoreSql 数据库存储
+	SnapshotStoreSql = "sql"
+)
+
+var ErrSnapshotStoreNotSupport = errors.New("snapshot store not support")
+
+// ErrSnapshotExists 同一用户快照ID已存在
+var ErrSnapshotExists = errors.New("snapshot already exists")
+
+// SnapshotStore 规则链运行快照存储，按用户隔离
+type SnapshotStore interface {
+	// Save 保存运行快照，snapshot.Id为快照ID，同一用户快照ID已存在返回ErrSnapshotExists
+	Save(username, chainId string, snapshot types.RuleChainRunSnapshot) error
+	// Get 获取运行快照
+	Get(username, chainId, id string) (types.RuleChainRunSnapshot, error)
+	// List 按查询条件分页查询运行快照，默认按开始时间降序，规则链ID为空则查询用户所有规则链
+	List(username string, query model.RunSnapshotQuery) ([]types.RuleChainRunSnapshot, int, error)
+	// Delete 删除运行快照，快照不存在返回constants.ErrNotFound
+	Delete(username, chainId, id string) error
+	// DeleteByChainId 删除规则链所有运行快照
+	DeleteByChainId(username, chainId string) error
+	// Usage 按规则链统计运行快照存储占用，用户为空则统计所有用户
+	Usage(username string) ([]model.SnapshotUsage, error)
+	// Purge 按保留策略清理规则链运行快照
+	Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (model.PurgeResult, error)
+}
+
+// NewSnapshotStore 根据配置创建运行快照存储，默认使用数据库存储
+func NewSnapshotStore(config config.Config) (SnapshotStore, error) {
+	switch config.SnapshotStore {
+	case SnapshotStoreFile:
+		return NewFileSnapshotStore(config), nil
+	case SnapshotStoreSql, "":
+		return NewSqlSnapshotStore(), nil
+	default:
+		return nil, ErrSnapshotStoreNotSupport
+	}
+}
+
+// SnapshotStatus 根据节点运行日志计算运行状态，任意节点有错误则为失败
+func SnapshotStatus(snapshot types.RuleChainRunSnapshot) string {
+	for _, item := range snapshot.Logs {
+		if item.Err != "" {
+			return model.RunStatusFailed
+		}
+	}
+	return model.RunStatusSucceeded
+}
+
+// SnapshotMsgType 获取触发运行的消息类型，取最先执行节点的输入消息类型
+func SnapshotMsgType(snapshot types.RuleChainRunSnapshot) string {
+	msg, _ := SnapshotInMsg(snapshot, "")
+	return msg.Type
+}
+
+// SnapshotMsgId 获取触发运行的消息ID，没有节点运行日志则从快照ID解析
+func SnapshotMsgId(snapshot types.RuleChainRunSnapshot) string {
+	for _, item := range snapshot.Logs {
+		if item.InMsg.Id != "" {
+			return item.InMsg.Id
+		}
+	}
+	//快照ID格式：{时间}_{msgId}
+	if i := strings.Index(snapshot.Id, "_"); i >= 0 {
+		return snapshot.Id[i+1:]
+	}
+	return snapshot.Id
+}
+
+// SnapshotInMsg 获取运行的输入消息，nodeId为空取最先执行节点的输入消息，否则取指定节点最先执行的输入消息
+func SnapshotInMsg(snapshot types.RuleChainRunSnapshot, nodeId string) (types.RuleMsg, bool) {
+	var msg types.RuleMsg
+	var startTs int64
+	var found bool
+	for _, item := range snapshot.Logs {
+		if nodeId != "" && item.Id != nodeId {
+			continue
+		}
+		if !found || item.StartTs < startTs {
+			msg = item.InMsg
+			startTs = item.StartTs
+			found = true
+		}
+	}
+	return msg, found
+}
+
+// SnapshotReplayOf 获取重放的原运行快照ID，不是重放则返回空
+func SnapshotReplayOf(snapshot types.RuleChainRunSnapshot) string {
+	msg, _ := SnapshotInMsg(snapshot, "")
+	return msg.Metadata.GetValue(constants.KeyReplayOf)
+}
+
+// SnapshotNodeIds 获取执行过的节点ID，去重
+func SnapshotNodeIds(snapshot types.RuleChainRunSnapshot) []string {
+	var nodeIds []string
+	var exists = make(map[string]struct{})
+	for _, item := range snapshot.Logs {
+		if _, ok := exists[item.Id]; ok || item.Id == "" {
+			continue
+		}
+		exists[item.Id] = struct{}{}
+		nodeIds = append(nodeIds, item.Id)
+	}
+	return nodeIds
+}
+
+// SnapshotMsgText 获取各节点输入输出消息内容和元数据，相同内容只保留一份，用于关键字查询
+func SnapshotMsgText(snapshot types.RuleChainRunSnapshot) string {
+	var texts []string
+	var exists = make(map[string]struct{})
+	var add = func(v string) {
+		if _, ok := exists[v]; ok || v == "" {
+			return
+		}
+		exists[v] = struct{}{}
+		texts = append(texts, v)
+	}
+	for _, item := range snapshot.Logs {
+		for _, msg := range []types.RuleMsg{item.InMsg, item.OutMsg} {
+			add(msg.Data)
+			if len(msg.Metadata) > 0 {
+				v, _ := json.Marshal(msg.Metadata)
+				add(string(v))
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// MatchSnapshot 运行快照是否满足查询条件，不检查规则链ID
+func MatchSnapshot(snapshot types.RuleChainRunSnapshot, query model.RunSnapshotQuery) bool {
+	if query.StartTime > 0 && snapshot.StartTs < query.StartTime {
+		return false
+	}
+	if query.EndTime > 0 && snapshot.StartTs >= query.EndTime {
+		return false
+	}
+	if query.Status != "" && SnapshotStatus(snapshot) != query.Status {
+		return false
+	}
+	if query.MsgType != "" && SnapshotMsgType(snapshot) != query.MsgType {
+		return false
+	}
+	if query.MsgId != "" && SnapshotMsgId(snapshot) != query.MsgId {
+		return false
+	}
+	if query.NodeId != "" {
+		var found bool
+		for _, nodeId := range SnapshotNodeIds(snapshot) {
+			if nodeId == query.NodeId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if query.ReplayOf != "" && SnapshotReplayOf(snapshot) != query.ReplayOf {
+		return false
+	}
+	if query.Keyword != "" && !strings.Contains(SnapshotMsgText(snapshot), query.Keyword) {
+		return false
+	}
+	return true
+}
+
+// SortSnapshots 按查询条件排序运行快照
+func SortSnapshots(snapshots []types.RuleChainRunSnapshot, query model.RunSnapshotQuery) {
+	var value = func(snapshot types.RuleChainRunSnapshot) int64 {
+		switch query.Sort {
+		case model.RunSortEndTs:
+			return snapshot.EndTs
+		case model.RunSortDuration:
+			return snapshot.EndTs - snapshot.StartTs
+		default:
+			return snapshot.StartTs
+		}
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if query.Asc {
+			return value(snapshots[i]) < value(snapshots[j])
+		}
+		return value(snapshots[i]) > value(snapshots[j])
+	})
+}
+
+// ExpiredSnapshots 按保留策略计算需要清理的运行快照或者节点调试数据，metas需要按开始时间降序
+// 从最新的快照开始保留，超过最长保留时间、条数或者字节数之后的快照都需要清理
+func ExpiredSnapshots(metas []model.SnapshotMeta, policy model.RetentionPolicy, now time.Time) []model.SnapshotMeta {
+	var expired []model.SnapshotMeta
+	var cutoff int64
+	if policy.MaxAge > 0 {
+		cutoff = now.Add(-policy.MaxAge).UnixMilli()
+	}
+	var bytes int64
+	for i, meta := range metas {
+		bytes += meta.Size
+		if (cutoff > 0 && meta.StartTs < cutoff) ||
+			(policy.MaxCount > 0 && i >= policy.MaxCount) ||
+			(policy.MaxBytes > 0 && bytes > policy.MaxBytes) {
+			expired = append(expired, meta)
+		}
+	}
+	return expired
+}
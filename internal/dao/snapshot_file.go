@@ -0,0 +1,303 @@
+package dao
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/utils/file"
+	"sort"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/fs"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// FileSnapshotStore 基于文件的运行快照存储，快照保存在{data_dir}/workflows/{username}/runs/{chainId}/{id}
+type FileSnapshotStore struct {
+	config config.Config
+}
+
+func NewFileSnapshotStore(config config.Config) *FileSnapshotStore {
+	return &FileSnapshotStore{
+		config: config,
+	}
+}
+
+// Save 保存运行快照到文件
+func (s *FileSnapshotStore) Save(username, chainId string, snapshot types.RuleChainRunSnapshot) error {
+	pathStr := s.runPath(username, chainId)
+	//创建文件夹
+	_ = fs.CreateDirs(pathStr)
+	//保存到文件
+	if byteV, err := json.Marshal(snapshot); err != nil {
+		logger.Logger.Printf("dao/FileSnapshotStore:Save marshal error%s", err.Error())
+		return err
+	} else {
+		v, _ := json.Format(byteV)
+		filePath := filepath.Join(pathStr, snapshot.Id)
+		if _, err = os.Stat(filePath); err == nil {
+			return fmt.Errorf("%w: %s", ErrSnapshotExists, snapshot.Id)
+		}
+		if err = fs.SaveFile(filePath, v); err != nil {
+			logger.Logger.Printf("dao/FileSnapshotStore:Save save file error%s", err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete 删除运行快照文件，快照不存在返回ErrNotFound
+func (s *FileSnapshotStore) Delete(username string, chainId, id string) error {
+	if !isSnapshotFileName(id) {
+		return constants.ErrNotFound
+	}
+	err := os.Remove(path.Join(s.runPath(username, chainId), id))
+	if os.IsNotExist(err) {
+		return constants.ErrNotFound
+	}
+	return err
+}
+
+func (s *FileSnapshotStore) DeleteByChainId(username string, chainId string) error {
+	return os.RemoveAll(s.runPath(username, chainId))
+}
+
+// List 没有过滤条件时按文件名时间戳分页，只读取当前页文件，否则需要读取所有快照文件后过滤排序
+func (s *FileSnapshotStore) List(username string, query model.RunSnapshotQuery) ([]types.RuleChainRunSnapshot, int, error) {
+	var snapshots []types.RuleChainRunSnapshot
+	// 获取目录下所有运行日志文件
+	files, err := s.listFiles(username, query.ChainId)
+	if err != nil {
+		return snapshots, 0, nil
+	}
+	if !isFilterQuery(query) {
+		// 按文件时间戳排序
+		fileWithTimestamps := file.SortFilesByTimestamp(files)
+		start, end := pageRange(len(files), query.Current, query.Size)
+		// 遍历文件，每个文件对应一条 RuleChainRunSnapshot 记录
+		for _, item := range fileWithTimestamps[start:end] {
+			snapshot, err := ReadSnapshotFile(item.Path)
+			if err != nil {
+				return nil, 0, err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return snapshots, len(files), nil
+	}
+	var matched []types.RuleChainRunSnapshot
+	for _, item := range files {
+		snapshot, err := ReadSnapshotFile(item)
+		if err != nil {
+			logger.Logger.Printf("dao/FileSnapshotStore:List read file=%s error%s", item, err.Error())
+			continue
+		}
+		if MatchSnapshot(snapshot, query) {
+			matched = append(matched, snapshot)
+		}
+	}
+	SortSnapshots(matched, query)
+	start, end := pageRange(len(matched), query.Current, query.Size)
+	return append(snapshots, matched[start:end]...), len(matched), nil
+}
+
+// isSnapshotFileName 快照ID是否是合法的文件名，不能包含路径
+func isSnapshotFileName(id string) bool {
+	return id != "" && id != "." && id != ".." && filepath.Base(id) == id
+}
+
+// Get 获取运行快照，规则链ID为空则在用户所有规则链中查找
+func (s *FileSnapshotStore) Get(username, chainId, snapshotId string) (types.RuleChainRunSnapshot, error) {
+	if !isSnapshotFileName(snapshotId) {
+		return types.RuleChainRunSnapshot{}, constants.ErrNotFound
+	}
+	if chainId == "" {
+		chains, _ := os.ReadDir(s.runPath(username, ""))
+		for _, chain := range chains {
+			filePath := path.Join(s.runPath(username, chain.Name()), snapshotId)
+			if _, err := os.Stat(filePath); chain.IsDir() && err == nil {
+				return ReadSnapshotFile(filePath)
+			}
+		}
+		return types.RuleChainRunSnapshot{}, constants.ErrNotFound
+	}
+	snapshot, err := ReadSnapshotFile(path.Join(s.runPath(username, chainId), snapshotId))
+	if os.IsNotExist(err) {
+		return snapshot, constants.ErrNotFound
+	}
+	return snapshot, err
+}
+
+// Range 遍历所有用户的运行快照文件，用于统计存储占用和迁移到其他存储
+func (s *FileSnapshotStore) Range(f func(username, chainId, filePath string) bool) error {
+	userPath := path.Join(s.config.DataDir, constants.DirWorkflows)
+	users, err := os.ReadDir(userPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, user := range users {
+		if !user.IsDir() {
+			continue
+		}
+		runPath := path.Join(userPath, user.Name(), constants.DirWorkflowsRun)
+		chains, err := os.ReadDir(runPath)
+		if err != nil {
+			continue
+		}
+		for _, chain := range chains {
+			if !chain.IsDir() {
+				continue
+			}
+			entries, err := os.ReadDir(path.Join(runPath, chain.Name()))
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				if !f(user.Name(), chain.Name(), path.Join(runPath, chain.Name(), entry.Name())) {
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Usage 统计快照文件数量和大小，开始时间使用文件修改时间
+func (s *FileSnapshotStore) Usage(username string) ([]model.SnapshotUsage, error) {
+	var usages []model.SnapshotUsage
+	var index = make(map[string]int)
+	err := s.Range(func(user, chainId, filePath string) bool {
+		if username != "" && user != username {
+			return true
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return true
+		}
+		key := user + "/" + chainId
+		i, ok := index[key]
+		if !ok {
+			i = len(usages)
+			index[key] = i
+			usages = append(usages, model.SnapshotUsage{Username: user, ChainId: chainId})
+		}
+		usage := &usages[i]
+		ts := info.ModTime().UnixMilli()
+		if usage.Count == 0 || ts < usage.OldestTs {
+			usage.OldestTs = ts
+		}
+		if ts > usage.NewestTs {
+			usage.NewestTs = ts
+		}
+		usage.Count++
+		usage.Bytes += info.Size()
+		return true
+	})
+	return usages, err
+}
+
+// Purge 按文件修改时间降序计算需要清理的快照文件并删除
+func (s *FileSnapshotStore) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (model.PurgeResult, error) {
+	var result model.PurgeResult
+	if policy.IsEmpty() {
+		return result, nil
+	}
+	pathStr := s.runPath(username, chainId)
+	entries, err := os.ReadDir(pathStr)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	var metas []model.SnapshotMeta
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			metas = append(metas, model.SnapshotMeta{Id: entry.Name(), StartTs: info.ModTime().UnixMilli(), Size: info.Size()})
+		}
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		if metas[i].StartTs == metas[j].StartTs {
+			return metas[i].Id > metas[j].Id
+		}
+		return metas[i].StartTs > metas[j].StartTs
+	})
+	for _, meta := range ExpiredSnapshots(metas, policy, now) {
+		if err := os.Remove(path.Join(pathStr, meta.Id)); err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+		result.Deleted++
+		result.Bytes += meta.Size
+	}
+	return result, nil
+}
+
+// runPath 运行快照目录，chainId为空则返回用户所有规则链运行快照目录
+func (s *FileSnapshotStore) runPath(username, chainId string) string {
+	var paths = []string{s.config.DataDir, constants.DirWorkflows, username, constants.DirWorkflowsRun}
+	if chainId != "" {
+		paths = append(paths, chainId)
+	}
+	return path.Join(paths...)
+}
+
+func (s *FileSnapshotStore) listFiles(username, chainId string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(s.runPath(username, chainId), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ReadSnapshotFile 读取运行快照文件
+func ReadSnapshotFile(filePath string) (types.RuleChainRunSnapshot, error) {
+	var snapshot types.RuleChainRunSnapshot
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
+
+// isFilterQuery 是否有过滤条件或者非默认排序
+func isFilterQuery(query model.RunSnapshotQuery) bool {
+	return query.StartTime > 0 || query.EndTime > 0 || query.Status != "" || query.MsgType != "" || query.MsgId != "" ||
+		query.NodeId != "" || query.Keyword != "" || query.ReplayOf != "" || (query.Sort != "" && query.Sort != model.RunSortStartTs) || query.Asc
+}
+
+// pageRange 计算分页的起始索引
+func pageRange(total, current, size int) (int, int) {
+	start := (current - 1) * size
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return start, end
+}
@@ -0,0 +1,39 @@
+package dao
+
+import (
+	"errors"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/constants"
+	"testing"
+)
+
+func TestFileSnapshotStoreDelete(t *testing.T) {
+	store := NewFileSnapshotStore(config.Config{DataDir: t.TempDir()})
+	for _, id := range []string{"s1", "s2"} {
+		if err := store.Save("u1", "c1", newTestSnapshot(id, 1000, "n1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		chainId string
+		id      string
+		wantErr error
+	}{
+		{name: "other chain", chainId: "c2", id: "s1", wantErr: constants.ErrNotFound},
+		{name: "parent dir", chainId: "c1", id: "..", wantErr: constants.ErrNotFound},
+		{name: "empty id", chainId: "c1", id: "", wantErr: constants.ErrNotFound},
+		{name: "delete", chainId: "c1", id: "s1"},
+		{name: "delete again", chainId: "c1", id: "s1", wantErr: constants.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Delete("u1", tt.chainId, tt.id); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Delete() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := store.Get("u1", "c1", "s2"); err != nil {
+		t.Errorf("Get() s2 error = %v", err)
+	}
+}
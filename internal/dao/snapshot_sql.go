@@ -0,0 +1,257 @@
+package dao
+
+import (
+	"fmt"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"strings"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SqlSnapshotStore 基于数据库run_snapshot表的运行快照存储，查询条件字段建立索引，快照内容以json保存
+type SqlSnapshotStore struct {
+}
+
+func NewSqlSnapshotStore() *SqlSnapshotStore {
+	return &SqlSnapshotStore{}
+}
+
+// Save 保存运行快照，同一用户快照ID已存在则返回错误
+func (s *SqlSnapshotStore) Save(username, chainId string, snapshot types.RuleChainRunSnapshot) error {
+	_, err := s.insert(username, chainId, snapshot, false)
+	return err
+}
+
+// Import 导入运行快照，用于迁移，同一用户快照ID已存在则跳过并返回false
+func (s *SqlSnapshotStore) Import(username, chainId string, snapshot types.RuleChainRunSnapshot) (bool, error) {
+	return s.insert(username, chainId, snapshot, true)
+}
+
+// insert 保存运行快照，skipExists为true时快照ID已存在则跳过，否则返回ErrSnapshotExists
+func (s *SqlSnapshotStore) insert(username, chainId string, snapshot types.RuleChainRunSnapshot, skipExists bool) (bool, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return false, err
+	}
+	record := model.RunSnapshot{
+		SnapshotId:  snapshot.Id,
+		Owner:       username,
+		RuleChainId: chainId,
+		MsgId:       SnapshotMsgId(snapshot),
+		MsgType:     SnapshotMsgType(snapshot),
+		MsgText:     SnapshotMsgText(snapshot),
+		ReplayOf:    SnapshotReplayOf(snapshot),
+		Status:      SnapshotStatus(snapshot),
+		StartTs:     snapshot.StartTs,
+		EndTs:       snapshot.EndTs,
+		Data:        string(data),
+		Size:        int64(len(data)),
+	}
+	var inserted bool
+	err = model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if skipExists {
+				return nil
+			}
+			return fmt.Errorf("%w: %s", ErrSnapshotExists, snapshot.Id)
+		}
+		inserted = true
+		var nodes []model.RunSnapshotNode
+		for _, nodeId := range SnapshotNodeIds(snapshot) {
+			nodes = append(nodes, model.RunSnapshotNode{SnapshotId: snapshot.Id, Owner: username, NodeId: nodeId})
+		}
+		if len(nodes) == 0 {
+			return nil
+		}
+		return tx.Create(&nodes).Error
+	})
+	return inserted, err
+}
+
+func (s *SqlSnapshotStore) Get(username, chainId, id string) (types.RuleChainRunSnapshot, error) {
+	var snapshot types.RuleChainRunSnapshot
+	var record model.RunSnapshot
+	db := model.DBClient.Client.Model(&model.RunSnapshot{}).Where("owner = ? AND snapshot_id = ?", username, id)
+	if chainId != "" {
+		db = db.Where("rule_chain_id = ?", chainId)
+	}
+	if err := db.Limit(1).Find(&record).Error; err != nil {
+		return snapshot, err
+	}
+	if record.ID == 0 {
+		return snapshot, constants.ErrNotFound
+	}
+	err := json.Unmarshal([]byte(record.Data), &snapshot)
+	return snapshot, err
+}
+
+func (s *SqlSnapshotStore) List(username string, query model.RunSnapshotQuery) ([]types.RuleChainRunSnapshot, int, error) {
+	var snapshots []types.RuleChainRunSnapshot
+	db := model.DBClient.Client.Model(&model.RunSnapshot{}).Where("owner = ?", username)
+	if query.ChainId != "" {
+		db = db.Where("rule_chain_id = ?", query.ChainId)
+	}
+	if query.StartTime > 0 {
+		db = db.Where("start_ts >= ?", query.StartTime)
+	}
+	if query.EndTime > 0 {
+		db = db.Where("start_ts < ?", query.EndTime)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.MsgType != "" {
+		db = db.Where("msg_type = ?", query.MsgType)
+	}
+	if query.MsgId != "" {
+		db = db.Where("msg_id = ?", query.MsgId)
+	}
+	if query.NodeId != "" {
+		nodeQuery := model.DBClient.Client.Model(&model.RunSnapshotNode{}).Select("snapshot_id").Where("owner = ? AND node_id = ?", username, query.NodeId)
+		db = db.Where("snapshot_id IN (?)", nodeQuery)
+	}
+	if query.ReplayOf != "" {
+		db = db.Where("replay_of = ?", query.ReplayOf)
+	}
+	if query.Keyword != "" {
+		db = db.Where("msg_text LIKE ? ESCAPE '!'", "%"+escapeLike(query.Keyword)+"%")
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	current := query.Current
+	if current < 1 {
+		current = 1
+	}
+	var records []model.RunSnapshot
+	if err := db.Order(snapshotOrder(query)).Offset((current - 1) * query.Size).Limit(query.Size).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, record := range records {
+		var snapshot types.RuleChainRunSnapshot
+		if err := json.Unmarshal([]byte(record.Data), &snapshot); err != nil {
+			return nil, 0, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, int(total), nil
+}
+
+// Delete 删除规则链的运行快照及其节点索引，快照不存在返回ErrNotFound
+func (s *SqlSnapshotStore) Delete(username, chainId, id string) error {
+	return model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		snapshotQuery := tx.Model(&model.RunSnapshot{}).Select("snapshot_id").Where("owner = ? AND rule_chain_id = ? AND snapshot_id = ?", username, chainId, id)
+		if err := tx.Where("owner = ? AND snapshot_id IN (?)", username, snapshotQuery).Delete(&model.RunSnapshotNode{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("owner = ? AND rule_chain_id = ? AND snapshot_id = ?", username, chainId, id).Delete(&model.RunSnapshot{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *SqlSnapshotStore) DeleteByChainId(username, chainId string) error {
+	return model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		snapshotQuery := tx.Model(&model.RunSnapshot{}).Select("snapshot_id").Where("owner = ? AND rule_chain_id = ?", username, chainId)
+		if err := tx.Where("owner = ? AND snapshot_id IN (?)", username, snapshotQuery).Delete(&model.RunSnapshotNode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("owner = ? AND rule_chain_id = ?", username, chainId).Delete(&model.RunSnapshot{}).Error
+	})
+}
+
+func (s *SqlSnapshotStore) Usage(username string) ([]model.SnapshotUsage, error) {
+	var usages []model.SnapshotUsage
+	db := model.DBClient.Client.Model(&model.RunSnapshot{}).
+		Select("owner, rule_chain_id, COUNT(*) AS count, SUM(size) AS bytes, MIN(start_ts) AS oldest_ts, MAX(start_ts) AS newest_ts")
+	if username != "" {
+		db = db.Where("owner = ?", username)
+	}
+	err := db.Group("owner, rule_chain_id").Order("owner, rule_chain_id").Scan(&usages).Error
+	return usages, err
+}
+
+// Purge 只查询快照元数据计算需要清理的快照，然后分批删除
+func (s *SqlSnapshotStore) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (model.PurgeResult, error) {
+	var result model.PurgeResult
+	if policy.IsEmpty() {
+		return result, nil
+	}
+	var metas []model.SnapshotMeta
+	if err := model.DBClient.Client.Model(&model.RunSnapshot{}).Select("snapshot_id, start_ts, size").
+		Where("owner = ? AND rule_chain_id = ?", username, chainId).
+		Order("start_ts DESC, id DESC").Scan(&metas).Error; err != nil {
+		return result, err
+	}
+	expired := ExpiredSnapshots(metas, policy, now)
+	for start := 0; start < len(expired); start += purgeBatchSize {
+		end := start + purgeBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+		var ids []string
+		var bytes int64
+		for _, meta := range expired[start:end] {
+			ids = append(ids, meta.Id)
+			bytes += meta.Size
+		}
+		if err := model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("owner = ? AND snapshot_id IN ?", username, ids).Delete(&model.RunSnapshotNode{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("owner = ? AND rule_chain_id = ? AND snapshot_id IN ?", username, chainId, ids).Delete(&model.RunSnapshot{}).Error
+		}); err != nil {
+			return result, err
+		}
+		result.Deleted += len(ids)
+		result.Bytes += bytes
+	}
+	return result, nil
+}
+
+// MigrateSnapshotSize 计算历史运行快照的字节数，返回更新的条数
+func MigrateSnapshotSize() (int64, error) {
+	result := model.DBClient.Client.Model(&model.RunSnapshot{}).Where("size = 0 AND data IS NOT NULL AND data <> ''").
+		Update("size", gorm.Expr("LENGTH(data)"))
+	return result.RowsAffected, result.Error
+}
+
+// purgeBatchSize 每批删除的快照数量
+const purgeBatchSize = 500
+
+// snapshotOrder 排序语句
+func snapshotOrder(query model.RunSnapshotQuery) string {
+	var column string
+	switch query.Sort {
+	case model.RunSortEndTs:
+		column = "end_ts"
+	case model.RunSortDuration:
+		column = "(end_ts - start_ts)"
+	default:
+		column = "start_ts"
+	}
+	if query.Asc {
+		return column + " ASC, id ASC"
+	}
+	return column + " DESC, id DESC"
+}
+
+// escapeLike 转义LIKE查询的通配符，使用!作为转义符，兼容mysql对反斜杠的处理
+func escapeLike(v string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(v)
+}
@@ -0,0 +1,150 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"testing"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+// newTestSnapshot 创建运行快照，nodeIds为执行过的节点
+func newTestSnapshot(id string, startTs int64, nodeIds ...string) types.RuleChainRunSnapshot {
+	snapshot := types.RuleChainRunSnapshot{Id: id, StartTs: startTs, EndTs: startTs + 1}
+	for _, nodeId := range nodeIds {
+		snapshot.Logs = append(snapshot.Logs, types.RuleNodeRunLog{
+			Id:    nodeId,
+			InMsg: types.RuleMsg{Id: "msg_" + id, Type: "TEST", Data: "{}"},
+		})
+	}
+	return snapshot
+}
+
+func snapshotIds(snapshots []types.RuleChainRunSnapshot) []string {
+	var ids = []string{}
+	for _, item := range snapshots {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+// newTestSqlSnapshotStore 规则链c1保存s1~s5，奇数快照执行过节点n2，规则链c2和用户u2各保存一个快照
+func newTestSqlSnapshotStore(t *testing.T) *SqlSnapshotStore {
+	t.Helper()
+	newTestDB(t)
+	store := NewSqlSnapshotStore()
+	for i := 1; i <= 5; i++ {
+		nodeIds := []string{"n1"}
+		if i%2 == 1 {
+			nodeIds = append(nodeIds, "n2")
+		}
+		if err := store.Save("u1", "c1", newTestSnapshot(fmt.Sprintf("s%d", i), int64(i*1000), nodeIds...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Save("u1", "c2", newTestSnapshot("s6", 6000, "n2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("u2", "c1", newTestSnapshot("s1", 1000, "n2")); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSqlSnapshotStoreList(t *testing.T) {
+	store := newTestSqlSnapshotStore(t)
+	tests := []struct {
+		name      string
+		query     model.RunSnapshotQuery
+		want      []string
+		wantTotal int
+	}{
+		{name: "first page", query: model.RunSnapshotQuery{ChainId: "c1", Current: 1, Size: 2}, want: []string{"s5", "s4"}, wantTotal: 5},
+		{name: "last page", query: model.RunSnapshotQuery{ChainId: "c1", Current: 3, Size: 2}, want: []string{"s1"}, wantTotal: 5},
+		{name: "page out of range", query: model.RunSnapshotQuery{ChainId: "c1", Current: 4, Size: 2}, want: []string{}, wantTotal: 5},
+		{name: "current defaults to first page", query: model.RunSnapshotQuery{ChainId: "c1", Size: 2}, want: []string{"s5", "s4"}, wantTotal: 5},
+		{name: "asc", query: model.RunSnapshotQuery{ChainId: "c1", Asc: true, Current: 1, Size: 2}, want: []string{"s1", "s2"}, wantTotal: 5},
+		{name: "all chains", query: model.RunSnapshotQuery{Current: 1, Size: 10}, want: []string{"s6", "s5", "s4", "s3", "s2", "s1"}, wantTotal: 6},
+		{name: "time range", query: model.RunSnapshotQuery{ChainId: "c1", StartTime: 2000, EndTime: 4000, Current: 1, Size: 10}, want: []string{"s3", "s2"}, wantTotal: 2},
+		{name: "node id", query: model.RunSnapshotQuery{ChainId: "c1", NodeId: "n2", Current: 1, Size: 2}, want: []string{"s5", "s3"}, wantTotal: 3},
+		{name: "node id other user", query: model.RunSnapshotQuery{NodeId: "n2", Current: 1, Size: 10}, want: []string{"s6", "s5", "s3", "s1"}, wantTotal: 4},
+		{name: "msg id", query: model.RunSnapshotQuery{MsgId: "msg_s2", Current: 1, Size: 10}, want: []string{"s2"}, wantTotal: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots, total, err := store.List("u1", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshotIds(snapshots); !reflect.DeepEqual(got, tt.want) || total != tt.wantTotal {
+				t.Errorf("List() = %v, %d, want %v, %d", got, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSqlSnapshotStoreSave(t *testing.T) {
+	store := newTestSqlSnapshotStore(t)
+	if err := store.Save("u1", "c1", newTestSnapshot("s1", 1000)); !errors.Is(err, ErrSnapshotExists) {
+		t.Errorf("Save() error = %v, want %v", err, ErrSnapshotExists)
+	}
+	if ok, err := store.Import("u1", "c1", newTestSnapshot("s1", 1000)); err != nil || ok {
+		t.Errorf("Import() = %v, %v, want skipped", ok, err)
+	}
+	if snapshot, err := store.Get("u1", "", "s6"); err != nil || snapshot.Id != "s6" {
+		t.Errorf("Get() = %+v, %v", snapshot, err)
+	}
+	if _, err := store.Get("u1", "c1", "s6"); !errors.Is(err, constants.ErrNotFound) {
+		t.Errorf("Get() other chain error = %v, want %v", err, constants.ErrNotFound)
+	}
+}
+
+func TestSqlSnapshotStoreDelete(t *testing.T) {
+	store := newTestSqlSnapshotStore(t)
+	countNodes := func(username, snapshotId string) int64 {
+		var count int64
+		model.DBClient.Client.Model(&model.RunSnapshotNode{}).Where("owner = ? AND snapshot_id = ?", username, snapshotId).Count(&count)
+		return count
+	}
+
+	//规则链不匹配不删除快照和节点索引
+	if err := store.Delete("u1", "c2", "s1"); !errors.Is(err, constants.ErrNotFound) {
+		t.Errorf("Delete() other chain error = %v, want %v", err, constants.ErrNotFound)
+	}
+	if got := countNodes("u1", "s1"); got != 2 {
+		t.Errorf("nodes of s1 = %d, want 2", got)
+	}
+
+	if err := store.Delete("u1", "c1", "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("u1", "c1", "s1"); !errors.Is(err, constants.ErrNotFound) {
+		t.Errorf("Get() deleted error = %v, want %v", err, constants.ErrNotFound)
+	}
+	if got := countNodes("u1", "s1"); got != 0 {
+		t.Errorf("nodes of deleted s1 = %d, want 0", got)
+	}
+	if err := store.Delete("u1", "c1", "s1"); !errors.Is(err, constants.ErrNotFound) {
+		t.Errorf("Delete() again error = %v, want %v", err, constants.ErrNotFound)
+	}
+	//其他用户相同快照ID不受影响
+	if got := countNodes("u2", "s1"); got != 1 {
+		t.Errorf("nodes of other user s1 = %d, want 1", got)
+	}
+	if _, err := store.Get("u2", "c1", "s1"); err != nil {
+		t.Errorf("Get() other user error = %v", err)
+	}
+
+	if err := store.DeleteByChainId("u1", "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if snapshots, total, _ := store.List("u1", model.RunSnapshotQuery{Current: 1, Size: 10}); total != 1 || snapshotIds(snapshots)[0] != "s6" {
+		t.Errorf("List() after DeleteByChainId = %v, %d", snapshotIds(snapshots), total)
+	}
+	if got := countNodes("u1", "s3"); got != 0 {
+		t.Errorf("nodes of s3 = %d, want 0", got)
+	}
+}
@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"ruleGoProject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 查询规则链所有测试用例，按创建顺序
+func ListRuleTestCase(owner, ruleChainId string) ([]model.RuleTestCase, error) {
+	re := make([]model.RuleTestCase, 0)
+	err := model.DBClient.Client.Model(&model.RuleTestCase{}).
+		Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Order("id").Find(&re).Error
+	return re, err
+}
+
+// 保存规则链测试用例，覆盖原有用例
+func SaveRuleTestCases(owner, ruleChainId string, cases []model.RuleTestCase) error {
+	return model.DBClient.Client.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Delete(&model.RuleTestCase{}).Error; err != nil {
+			return err
+		}
+		for i := range cases {
+			cases[i].ID = 0
+			cases[i].Owner = owner
+			cases[i].RuleChainId = ruleChainId
+			if err := tx.Create(&cases[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 删除规则链所有测试用例
+func DeleteRuleTestCaseByRuleChainId(owner, ruleChainId string) error {
+	return model.DBClient.Client.Where("owner = ? AND rule_chain_id = ?", owner, ruleChainId).Delete(&model.RuleTestCase{}).Error
+}
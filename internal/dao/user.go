@@ -1,9 +1,15 @@
 package dao
 
 import (
+	"crypto/subtle"
 	"path"
 	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
 	"ruleGoProject/internal/model"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -27,31 +33,67 @@ func NewUserDao(config config.Config) (*UserDao, error) {
 	}, nil
 }
 
+// CreateUser 创建用户，密码使用bcrypt加盐哈希后保存
 func (d *UserDao) CreateUser(user model.User) error {
-	return d.fs.Save(UsersSectionName, user.Username, user.Password)
+	return d.SavePassword(user.Username, user.Password)
+}
+
+// SavePassword 保存用户密码，密码使用bcrypt加盐哈希后保存
+func (d *UserDao) SavePassword(username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return d.fs.Save(UsersSectionName, username, string(hash))
 }
 
 // ValidatePassword 验证密码
+// 兼容旧版本明文存储的密码，验证通过后自动升级为哈希存储
 func (d *UserDao) ValidatePassword(username, password string) bool {
 	if v := d.fs.Get(UsersSectionName, username); v == "" {
 		return false
+	} else if isPasswordHash(v) {
+		return bcrypt.CompareHashAndPassword([]byte(v), []byte(password)) == nil
+	} else if subtle.ConstantTimeCompare([]byte(v), []byte(password)) == 1 {
+		if err := d.SavePassword(username, password); err != nil {
+			logger.Logger.Printf("dao/UserDao:ValidatePassword upgrade password hash error%s", err.Error())
+		}
+		return true
 	} else {
-		return v == password
+		return false
 	}
 }
 
+// Exists 用户是否存在
+func (d *UserDao) Exists(username string) bool {
+	return d.fs.Get(UsersSectionName, username) != ""
+}
+
+// PasswordHash 获取用户保存的密码哈希，用户不存在返回空
+func (d *UserDao) PasswordHash(username string) string {
+	return d.fs.Get(UsersSectionName, username)
+}
+
 func (d *UserDao) Delete(username string) error {
 	return d.fs.Delete(UsersSectionName, username)
 }
 
+// List 获取所有用户，不返回密码
 func (d *UserDao) List() []model.User {
-	var users []model.User
+	var users = make([]model.User, 0)
 	values := d.fs.GetAll(UsersSectionName)
-	for key, value := range values {
+	for key := range values {
 		users = append(users, model.User{
 			Username: key,
-			Password: value,
 		})
 	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
 	return users
 }
+
+// isPasswordHash 是否是bcrypt哈希值
+func isPasswordHash(v string) bool {
+	return strings.HasPrefix(v, "$2a$") || strings.HasPrefix(v, "$2b$") || strings.HasPrefix(v, "$2y$")
+}
@@ -9,11 +9,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"sync"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/silenceper/log"
 	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormLogger "gorm.io/gorm/logger"
@@ -25,6 +28,20 @@ const (
 	SSLMODE_DISABLE string = "disable"
 )
 
+// Driver 数据库驱动
+const (
+	DriverPostgres = `postgres`
+	DriverMysql    = `mysql`
+	// DriverSqlite 内嵌sqlite，DBname为数据库文件路径
+	DriverSqlite = `sqlite`
+)
+
+// 数据库健康状态，GetHealthStatus返回值
+const (
+	DBHealth   = `health`
+	DBUnhealth = `unhealth`
+)
+
 // LogMode 日志等级
 const (
 	LogModeSilent = `silent`
@@ -35,31 +52,43 @@ const (
 
 var (
 	// log                logger.ILogger
-	ErrConfigJSONParse error = errors.New("configParseError")
+	ErrConfigJSONParse  error = errors.New("configParseError")
+	ErrDriverNotSupport error = errors.New("database driver not support")
 )
 
 var pgOnce sync.Once
 var DBClient *DB
 
+// migrateModels 需要自动创建表结构的模型
+var migrateModels = []interface{}{
+	&Regulation{},
+	&RuleRevision{},
+	&MsgDedupe{},
+	&ScheduleFiring{},
+	&RunSnapshot{},
+	&RunSnapshotNode{},
+	&RuleTestCase{},
+	&NodeDebugLog{},
+}
+
+// legacyIndexes 旧版本创建的唯一索引，自动迁移时删除
+var legacyIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{model: &Regulation{}, name: "regulation_rule_chain_id_unique_idx"},
+	{model: &RunSnapshot{}, name: "run_snapshot_snapshot_id_unique_idx"},
+}
+
 // StartDB 启动并初始化数据库
-func StartDB() error {
+func StartDB(config *ORMConfig) error {
 	var err error
 	pgOnce.Do(func() {
-
-		client, err := NewDBWithStruct(&ORMConfig{
-			User:            "rust",
-			Password:        "rust",
-			Host:            "127.0.0.1",
-			Port:            5432,
-			DBname:          "rule_go",
-			MaxIdleConns:    4,
-			MaxOpenConns:    4,
-			ConnMaxLifeTime: 60 * time.Second,
-			LogMode:         "info",
-		})
+		var client *DB
+		client, err = NewDBWithStruct(config)
 		if err != nil {
 			log.Infof("PG_CONGIG_ERR %s", err.Error())
-			panic(err)
+			return
 		}
 		DBClient = client
 	})
@@ -67,17 +96,18 @@ func StartDB() error {
 }
 
 type ORMConfig struct {
+	Driver          string        `json:"driver"`          // 可选，默认postgres，可传入常量定义例如：DriverPostgres、DriverMysql、DriverSqlite
 	User            string        `json:"user"`            // 必须
 	Password        string        `json:"password"`        // 必须
-	Host            string        `json:"host"`            // 必须
-	Port            int32         `json:"port"`            // 可选，默认5432
-	DBname          string        `json:"dbname"`          // 必须
+	Host            string        `json:"host"`            // 必须，sqlite不需要
+	Port            int32         `json:"port"`            // 可选，postgres默认5432，mysql默认3306
+	DBname          string        `json:"dbname"`          // 必须，sqlite为数据库文件路径
 	SSlMode         string        `json:"sslmode"`         // 可选，默认SSLMODE_DISABLE
 	MaxIdleConns    int           `json:"maxIdleConns"`    // 可选，默认1
 	MaxOpenConns    int           `json:"maxOpenConns"`    // 可选，默认15
 	ConnMaxLifeTime time.Duration `json:"connMaxLifeTime"` // 可选，默认time.Hour
 	ConnMaxIdleTime time.Duration `json:"connMaxIdleTime"` // 可选，默认10 * time.Minute
-	timeZone        string        `json:"-"`               // 固定Asia/Shanghai
+	TimeZone        string        `json:"timeZone"`        // 可选，默认Asia/Shanghai
 	Callback        func(orm *DB) `json:"callback"`        // 初始化后需要执行的方法，比如注册callback之类的
 	LogMode         string        `json:"logMode"`         // 可选，默认warn，可传入常量定义例如：LogModeSilent、LogModeError、LogModeWarn、LogModeInfo
 }
@@ -110,17 +140,28 @@ func LogModeString2GormLogLevel(mode string) gormLogger.LogLevel {
 func NewDBWithStruct(config *ORMConfig) (*DB, error) {
 	c := &DB{}
 	defaultConfig := getDefaultConfig()
+	if config.Driver == "" {
+		config.Driver = defaultConfig.Driver
+	}
+	if config.Port == 0 {
+		config.Port = defaultPort(config.Driver)
+	}
 	if config.SSlMode == "" {
 		config.SSlMode = defaultConfig.SSlMode
 	}
-	config.timeZone = defaultConfig.timeZone
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s timezone=%s", config.Host, config.User, config.Password, config.DBname, config.Port, config.SSlMode, config.timeZone)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	if config.TimeZone == "" {
+		config.TimeZone = defaultConfig.TimeZone
+	}
+	dialector, err := newDialector(config)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger:         gormLogger.Default.LogMode(LogModeString2GormLogLevel(config.LogMode)),
 		NamingStrategy: schema.NamingStrategy{SingularTable: true},
 	})
 	if err != nil {
-		log.Errorf("dbInitFailed%s%v", err.Error(), config)
+		log.Errorf("dbInitFailed%s host=%s dbname=%s", err.Error(), config.Host, config.DBname)
 		return nil, err
 	}
 	sqlDB, _ := db.DB()
@@ -137,12 +178,63 @@ func NewDBWithStruct(config *ORMConfig) (*DB, error) {
 	}
 	c.Client = db
 	c.config = config
+	//自动创建表结构
+	if err := c.AutoMigrate(); err != nil {
+		log.Errorf("dbAutoMigrateFailed%s", err.Error())
+		return nil, err
+	}
 	if config.Callback != nil {
 		config.Callback(c)
 	}
 	return c, nil
 }
 
+// newDialector 根据驱动创建gorm dialector
+func newDialector(config *ORMConfig) (gorm.Dialector, error) {
+	switch config.Driver {
+	case DriverPostgres:
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s timezone=%s", config.Host, config.User, config.Password, config.DBname, config.Port, config.SSlMode, config.TimeZone)
+		return postgres.Open(dsn), nil
+	case DriverMysql:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=%s", config.User, config.Password, config.Host, config.Port, config.DBname, url.QueryEscape(config.TimeZone))
+		return mysql.Open(dsn), nil
+	case DriverSqlite:
+		//开启WAL，避免并发写入时database is locked
+		return sqlite.Open(config.DBname + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrDriverNotSupport, config.Driver)
+	}
+}
+
+// defaultPort 驱动默认端口
+func defaultPort(driver string) int32 {
+	switch driver {
+	case DriverMysql:
+		return 3306
+	case DriverSqlite:
+		return 0
+	default:
+		return 5432
+	}
+}
+
+// AutoMigrate 根据模型自动创建或者更新表结构
+func (d *DB) AutoMigrate() error {
+	if err := d.Client.AutoMigrate(migrateModels...); err != nil {
+		return err
+	}
+	//旧版本规则链ID和快照ID全局唯一，改为同一用户内唯一
+	migrator := d.Client.Migrator()
+	for _, item := range legacyIndexes {
+		if migrator.HasIndex(item.model, item.name) {
+			if err := migrator.DropIndex(item.model, item.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func NewDBWithJson(c interface{}) (*DB, error) {
 	value, err := json.Marshal(c)
 	if err != nil {
@@ -158,7 +250,8 @@ func NewDBWithJson(c interface{}) (*DB, error) {
 
 func getDefaultConfig() *ORMConfig {
 	return &ORMConfig{
-		timeZone:        TIMEZONE_CHINA,
+		Driver:          DriverPostgres,
+		TimeZone:        TIMEZONE_CHINA,
 		SSlMode:         SSLMODE_DISABLE,
 		MaxIdleConns:    1,
 		MaxOpenConns:    15,
@@ -172,18 +265,23 @@ func (d *DB) GetHealthStatus(ctx context.Context) string {
 	gormDB := d.Client.WithContext(ctx)
 	sqlDB, err := gormDB.DB()
 	if err != nil {
-		return "unhealth"
+		return DBUnhealth
 	}
 	// verifies a connection to the database is still alive
-	err = sqlDB.Ping()
+	err = sqlDB.PingContext(ctx)
 	if err != nil {
-		return "unhealth"
+		return DBUnhealth
 	}
 	err = gormDB.Raw(`select 1`).Error
 	if err != nil {
-		return "unhealth"
+		return DBUnhealth
 	}
-	return "health"
+	return DBHealth
+}
+
+// Driver 数据库驱动
+func (d *DB) Driver() string {
+	return d.config.Driver
 }
 
 // 获取目前数据库状态参数
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoMigrateDropLegacyIndexes(t *testing.T) {
+	config := &ORMConfig{Driver: DriverSqlite, DBname: filepath.Join(t.TempDir(), "test.db"), LogMode: LogModeSilent}
+	db, err := NewDBWithStruct(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	//模拟旧版本的全局唯一索引
+	for _, sql := range []string{
+		"CREATE UNIQUE INDEX regulation_rule_chain_id_unique_idx ON regulation (rule_chain_id)",
+		"CREATE UNIQUE INDEX run_snapshot_snapshot_id_unique_idx ON run_snapshot (snapshot_id)",
+	} {
+		if err := db.Client.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	migrator := db.Client.Migrator()
+	for _, item := range legacyIndexes {
+		if migrator.HasIndex(item.model, item.name) {
+			t.Errorf("legacy index %s is not dropped", item.name)
+		}
+	}
+	//不同用户可以使用相同的规则链ID
+	for _, owner := range []string{"u1", "u2"} {
+		if err := db.Client.Create(&Regulation{Owner: owner, RuleChainId: "c1"}).Error; err != nil {
+			t.Errorf("create regulation of %s error: %v", owner, err)
+		}
+	}
+	if err := db.Client.Create(&Regulation{Owner: "u1", RuleChainId: "c1"}).Error; err == nil {
+		t.Error("duplicate regulation of the same owner is created")
+	}
+}
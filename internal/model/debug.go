@@ -0,0 +1,48 @@
+package model
+
+import "github.com/rulego/rulego/api/types"
+
+// DebugDataQuery 节点调试数据查询条件，空值表示不过滤
+type DebugDataQuery struct {
+	// 规则链ID
+	ChainId string
+	// 节点ID
+	NodeId string
+	// 消息ID
+	MsgId string
+	// 开始时间范围，毫秒，包含
+	StartTime int64
+	// 结束时间范围，毫秒，不包含
+	EndTime int64
+	// 是否按时间升序，默认降序
+	Asc bool
+	// 当前页，从1开始
+	Current int
+	// 每页条数，0表示所有
+	Size int
+}
+
+// NodeDebugLog 节点调试数据，debug_store = sql 时使用
+type NodeDebugLog struct {
+	ID uint `gorm:"primarykey"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;index:node_debug_log_owner_chain_ts_idx,priority:1"`
+	// 规则链ID
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;not null;index:node_debug_log_owner_chain_ts_idx,priority:2"`
+	// 节点ID
+	NodeId string `gorm:"column:node_id;size:64"`
+	// 消息ID
+	MsgId string `gorm:"column:msg_id;size:128;index:node_debug_log_msg_id_idx"`
+	// 流向 IN/OUT/Log
+	FlowType string `gorm:"column:flow_type;size:16"`
+	// 发生时间，毫秒
+	Ts int64 `gorm:"column:ts;index:node_debug_log_owner_chain_ts_idx,priority:3"`
+	// 关系
+	RelationType string `gorm:"column:relation_type;size:128"`
+	// 错误信息
+	Err string `gorm:"column:err"`
+	// 是否模拟节点
+	Mocked bool `gorm:"column:mocked;not null;default:false"`
+	// 消息json
+	Msg types.RuleMsg `gorm:"column:msg;type:text;serializer:json"`
+}
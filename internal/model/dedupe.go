@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// 消息去重记录状态
+const (
+	// DedupeStatusProcessing 正在处理
+	DedupeStatusProcessing = "processing"
+	// DedupeStatusDone 处理完成
+	DedupeStatusDone = "done"
+)
+
+// MsgDedupe 消息去重记录，同一个规则链在幂等窗口内相同msgId只处理一次
+type MsgDedupe struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;uniqueIndex:msg_dedupe_owner_chain_msg_unique_idx" json:"owner"`
+	// 规则链ID
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;not null;uniqueIndex:msg_dedupe_owner_chain_msg_unique_idx" json:"chainId"`
+	// 消息ID
+	MsgId string `gorm:"column:msg_id;size:128;not null;uniqueIndex:msg_dedupe_owner_chain_msg_unique_idx" json:"msgId"`
+	// 状态 processing/done
+	Status string `gorm:"column:status;size:16" json:"status"`
+	// 处理结果
+	Result string `gorm:"column:result" json:"result"`
+	// 过期时间，过期后相同msgId可以再次处理
+	ExpireAt time.Time `gorm:"column:expire_at;index" json:"expireAt"`
+	// 处理中租约过期时间，处理中的记录超过该时间没有完成则可以再次处理
+	LeaseExpireAt time.Time `gorm:"column:lease_expire_at;index" json:"leaseExpireAt"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+// IsExpired 是否已过期，处理中的记录租约过期也视为过期
+func (d *MsgDedupe) IsExpired(now time.Time) bool {
+	return !d.ExpireAt.After(now) || (d.Status == DedupeStatusProcessing && !d.LeaseExpireAt.After(now))
+}
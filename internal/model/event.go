@@ -1,5 +1,7 @@
 package model
 
+import "github.com/rulego/rulego/api/types"
+
 type Event struct {
 	Type string `json:"Type"`
 }
@@ -7,30 +9,28 @@ type Event struct {
 // DebugData 调试数据
 // OnDebug 回调函数提供的数据
 type DebugData struct {
+	//规则链ID
+	ChainId string `json:"chainId,omitempty"`
 	//debug数据发生时间
 	Ts int64 `json:"ts"`
 	//节点ID
 	NodeId string `json:"nodeId"`
 	//流向OUT/IN
 	FlowType string `json:"flowType"`
-	//消息类型
-	MsgType string `json:"msgType"`
-	//消息ID
-	MsgId string `json:"msgId"`
-	//消息内容
-	Data string `json:"data"`
-	//消息元数据
-	Metadata string `json:"metadata"`
-	//Err 错误
-	Err string `json:"err"`
+	//消息
+	Msg types.RuleMsg `json:"msg"`
 	//关系
 	RelationType string `json:"relationType"`
+	//Err 错误
+	Err string `json:"err"`
+	//Mocked 是否模拟节点，测试模式下被模拟的节点不执行原组件
+	Mocked bool `json:"mocked,omitempty"`
 }
 
 // DebugDataPage 分页返回数据
 type DebugDataPage struct {
 	//每页多少条，默认读取所有
-	Size int `json:"PageSize"`
+	PageSize int `json:"pageSize"`
 	//当前第几页，默认读取所有
 	Current int `json:"current"`
 	//总数
@@ -0,0 +1,19 @@
+package model
+
+// NodeMock 模拟节点，匹配的节点不执行原组件，直接返回配置的输出、关系或者错误
+type NodeMock struct {
+	// 节点ID，和type至少配置一个
+	NodeId string `json:"nodeId,omitempty"`
+	// 节点类型，模拟该类型的所有节点，例如：restApiCall
+	Type string `json:"type,omitempty"`
+	// 输出消息内容，为空则使用输入消息内容
+	Data string `json:"data,omitempty"`
+	// 合并到输出消息的元数据
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// 关系类型，默认：Success
+	RelationType string `json:"relationType,omitempty"`
+	// 错误信息，不为空则以Failure关系输出该错误
+	Err string `json:"err,omitempty"`
+	// 是否禁用
+	Disabled bool `json:"disabled,omitempty"`
+}
@@ -6,6 +6,8 @@ import (
 
 type Regulation struct {
 	gorm.Model
-	RuleChainId string `gorm:"column:rule_chain_id"`
+	// 所属用户
+	Owner       string `gorm:"column:owner;size:64;not null;default:'';uniqueIndex:regulation_owner_rule_chain_id_unique_idx"`
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;uniqueIndex:regulation_owner_rule_chain_id_unique_idx"`
 	RuleConfig  string `gorm:"column:rule_config"`
 }
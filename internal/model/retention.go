@@ -0,0 +1,134 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rulego/rulego/utils/json"
+)
+
+// RetentionUnlimited 不限制，用于取消上一级策略的限制，maxAge为-1表示不限制保留时间
+const RetentionUnlimited = -1
+
+// RetentionPolicy 运行快照和节点调试数据保留策略，0表示继承上一级，RetentionUnlimited表示不限制
+type RetentionPolicy struct {
+	// 最长保留时间
+	MaxAge time.Duration
+	// 每个规则链最多保留条数
+	MaxCount int
+	// 每个规则链最多占用字节数
+	MaxBytes int64
+}
+
+// retentionPolicyJSON 保留策略json格式，maxAge使用时间字符串，例如：72h，数字表示秒，-1表示不限制
+type retentionPolicyJSON struct {
+	MaxAge   interface{} `json:"maxAge,omitempty"`
+	MaxCount int         `json:"maxCount,omitempty"`
+	MaxBytes int64       `json:"maxBytes,omitempty"`
+}
+
+// IsEmpty 是否没有任何限制
+func (p RetentionPolicy) IsEmpty() bool {
+	return p.MaxAge <= 0 && p.MaxCount <= 0 && p.MaxBytes <= 0
+}
+
+// Merge 使用other中非0的项覆盖当前策略，RetentionUnlimited覆盖后不限制
+func (p RetentionPolicy) Merge(other RetentionPolicy) RetentionPolicy {
+	if other.MaxAge != 0 {
+		p.MaxAge = other.MaxAge
+	}
+	if other.MaxCount != 0 {
+		p.MaxCount = other.MaxCount
+	}
+	if other.MaxBytes != 0 {
+		p.MaxBytes = other.MaxBytes
+	}
+	return p
+}
+
+func (p RetentionPolicy) MarshalJSON() ([]byte, error) {
+	v := retentionPolicyJSON{MaxCount: p.MaxCount, MaxBytes: p.MaxBytes}
+	if p.MaxAge > 0 {
+		v.MaxAge = p.MaxAge.String()
+	} else if p.MaxAge == RetentionUnlimited {
+		v.MaxAge = RetentionUnlimited
+	}
+	return json.Marshal(v)
+}
+
+func (p *RetentionPolicy) UnmarshalJSON(data []byte) error {
+	var v retentionPolicyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch maxAge := v.MaxAge.(type) {
+	case nil:
+		p.MaxAge = 0
+	case string:
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return fmt.Errorf("maxAge=%s format error", maxAge)
+		}
+		p.MaxAge = d
+	case float64:
+		if maxAge == RetentionUnlimited {
+			p.MaxAge = RetentionUnlimited
+		} else {
+			p.MaxAge = time.Duration(maxAge * float64(time.Second))
+		}
+	default:
+		return fmt.Errorf("maxAge=%v format error", maxAge)
+	}
+	if (p.MaxAge < 0 && p.MaxAge != RetentionUnlimited) || v.MaxCount < RetentionUnlimited || v.MaxBytes < RetentionUnlimited {
+		return errors.New("retention must not be negative except -1 for unlimited")
+	}
+	p.MaxCount = v.MaxCount
+	p.MaxBytes = v.MaxBytes
+	return nil
+}
+
+// SnapshotUsage 规则链运行快照存储占用
+type SnapshotUsage struct {
+	// 所属用户
+	Username string `gorm:"column:owner" json:"username"`
+	// 规则链ID
+	ChainId string `gorm:"column:rule_chain_id" json:"chainId"`
+	// 快照数量
+	Count int64 `gorm:"column:count" json:"count"`
+	// 占用字节数
+	Bytes int64 `gorm:"column:bytes" json:"bytes"`
+	// 最早的快照开始时间，毫秒
+	OldestTs int64 `gorm:"column:oldest_ts" json:"oldestTs"`
+	// 最新的快照开始时间，毫秒
+	NewestTs int64 `gorm:"column:newest_ts" json:"newestTs"`
+	// 生效的保留策略
+	Retention *RetentionPolicy `gorm:"-" json:"retention,omitempty"`
+}
+
+// SnapshotMeta 运行快照元数据，用于计算需要清理的快照
+type SnapshotMeta struct {
+	// 快照ID
+	Id string `gorm:"column:snapshot_id"`
+	// 开始时间，毫秒
+	StartTs int64 `gorm:"column:start_ts"`
+	// 占用字节数
+	Size int64 `gorm:"column:size"`
+}
+
+// PurgeResult 清理结果
+type PurgeResult struct {
+	// 删除的快照数量
+	Deleted int `json:"deleted"`
+	// 释放的字节数
+	Bytes int64 `json:"bytes"`
+	// 删除的节点调试数据条数
+	DebugDeleted int `json:"debugDeleted"`
+}
+
+// Add 累加清理结果
+func (r *PurgeResult) Add(other PurgeResult) {
+	r.Deleted += other.Deleted
+	r.Bytes += other.Bytes
+	r.DebugDeleted += other.DebugDeleted
+}
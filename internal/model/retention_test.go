@@ -0,0 +1,156 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    RetentionPolicy
+		wantErr bool
+	}{
+		{name: "empty", data: `{}`, want: RetentionPolicy{}},
+		{name: "duration string", data: `{"maxAge":"72h"}`, want: RetentionPolicy{MaxAge: 72 * time.Hour}},
+		{name: "seconds number", data: `{"maxAge":90}`, want: RetentionPolicy{MaxAge: 90 * time.Second}},
+		{name: "fractional seconds", data: `{"maxAge":1.5}`, want: RetentionPolicy{MaxAge: 1500 * time.Millisecond}},
+		{name: "null max age", data: `{"maxAge":null,"maxCount":10}`, want: RetentionPolicy{MaxCount: 10}},
+		{
+			name: "all fields",
+			data: `{"maxAge":"1h30m","maxCount":100,"maxBytes":1048576}`,
+			want: RetentionPolicy{MaxAge: 90 * time.Minute, MaxCount: 100, MaxBytes: 1048576},
+		},
+		{name: "invalid duration", data: `{"maxAge":"3 days"}`, wantErr: true},
+		{name: "invalid max age type", data: `{"maxAge":true}`, wantErr: true},
+		{
+			name: "unlimited",
+			data: `{"maxAge":-1,"maxCount":-1,"maxBytes":-1}`,
+			want: RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: RetentionUnlimited, MaxBytes: RetentionUnlimited},
+		},
+		{name: "negative max age", data: `{"maxAge":"-1h"}`, wantErr: true},
+		{name: "negative max age seconds", data: `{"maxAge":-2}`, wantErr: true},
+		{name: "negative max count", data: `{"maxCount":-2}`, wantErr: true},
+		{name: "negative max bytes", data: `{"maxBytes":-2}`, wantErr: true},
+		{name: "invalid max count type", data: `{"maxCount":"10"}`, wantErr: true},
+		{name: "invalid json", data: `{"maxAge":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got RetentionPolicy
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   string
+	}{
+		{name: "empty", policy: RetentionPolicy{}, want: `{}`},
+		{name: "max age as duration string", policy: RetentionPolicy{MaxAge: 72 * time.Hour}, want: `{"maxAge":"72h0m0s"}`},
+		{
+			name:   "all fields",
+			policy: RetentionPolicy{MaxAge: time.Minute, MaxCount: 10, MaxBytes: 1024},
+			want:   `{"maxAge":"1m0s","maxCount":10,"maxBytes":1024}`,
+		},
+		{
+			name:   "unlimited",
+			policy: RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: RetentionUnlimited, MaxBytes: RetentionUnlimited},
+			want:   `{"maxAge":-1,"maxCount":-1,"maxBytes":-1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", b, tt.want)
+			}
+			var got RetentionPolicy
+			if err := json.Unmarshal(b, &got); err != nil || got != tt.policy {
+				t.Errorf("round trip = %+v, %v, want %+v", got, err, tt.policy)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicyMerge(t *testing.T) {
+	base := RetentionPolicy{MaxAge: time.Hour, MaxCount: 100, MaxBytes: 1024}
+	tests := []struct {
+		name  string
+		base  RetentionPolicy
+		other RetentionPolicy
+		want  RetentionPolicy
+	}{
+		{name: "empty other keeps base", base: base, other: RetentionPolicy{}, want: base},
+		{name: "empty base takes other", base: RetentionPolicy{}, other: base, want: base},
+		{
+			name:  "override max age only",
+			base:  base,
+			other: RetentionPolicy{MaxAge: time.Minute},
+			want:  RetentionPolicy{MaxAge: time.Minute, MaxCount: 100, MaxBytes: 1024},
+		},
+		{
+			name:  "override count and bytes",
+			base:  base,
+			other: RetentionPolicy{MaxCount: 5, MaxBytes: 10},
+			want:  RetentionPolicy{MaxAge: time.Hour, MaxCount: 5, MaxBytes: 10},
+		},
+		{
+			name:  "unlimited overrides limits",
+			base:  base,
+			other: RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: RetentionUnlimited},
+			want:  RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: RetentionUnlimited, MaxBytes: 1024},
+		},
+		{
+			name:  "limit overrides unlimited",
+			base:  RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: RetentionUnlimited, MaxBytes: RetentionUnlimited},
+			other: RetentionPolicy{MaxCount: 5},
+			want:  RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: 5, MaxBytes: RetentionUnlimited},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.base.Merge(tt.other); got != tt.want {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if base != (RetentionPolicy{MaxAge: time.Hour, MaxCount: 100, MaxBytes: 1024}) {
+		t.Errorf("Merge() modified receiver: %+v", base)
+	}
+}
+
+func TestRetentionPolicyIsEmpty(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   bool
+	}{
+		{name: "zero", policy: RetentionPolicy{}, want: true},
+		{name: "unlimited", policy: RetentionPolicy{MaxAge: RetentionUnlimited, MaxCount: RetentionUnlimited, MaxBytes: RetentionUnlimited}, want: true},
+		{name: "max age", policy: RetentionPolicy{MaxAge: time.Second}, want: false},
+		{name: "max count", policy: RetentionPolicy{MaxCount: 1}, want: false},
+		{name: "max bytes", policy: RetentionPolicy{MaxBytes: 1}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.IsEmpty(); got != tt.want {
+				t.Errorf("IsEmpty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
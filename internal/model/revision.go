@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// RuleRevision 规则链历史版本，每次保存规则链生成一个不可修改的版本
+type RuleRevision struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;uniqueIndex:rule_revision_owner_chain_version_unique_idx" json:"owner"`
+	// 规则链ID
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;not null;uniqueIndex:rule_revision_owner_chain_version_unique_idx" json:"chainId"`
+	// 版本号，同一个规则链从1开始递增
+	Version int `gorm:"column:version;not null;uniqueIndex:rule_revision_owner_chain_version_unique_idx" json:"version"`
+	// 规则链DSL，查询列表时不返回
+	RuleConfig string `gorm:"column:rule_config" json:"ruleConfig,omitempty"`
+	// 修改人
+	Author string `gorm:"column:author;size:64" json:"author"`
+	// 修改说明
+	Message string `gorm:"column:message;size:512" json:"message"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"column:created_at" json:"createdAt"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import "github.com/rulego/rulego/api/types"
+
+// 异步执行状态
+const (
+	RunStatusQueued    = "queued"
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+)
+
+// Run 异步执行记录
+type Run struct {
+	// 执行ID
+	Id string `json:"id"`
+	// 所属用户
+	Username string `json:"username"`
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 消息ID
+	MsgId string `json:"msgId"`
+	// 状态 queued/running/succeeded/failed
+	Status string `json:"status"`
+	// 最终输出消息，规则链有多个结束分支时取最后结束的分支
+	Msg *types.RuleMsg `json:"msg,omitempty"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+	// 执行完成后回调地址
+	CallbackUrl string `json:"callbackUrl,omitempty"`
+	// 提交时间
+	CreateTime int64 `json:"createTime"`
+	// 开始执行时间
+	StartTime int64 `json:"startTime,omitempty"`
+	// 执行结束时间
+	EndTime int64 `json:"endTime,omitempty"`
+}
+
+// IsDone 是否执行结束
+func (r *Run) IsDone() bool {
+	return r.Status == RunStatusSucceeded || r.Status == RunStatusFailed
+}
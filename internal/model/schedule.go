@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// Schedule 规则链定时触发配置，配置在规则链ruleChain.configuration.schedules
+type Schedule struct {
+	// 定时任务ID，同一个规则链内唯一
+	Id string `json:"id"`
+	// cron表达式，支持秒(可选)，例如：0 0 2 * * * ，也支持@every 1h、@daily等描述符
+	Cron string `json:"cron"`
+	// 触发消息类型，默认：CRON
+	MsgType string `json:"msgType,omitempty"`
+	// 触发消息内容，默认：{}
+	Data string `json:"data,omitempty"`
+	// 触发消息元数据
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// 是否禁用
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// ScheduleFiring 定时任务触发记录
+type ScheduleFiring struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;index:schedule_firing_owner_chain_idx" json:"owner"`
+	// 规则链ID
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;not null;index:schedule_firing_owner_chain_idx" json:"chainId"`
+	// 定时任务ID
+	ScheduleId string `gorm:"column:schedule_id;size:64" json:"scheduleId"`
+	// cron表达式
+	Cron string `gorm:"column:cron;size:128" json:"cron"`
+	// 触发消息ID
+	MsgId string `gorm:"column:msg_id;size:64" json:"msgId"`
+	// 状态 running/succeeded/failed
+	Status string `gorm:"column:status;size:16" json:"status"`
+	// 错误信息
+	Err string `gorm:"column:err" json:"err,omitempty"`
+	// 触发时间
+	FireTime time.Time `gorm:"column:fire_time" json:"fireTime"`
+	// 执行结束时间
+	EndTime *time.Time `gorm:"column:end_time" json:"endTime,omitempty"`
+}
+
+// UpcomingFiring 定时任务即将触发时间
+type UpcomingFiring struct {
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 定时任务ID
+	ScheduleId string `json:"scheduleId"`
+	// cron表达式
+	Cron string `json:"cron"`
+	// 触发时间
+	FireTime time.Time `json:"fireTime"`
+}
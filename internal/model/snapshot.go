@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// RunSnapshot 规则链运行快照
+type RunSnapshot struct {
+	ID uint `gorm:"primarykey" json:"-"`
+	// 快照ID，同一用户内唯一
+	SnapshotId string `gorm:"column:snapshot_id;size:128;not null;uniqueIndex:run_snapshot_owner_snapshot_id_unique_idx,priority:2" json:"id"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;uniqueIndex:run_snapshot_owner_snapshot_id_unique_idx,priority:1;index:run_snapshot_owner_chain_start_idx,priority:1;index:run_snapshot_owner_start_idx,priority:1" json:"owner"`
+	// 规则链ID
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;not null;index:run_snapshot_owner_chain_start_idx,priority:2" json:"chainId"`
+	// 消息ID
+	MsgId string `gorm:"column:msg_id;size:128;index:run_snapshot_msg_id_idx" json:"msgId"`
+	// 消息类型
+	MsgType string `gorm:"column:msg_type;size:128" json:"msgType"`
+	// 状态 succeeded/failed
+	Status string `gorm:"column:status;size:16;index:run_snapshot_status_idx" json:"status"`
+	// 开始时间，毫秒
+	StartTs int64 `gorm:"column:start_ts;index:run_snapshot_owner_chain_start_idx,priority:3;index:run_snapshot_owner_start_idx,priority:2" json:"startTs"`
+	// 结束时间，毫秒
+	EndTs int64 `gorm:"column:end_ts;index:run_snapshot_end_ts_idx" json:"endTs"`
+	// 重放的原运行快照ID
+	ReplayOf string `gorm:"column:replay_of;size:128;index:run_snapshot_replay_of_idx" json:"replayOf"`
+	// 各节点输入输出消息内容和元数据，用于关键字查询
+	MsgText string `gorm:"column:msg_text" json:"-"`
+	// 快照内容json
+	Data string `gorm:"column:data" json:"-"`
+	// 快照内容字节数
+	Size int64 `gorm:"column:size;not null;default:0" json:"size"`
+	// 创建时间
+	CreatedAt time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+// RunSnapshotNode 运行快照执行过的节点，用于按节点ID查询运行快照
+type RunSnapshotNode struct {
+	ID uint `gorm:"primarykey"`
+	// 快照ID
+	SnapshotId string `gorm:"column:snapshot_id;size:128;not null;index:run_snapshot_node_snapshot_id_idx"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;index:run_snapshot_node_owner_node_idx,priority:1"`
+	// 节点ID
+	NodeId string `gorm:"column:node_id;size:64;not null;index:run_snapshot_node_owner_node_idx,priority:2"`
+}
+
+// 运行快照排序字段
+const (
+	RunSortStartTs  = "startTs"
+	RunSortEndTs    = "endTs"
+	RunSortDuration = "duration"
+)
+
+// RunSnapshotQuery 运行快照查询条件，空值表示不过滤
+type RunSnapshotQuery struct {
+	// 规则链ID
+	ChainId string
+	// 开始时间范围，毫秒，包含
+	StartTime int64
+	// 结束时间范围，毫秒，不包含
+	EndTime int64
+	// 状态 succeeded/failed
+	Status string
+	// 消息类型
+	MsgType string
+	// 消息ID
+	MsgId string
+	// 执行过的节点ID
+	NodeId string
+	// 消息内容或者元数据包含的关键字
+	Keyword string
+	// 重放的原运行快照ID
+	ReplayOf string
+	// 排序字段 startTs/endTs/duration，默认startTs
+	Sort string
+	// 是否升序，默认降序
+	Asc bool
+	// 当前页，从1开始
+	Current int
+	// 每页条数
+	Size int
+}
@@ -0,0 +1,110 @@
+package model
+
+import "database/sql"
+
+// Readiness 就绪检查结果
+type Readiness struct {
+	// 是否所有检查都通过
+	Ready bool `json:"ready"`
+	// 各项检查结果
+	Checks []CheckResult `json:"checks"`
+}
+
+// CheckResult 单项就绪检查结果
+type CheckResult struct {
+	// 检查项：db/pools/mqtt
+	Name string `json:"name"`
+	// 是否通过
+	Ok bool `json:"ok"`
+	// 未通过原因
+	Err string `json:"err,omitempty"`
+}
+
+// Status 服务运行状态
+type Status struct {
+	// 服务版本
+	Version string `json:"version"`
+	// 启动时间，毫秒时间戳
+	StartTime int64 `json:"startTime"`
+	// 运行时长，例如：1h2m3s
+	Uptime string `json:"uptime"`
+	// 运行时长秒数
+	UptimeSeconds int64 `json:"uptimeSeconds"`
+	// 就绪检查结果
+	Readiness Readiness `json:"readiness"`
+	// 用户规则引擎池状态，管理员返回所有用户，否则只返回当前用户
+	Users []UserStatus `json:"users"`
+	// 数据库连接池状态
+	DB *DBStatus `json:"db,omitempty"`
+}
+
+// UserStatus 用户规则引擎池状态
+type UserStatus struct {
+	// 用户名
+	Username string `json:"username"`
+	// 规则引擎池是否初始化成功
+	Initialized bool `json:"initialized"`
+	// 规则引擎池初始化失败原因
+	Err string `json:"err,omitempty"`
+	// 已加载的规则链数量
+	Chains int `json:"chains"`
+	// 加载失败的规则链，重新保存成功或者删除后移除
+	FailedChains []LoadResult `json:"failedChains"`
+	// 组件插件加载结果
+	Plugins []LoadResult `json:"plugins"`
+	// js自定义函数文件加载结果
+	Udfs []LoadResult `json:"udfs"`
+}
+
+// LoadResult 规则链、插件或者js文件加载结果
+type LoadResult struct {
+	// 规则链ID或者文件名
+	Name string `json:"name"`
+	// 是否加载成功
+	Ok bool `json:"ok"`
+	// 加载失败原因
+	Err string `json:"err,omitempty"`
+}
+
+// DBStatus 数据库连接池状态
+type DBStatus struct {
+	// 数据库驱动
+	Driver string `json:"driver"`
+	// 健康状态：health/unhealth
+	Health string `json:"health"`
+	// 最大连接数
+	MaxOpenConnections int `json:"maxOpenConnections"`
+	// 已建立的连接数，包含使用中和空闲的连接
+	OpenConnections int `json:"openConnections"`
+	// 使用中的连接数
+	InUse int `json:"inUse"`
+	// 空闲连接数
+	Idle int `json:"idle"`
+	// 等待连接的总次数
+	WaitCount int64 `json:"waitCount"`
+	// 等待连接的总时长，例如：1.5s
+	WaitDuration string `json:"waitDuration"`
+	// 因超过最大空闲连接数关闭的连接数
+	MaxIdleClosed int64 `json:"maxIdleClosed"`
+	// 因超过最大空闲时间关闭的连接数
+	MaxIdleTimeClosed int64 `json:"maxIdleTimeClosed"`
+	// 因超过最大存活时间关闭的连接数
+	MaxLifetimeClosed int64 `json:"maxLifetimeClosed"`
+}
+
+// NewDBStatus 把连接池统计转换成数据库状态
+func NewDBStatus(driver, health string, stats *sql.DBStats) *DBStatus {
+	status := &DBStatus{Driver: driver, Health: health}
+	if stats != nil {
+		status.MaxOpenConnections = stats.MaxOpenConnections
+		status.OpenConnections = stats.OpenConnections
+		status.InUse = stats.InUse
+		status.Idle = stats.Idle
+		status.WaitCount = stats.WaitCount
+		status.WaitDuration = stats.WaitDuration.String()
+		status.MaxIdleClosed = stats.MaxIdleClosed
+		status.MaxIdleTimeClosed = stats.MaxIdleTimeClosed
+		status.MaxLifetimeClosed = stats.MaxLifetimeClosed
+	}
+	return status
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+// 断言匹配方式
+const (
+	// MatchExact 精确匹配，json按字段比较
+	MatchExact = "exact"
+	// MatchJsonPath 按jsonPath取值后精确匹配
+	MatchJsonPath = "jsonPath"
+	// MatchRegex 正则表达式匹配
+	MatchRegex = "regex"
+)
+
+// RuleTestCase 规则链测试用例，同一个规则链内用例名称唯一
+type RuleTestCase struct {
+	ID uint `gorm:"primarykey" json:"-"`
+	// 所属用户
+	Owner string `gorm:"column:owner;size:64;not null;uniqueIndex:rule_test_case_owner_chain_name_unique_idx" json:"-"`
+	// 规则链ID
+	RuleChainId string `gorm:"column:rule_chain_id;size:64;not null;uniqueIndex:rule_test_case_owner_chain_name_unique_idx" json:"-"`
+	// 用例名称
+	Name string `gorm:"column:name;size:128;not null;uniqueIndex:rule_test_case_owner_chain_name_unique_idx" json:"name"`
+	// 输入消息类型，默认：TEST
+	MsgType string `gorm:"column:msg_type;size:128" json:"msgType,omitempty"`
+	// 输入消息内容
+	Data string `gorm:"column:data;type:text" json:"data,omitempty"`
+	// 输入消息元数据
+	Metadata map[string]string `gorm:"column:metadata;type:text;serializer:json" json:"metadata,omitempty"`
+	// 模拟节点，执行时替换匹配的节点，用于隔离restApiCall等外部组件
+	Mocks []NodeMock `gorm:"column:mocks;type:text;serializer:json" json:"mocks,omitempty"`
+	// 期望结果
+	Expect TestExpect `gorm:"column:expect;type:text;serializer:json" json:"expect"`
+	// 超时时间，毫秒，默认10秒
+	Timeout int64 `gorm:"column:timeout" json:"timeout,omitempty"`
+	// 更新时间
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TestExpect 测试用例期望结果，未设置的项不检查
+type TestExpect struct {
+	// 输出消息类型
+	MsgType string `json:"msgType,omitempty"`
+	// 输出消息内容断言，需要全部满足
+	Data []Matcher `json:"data,omitempty"`
+	// 输出消息元数据断言，key为元数据key，支持exact和regex
+	Metadata map[string]Matcher `json:"metadata,omitempty"`
+	// 按执行完成顺序经过的节点ID
+	Path []string `json:"path,omitempty"`
+	// 期望错误，正则表达式匹配错误信息，为空表示不能出现错误
+	Err string `json:"err,omitempty"`
+}
+
+// Matcher 断言
+type Matcher struct {
+	// 匹配方式 exact/jsonPath/regex，默认exact
+	Mode string `json:"mode,omitempty"`
+	// jsonPath表达式，例如：$.items[0].name，mode=jsonPath时有效
+	Path string `json:"path,omitempty"`
+	// 期望值，mode=regex时为正则表达式
+	Value interface{} `json:"value"`
+}
+
+// TestReport 规则链测试报告
+type TestReport struct {
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 用例总数
+	Total int `json:"total"`
+	// 通过数
+	Passed int `json:"passed"`
+	// 失败数
+	Failed int `json:"failed"`
+	// 总耗时，毫秒
+	Duration int64 `json:"duration"`
+	// 各用例执行结果
+	Results []TestResult `json:"results"`
+}
+
+// TestResult 测试用例执行结果
+type TestResult struct {
+	// 用例名称
+	Name string `json:"name"`
+	// 是否通过
+	Passed bool `json:"passed"`
+	// 不满足的断言
+	Failures []string `json:"failures,omitempty"`
+	// 按执行完成顺序经过的节点ID
+	Path []string `json:"path"`
+	// 最终输出消息，规则链有多个结束分支时取最后结束的分支
+	Msg *types.RuleMsg `json:"msg,omitempty"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+	// 耗时，毫秒
+	Duration int64 `json:"duration"`
+}
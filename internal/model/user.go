@@ -4,6 +4,26 @@ package model
 type User struct {
 	// 用户名
 	Username string `json:"username"`
-	// 密码
+	// 密码，查询列表时不返回
+	Password string `json:"password,omitempty"`
+}
+
+// ChangePassword 修改密码请求
+type ChangePassword struct {
+	// 旧密码，修改自己的密码时必须
+	OldPassword string `json:"oldPassword"`
+	// 新密码
 	Password string `json:"password"`
 }
+
+// Token 登录令牌
+type Token struct {
+	// 访问token
+	Token string `json:"token"`
+	// 访问token过期时间，毫秒时间戳
+	ExpireAt int64 `json:"expireAt"`
+	// 刷新token
+	RefreshToken string `json:"refreshToken"`
+	// 刷新token过期时间，毫秒时间戳
+	RefreshExpireAt int64 `json:"refreshExpireAt"`
+}
@@ -5,6 +5,7 @@ import (
 	"ruleGoProject/config"
 	"ruleGoProject/config/logger"
 	"ruleGoProject/internal/controller"
+	"ruleGoProject/internal/service"
 	"strings"
 
 	"github.com/rulego/rulego"
@@ -17,6 +18,9 @@ const (
 	// base HTTP paths.
 	apiVersion  = "v1"
 	apiBasePath = "/api/" + apiVersion
+	// 存活和就绪探针路径
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
 )
 
 // NewRestServe rest服务 接收端点
@@ -46,6 +50,22 @@ func NewRestServe(config config.Config) *rest.Endpoint {
 		// 返回 204 状态码
 		w.WriteHeader(http.StatusNoContent)
 	}))
+	//登录
+	restEndpoint.POST(controller.LoginRouter(apiBasePath + "/login"))
+	//刷新token
+	restEndpoint.POST(controller.RefreshTokenRouter(apiBasePath + "/refreshToken"))
+	//获取所有用户
+	restEndpoint.GET(controller.ListUserRouter(apiBasePath + "/users"))
+	//新增用户
+	restEndpoint.POST(controller.CreateUserRouter(apiBasePath + "/users"))
+	//删除用户
+	restEndpoint.DELETE(controller.DeleteUserRouter(apiBasePath + "/users/:user"))
+	//修改密码
+	restEndpoint.POST(controller.ChangePasswordRouter(apiBasePath + "/users/:user/password"))
+	//查询运行快照存储占用
+	restEndpoint.GET(controller.StorageUsageRouter(apiBasePath + "/admin/storage"))
+	//按保留策略手动清理运行快照
+	restEndpoint.POST(controller.PurgeStorageRouter(apiBasePath + "/admin/storage/purge"))
 	//创建获取所有规则引擎组件列表路由
 	restEndpoint.GET(controller.ComponentsRouter(apiBasePath + "/components"))
 	//获取所有规则链列表
@@ -54,16 +74,54 @@ func NewRestServe(config config.Config) *rest.Endpoint {
 	restEndpoint.GET(controller.GetDslRouter(apiBasePath + "/rule/:chainId"))
 	//新增/修改规则链DSL
 	restEndpoint.POST(controller.SaveDslRouter(apiBasePath + "/rule/:chainId"))
+	//校验规则链DSL
+	restEndpoint.POST(controller.ValidateDslRouter(apiBasePath + "/rule/:chainId/validate"))
 	//删除规则链
 	restEndpoint.DELETE(controller.DeleteDslRouter(apiBasePath + "/rule/:chainId"))
 	//保存规则链附加信息
 	restEndpoint.POST(controller.SaveBaseInfo(apiBasePath + "/rule/:chainId/saveInfo"))
 	//保存规则链配置信息
 	restEndpoint.POST(controller.SaveConfiguration(apiBasePath + "/rule/:chainId/saveConfig/:varType"))
+	//获取规则链所有历史版本
+	restEndpoint.GET(controller.ListRevisionRouter(apiBasePath + "/rule/:chainId/revisions"))
+	//获取规则链指定历史版本
+	restEndpoint.GET(controller.GetRevisionRouter(apiBasePath + "/rule/:chainId/revisions/:version"))
+	//比较规则链两个历史版本
+	restEndpoint.GET(controller.DiffRevisionRouter(apiBasePath + "/rule/:chainId/revisionDiff"))
+	//回滚规则链到指定历史版本
+	restEndpoint.POST(controller.RollbackRevisionRouter(apiBasePath + "/rule/:chainId/revisions/:version/rollback"))
+	//获取规则链定时任务配置
+	restEndpoint.GET(controller.ListScheduleRouter(apiBasePath + "/rule/:chainId/schedules"))
+	//保存规则链定时任务配置
+	restEndpoint.POST(controller.SaveScheduleRouter(apiBasePath + "/rule/:chainId/schedules"))
+	//查询规则链定时任务即将触发时间
+	restEndpoint.GET(controller.UpcomingScheduleRouter(apiBasePath + "/rule/:chainId/schedules/upcoming"))
+	//查询规则链定时任务触发记录
+	restEndpoint.GET(controller.ScheduleHistoryRouter(apiBasePath + "/rule/:chainId/schedules/history"))
+	//获取规则链测试用例
+	restEndpoint.GET(controller.ListTestCaseRouter(apiBasePath + "/rule/:chainId/tests"))
+	//保存规则链测试用例
+	restEndpoint.POST(controller.SaveTestCaseRouter(apiBasePath + "/rule/:chainId/tests"))
+	//执行规则链测试用例
+	restEndpoint.POST(controller.RunTestCaseRouter(apiBasePath + "/rule/:chainId/tests/run"))
+	//获取规则链模拟节点配置
+	restEndpoint.GET(controller.ListMockRouter(apiBasePath + "/rule/:chainId/mocks"))
+	//保存规则链模拟节点配置
+	restEndpoint.POST(controller.SaveMockRouter(apiBasePath + "/rule/:chainId/mocks"))
+	//测试模式执行规则链，模拟节点不执行原组件
+	restEndpoint.POST(controller.TestRunRouter(apiBasePath + "/rule/:chainId/testRun"))
+	//查询用户所有规则链定时任务即将触发时间
+	restEndpoint.GET(controller.UpcomingScheduleRouter(apiBasePath + "/schedules/upcoming"))
+	//查询用户所有规则链定时任务触发记录
+	restEndpoint.GET(controller.ScheduleHistoryRouter(apiBasePath + "/schedules/history"))
 	//执行规则链,并得到规则链处理结果
 	restEndpoint.POST(controller.ExecuteRuleRouter(apiBasePath + "/rule/:chainId/execute/:msgType"))
 	//处理数据上报请求，并转发到规则引擎，不等待规则引擎处理结果
 	restEndpoint.POST(controller.PostMsgRouter(apiBasePath + "/rule/:chainId/notify/:msgType"))
+	//异步执行规则链，立即返回执行ID
+	restEndpoint.POST(controller.ExecuteAsyncRouter(apiBasePath + "/rule/:chainId/async/:msgType"))
+	//查询异步执行状态和结果
+	restEndpoint.GET(controller.GetRunRouter(apiBasePath + "/runs/:id"))
 
 	//处理数据上报请求，并转发到规则引擎
 	restEndpoint.POST(controller.PostMsgRouter(apiBasePath + "/msg/:chainId/:msgType"))
@@ -73,12 +131,29 @@ func NewRestServe(config config.Config) *rest.Endpoint {
 
 	restEndpoint.GET(controller.GetRunsRouter(apiBasePath + "/event/runs"))
 	restEndpoint.DELETE(controller.DeleteRunsRouter(apiBasePath + "/event/runs"))
+	//逐节点比较两次运行
+	restEndpoint.GET(controller.RunDiffRouter(apiBasePath + "/event/runDiff"))
+	//根据消息ID或者执行ID查询消息执行轨迹
+	restEndpoint.GET(controller.TraceRouter(apiBasePath + "/event/trace"))
+	//使用运行快照的输入消息重新执行规则链
+	restEndpoint.POST(controller.ReplayRunRouter(apiBasePath + "/event/runs/:id/replay"))
 
 	restEndpoint.POST(controller.TestWebhookRouter(apiBasePath + "/webhook/:integrationType/:username/:chainId"))
 
 	//获取所有共享组件
 	restEndpoint.GET(controller.ListNodePool(apiBasePath + "/node_pool/list"))
 
+	//存活探针
+	restEndpoint.GET(controller.HealthzRouter(healthzPath))
+	//就绪探针：数据库、用户规则引擎池、mqtt连接
+	restEndpoint.GET(controller.ReadyzRouter(readyzPath))
+	//服务运行状态
+	restEndpoint.GET(controller.StatusRouter(apiBasePath + "/status"))
+
+	//prometheus指标，配置了单独监听地址则通过MetricsServe提供
+	if service.MetricsImpl != nil && config.MetricsServer == "" {
+		restEndpoint.Router().Handler(http.MethodGet, metricsPath, service.MetricsImpl.Handler())
+	}
 	//静态文件映射
 	loadServeFiles(config, restEndpoint)
 	return restEndpoint
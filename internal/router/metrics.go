@@ -0,0 +1,105 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/service"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rulego/rulego/endpoint/rest"
+)
+
+// metricsPath prometheus指标路径
+const metricsPath = "/metrics"
+
+// unknownRoute 没有匹配路由的请求使用的路由标签
+const unknownRoute = "unknown"
+
+// MetricsServe 在metrics_server地址单独提供指标接口，未开启指标或者未配置地址返回nil
+func MetricsServe(c config.Config) *http.Server {
+	if service.MetricsImpl == nil || c.MetricsServer == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, service.MetricsImpl.Handler())
+	server := &http.Server{Addr: c.MetricsServer, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Logger.Println("metrics serve initialised.addr=" + c.MetricsServer)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Logger.Fatal("metrics serve error:", err)
+		}
+	}()
+	return server
+}
+
+// HttpMetrics 记录http请求耗时，需要在服务启动前调用
+// websocket连接请求不记录
+func HttpMetrics(restServer *rest.Rest) {
+	if service.MetricsImpl == nil || restServer.Server == nil {
+		return
+	}
+	router := restServer.Router()
+	next := restServer.Server.Handler
+	restServer.Server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		service.MetricsImpl.ObserveHttp(r.Method, routeTemplate(router, r), recorder.status, time.Since(start))
+	})
+}
+
+// routeTemplate 获取请求匹配的路由路径模板，例如：/api/v1/rule/:chainId，保证路由标签数量有上限
+func routeTemplate(router *httprouter.Router, r *http.Request) string {
+	handle, params, _ := router.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return unknownRoute
+	}
+	segments := strings.Split(r.URL.Path, "/")
+	i := 0
+	for index, param := range params {
+		//通配参数匹配剩余的路径
+		if strings.HasPrefix(param.Value, "/") {
+			prefix := strings.TrimSuffix(r.URL.Path, param.Value)
+			return prefix + "/*" + param.Key
+		}
+		for ; i < len(segments); i++ {
+			if segments[i] != param.Value {
+				continue
+			}
+			//参数值可能和前面的静态路径相同，替换后重新匹配确认是该参数所在位置
+			segments[i] = ":" + param.Key
+			if _, replaced, _ := router.Lookup(r.Method, strings.Join(segments, "/")); len(replaced) > index && replaced[index].Value == segments[i] {
+				i++
+				break
+			}
+			segments[i] = param.Value
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush 支持流式响应
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
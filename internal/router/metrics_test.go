@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	router := httprouter.New()
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {}
+	router.GET("/api/v1/rules", handle)
+	router.GET("/api/v1/rule/:chainId", handle)
+	router.POST("/api/v1/rule/:chainId/execute/:msgType", handle)
+	router.GET("/api/v1/event/runs/:id", handle)
+	router.GET("/ui/*filepath", handle)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{name: "static route", method: http.MethodGet, path: "/api/v1/rules", want: "/api/v1/rules"},
+		{name: "one param", method: http.MethodGet, path: "/api/v1/rule/chain01", want: "/api/v1/rule/:chainId"},
+		{name: "two params", method: http.MethodPost, path: "/api/v1/rule/chain01/execute/TEST", want: "/api/v1/rule/:chainId/execute/:msgType"},
+		{name: "param same as static segment", method: http.MethodGet, path: "/api/v1/rule/rule", want: "/api/v1/rule/:chainId"},
+		{name: "params with same value", method: http.MethodPost, path: "/api/v1/rule/execute/execute/execute", want: "/api/v1/rule/:chainId/execute/:msgType"},
+		{name: "param same as earlier static segment", method: http.MethodGet, path: "/api/v1/event/runs/v1", want: "/api/v1/event/runs/:id"},
+		{name: "catch all", method: http.MethodGet, path: "/ui/static/js/app.js", want: "/ui/*filepath"},
+		{name: "catch all root", method: http.MethodGet, path: "/ui/", want: "/ui/*filepath"},
+		{name: "unknown path", method: http.MethodGet, path: "/api/v1/unknown/chain01", want: unknownRoute},
+		{name: "unknown method", method: http.MethodDelete, path: "/api/v1/rule/chain01", want: unknownRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := routeTemplate(router, r); got != tt.want {
+				t.Errorf("routeTemplate(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
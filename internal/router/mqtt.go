@@ -1,15 +1,25 @@
 package router
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"log"
+	"os"
 	"ruleGoProject/config"
 	"ruleGoProject/internal/controller"
+	"ruleGoProject/internal/service"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	paho "github.com/eclipse/paho.mqtt.golang"
 	"github.com/rulego/rulego"
 	endpointApi "github.com/rulego/rulego/api/types/endpoint"
 	"github.com/rulego/rulego/endpoint"
 	endpointMqtt "github.com/rulego/rulego/endpoint/mqtt"
+	"github.com/rulego/rulego/utils/str"
 )
 
 // MqttServe mqtt 订阅服务
@@ -26,11 +36,125 @@ func MqttServe(c config.Config, logger *log.Logger) (endpoint.Endpoint, error) {
 		if topic == "" {
 			topic = "#"
 		}
-		router := endpoint.NewRouter(endpointApi.RouterOptions.WithRuleGoFunc(controller.GetRuleGoFunc)).From(topic).To(c.Mqtt.ToChainId).End()
+		router := endpoint.NewRouter(endpointApi.RouterOptions.WithRuleGoFunc(controller.GetRuleGoFunc), endpointApi.RouterOptions.WithContextFunc(controller.TraceContext)).From(topic).To(c.Mqtt.ToChainId).End()
 		_, _ = mqttEndpoint.AddRouter(router)
 	}
 	if err := mqttEndpoint.Start(); err != nil {
 		logger.Fatal(err)
 	}
-	return mqttEndpoint, err
+	if service.StatusServiceImpl == nil {
+		return mqttEndpoint, err
+	}
+	status, err := newMqttStatus(c.Mqtt)
+	if err != nil {
+		logger.Printf("mqtt status client error: %v", err)
+		return mqttEndpoint, nil
+	}
+	service.StatusServiceImpl.AddReadyCheck(service.CheckMqtt, func(ctx context.Context) error {
+		if !status.Connected() {
+			return errors.New("mqtt not connected, server=" + c.Mqtt.Server)
+		}
+		return nil
+	})
+	return &statusMqttEndpoint{Endpoint: mqttEndpoint, status: status}, nil
+}
+
+// statusMqttEndpoint 销毁mqtt端点时同时关闭连接状态客户端
+type statusMqttEndpoint struct {
+	endpoint.Endpoint
+	status *mqttStatus
+}
+
+// Destroy 销毁
+func (e *statusMqttEndpoint) Destroy() {
+	e.status.Close()
+	e.Endpoint.Destroy()
+}
+
+// mqttStatus 通过paho连接回调跟踪mqtt broker连接状态
+// rulego mqtt端点没有暴露连接回调，这里使用相同的broker配置建立一个只用于就绪检查的连接
+type mqttStatus struct {
+	connected atomic.Bool
+	client    paho.Client
+}
+
+// newMqttStatus 创建并在后台连接mqtt broker，连接断开后自动重连
+func newMqttStatus(c config.Mqtt) (*mqttStatus, error) {
+	tlsConfig, err := mqttTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	s := &mqttStatus{}
+	opts := paho.NewClientOptions()
+	opts.AddBroker(c.Server)
+	opts.SetUsername(c.Username)
+	opts.SetPassword(c.Password)
+	//不能与订阅端点使用相同的clientId，否则broker会断开订阅端点的连接
+	if c.ClientID == "" {
+		opts.SetClientID("rulego/status/" + str.RandomStr(8))
+	} else {
+		opts.SetClientID(c.ClientID + "/status")
+	}
+	opts.SetCleanSession(true)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetConnectRetryInterval(2 * time.Second)
+	if c.MaxReconnectInterval > 0 {
+		opts.SetMaxReconnectInterval(c.MaxReconnectInterval)
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	opts.SetOnConnectHandler(s.onConnect)
+	opts.SetConnectionLostHandler(s.onConnectionLost)
+	s.client = paho.NewClient(opts)
+	//开启连接重试后，连接失败会在后台重试，不需要等待
+	s.client.Connect()
+	return s, nil
+}
+
+func (s *mqttStatus) onConnect(paho.Client) {
+	s.connected.Store(true)
+}
+
+func (s *mqttStatus) onConnectionLost(paho.Client, error) {
+	s.connected.Store(false)
+}
+
+// Connected mqtt broker是否已连接
+func (s *mqttStatus) Connected() bool {
+	return s.connected.Load()
+}
+
+// Close 断开连接
+func (s *mqttStatus) Close() {
+	if s.client != nil {
+		s.client.Disconnect(250)
+	}
+	s.connected.Store(false)
+}
+
+// mqttTLSConfig 加载mqtt tls证书，与rulego mqtt客户端的加载方式一致，未配置证书返回nil
+func mqttTLSConfig(c config.Mqtt) (*tls.Config, error) {
+	if c.CAFile == "" && c.CertFile == "" && c.CertKeyFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{}
+	if c.CAFile != "" {
+		caCert, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = certPool
+	}
+	if c.CertFile != "" && c.CertKeyFile != "" {
+		kp, err := tls.LoadX509KeyPair(c.CertFile, c.CertKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{kp}
+	}
+	return tlsConfig, nil
 }
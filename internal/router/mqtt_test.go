@@ -0,0 +1,98 @@
+package router
+
+import (
+	"bufio"
+	"net"
+	"ruleGoProject/config"
+	"testing"
+	"time"
+)
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, name string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMqttStatus(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		//读取CONNECT报文后回复CONNACK
+		r := bufio.NewReader(conn)
+		if _, err := r.ReadByte(); err != nil {
+			return
+		}
+		length, multiplier := 0, 1
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			length += int(b&127) * multiplier
+			multiplier *= 128
+			if b&128 == 0 {
+				break
+			}
+		}
+		if _, err := r.Discard(length); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		conns <- conn
+	}()
+
+	status, err := newMqttStatus(config.Mqtt{Server: "tcp://" + ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(status.Close)
+	waitFor(t, "connected", status.Connected)
+
+	//broker断开连接后不再就绪
+	_ = ln.Close()
+	conn := <-conns
+	_ = conn.Close()
+	waitFor(t, "disconnected", func() bool { return !status.Connected() })
+}
+
+func TestMqttStatusUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	status, err := newMqttStatus(config.Mqtt{Server: "tcp://" + addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer status.Close()
+	time.Sleep(100 * time.Millisecond)
+	if status.Connected() {
+		t.Error("status connected to unreachable broker")
+	}
+}
+
+func TestMqttTLSConfig(t *testing.T) {
+	if tlsConfig, err := mqttTLSConfig(config.Mqtt{}); err != nil || tlsConfig != nil {
+		t.Errorf("mqttTLSConfig() = %v, %v, want nil", tlsConfig, err)
+	}
+	if _, err := mqttTLSConfig(config.Mqtt{CAFile: "not-exist.pem"}); err == nil {
+		t.Error("missing ca file accepted")
+	}
+}
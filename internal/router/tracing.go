@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+	"ruleGoProject/internal/service"
+	"strings"
+
+	"github.com/rulego/rulego/endpoint/rest"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HttpTracing 为每个http请求创建span，上游traceparent请求头作为父span，需要在服务启动前调用
+// span保存在请求上下文中，rest端点使用请求上下文执行规则链，规则链执行span以此为父span
+// websocket连接请求、指标请求和探针请求不记录
+func HttpTracing(restServer *rest.Rest) {
+	if service.TracingImpl == nil || restServer.Server == nil {
+		return
+	}
+	router := restServer.Router()
+	next := restServer.Server.Handler
+	restServer.Server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.URL.Path == metricsPath ||
+			r.URL.Path == healthzPath || r.URL.Path == readyzPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := routeTemplate(router, r)
+		ctx, span := service.TracingImpl.Tracer().Start(service.TracingImpl.Extract(r.Context(), r.Header), r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			))
+		defer span.End()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
@@ -6,18 +6,25 @@ import (
 	"ruleGoProject/internal/constants"
 	"ruleGoProject/internal/controller"
 	"ruleGoProject/internal/service"
-	"time"
+	"sync"
 
 	"github.com/gorilla/websocket"
-	"github.com/rulego/rulego/api/types"
 	endpointApi "github.com/rulego/rulego/api/types/endpoint"
 	"github.com/rulego/rulego/endpoint/rest"
 	websocketEndpoint "github.com/rulego/rulego/endpoint/websocket"
 	"github.com/rulego/rulego/utils/json"
 )
 
+// wsConnection 已认证的websocket连接
+type wsConnection struct {
+	username string
+	clientId string
+}
+
 // NewWebsocketServe Websocket服务 接收端点
 func NewWebsocketServe(c config.Config, restEndpoint *rest.Rest) *websocketEndpoint.Endpoint {
+	//连接exchange->已认证的连接，断开时使用连接时的用户清理，token可能已经过期
+	var connections sync.Map
 	//初始化日志
 	wsEndpoint := &websocketEndpoint.Endpoint{
 		Rest: restEndpoint,
@@ -33,37 +40,31 @@ func NewWebsocketServe(c config.Config, restEndpoint *rest.Rest) *websocketEndpo
 		switch eventName {
 		case endpointApi.EventConnect:
 			exchange := params[0].(*endpointApi.Exchange)
-			username := exchange.In.Headers().Get(constants.KeyUsername)
-			if username == "" {
-				username = config.C.DefaultUsername
+			username, err := controller.Authenticate(exchange)
+			if err != nil {
+				return
 			}
 			if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-				s.AddOnDebugObserver(exchange.In.GetParam(constants.KeyClientId), func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error) {
-					errStr := ""
-					if err != nil {
-						errStr = err.Error()
-					}
-					var log = map[string]interface{}{
-						"chainId":      chainId,
-						"flowType":     flowType,
-						"nodeId":       nodeId,
-						"relationType": relationType,
-						"err":          errStr,
-						"msg":          msg,
-						"ts":           time.Now().UnixMilli(),
-					}
-					jsonStr, _ := json.Marshal(log)
+				//调试事件和节点调试数据都通过连接的exchange写出，保证同一个连接串行写
+				write := func(v interface{}) {
+					jsonStr, _ := json.Marshal(v)
 					exchange.Out.SetBody(jsonStr)
-				})
+				}
+				clientId := exchange.In.GetParam(constants.KeyClientId)
+				connections.Store(exchange, wsConnection{username: username, clientId: clientId})
+				s.OpenDebugSession(clientId, write)
+				s.AddOnDebugObserver(clientId, write)
 			}
 		case endpointApi.EventDisconnect:
 			exchange := params[0].(*endpointApi.Exchange)
-			username := exchange.In.Headers().Get(constants.KeyUsername)
-			if username == "" {
-				username = config.C.DefaultUsername
+			v, ok := connections.LoadAndDelete(exchange)
+			if !ok {
+				return
 			}
-			if s, ok := service.UserRuleEngineServiceImpl.Get(username); ok {
-				s.RemoveOnDebugObserver(exchange.In.GetParam(constants.KeyClientId))
+			conn := v.(wsConnection)
+			if s, ok := service.UserRuleEngineServiceImpl.Load(conn.username); ok {
+				s.RemoveOnDebugObserver(conn.clientId)
+				s.CloseDebugSession(conn.clientId)
 			}
 		}
 	}
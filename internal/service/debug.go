@@ -0,0 +1,151 @@
+package service
+
+import (
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var DebugServiceImpl *DebugService
+
+const (
+	// debugWriteQueueSize 持久化存储异步写入队列大小，队列满丢弃新数据
+	debugWriteQueueSize = 10000
+	// debugWriteBatchSize 每批最多写入条数
+	debugWriteBatchSize = 200
+	// debugWriteInterval 批量写入间隔
+	debugWriteInterval = 200 * time.Millisecond
+)
+
+// debugRecord 等待写入的调试数据
+type debugRecord struct {
+	username string
+	data     model.DebugData
+}
+
+// DebugService 节点调试数据存储
+// 内存存储同步写入，持久化存储使用有界队列异步批量写入，不阻塞规则链执行
+type DebugService struct {
+	store   dao.DebugStore
+	async   bool
+	queue   chan debugRecord
+	dropped uint64
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+func NewDebugService(config config.Config) (*DebugService, error) {
+	store, err := dao.NewDebugStore(config)
+	if err != nil {
+		return nil, err
+	}
+	s := &DebugService{
+		store: store,
+		async: config.DebugStore != "" && config.DebugStore != dao.DebugStoreMemory,
+		done:  make(chan struct{}),
+	}
+	if s.async {
+		s.queue = make(chan debugRecord, debugWriteQueueSize)
+		s.wg.Add(1)
+		go s.write()
+	}
+	return s, nil
+}
+
+// Add 保存节点调试数据
+func (s *DebugService) Add(username string, data model.DebugData) {
+	if !s.async {
+		_ = s.store.Add(username, data)
+		return
+	}
+	select {
+	case s.queue <- debugRecord{username: username, data: data}:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// List 按查询条件分页查询节点调试数据
+func (s *DebugService) List(username string, query model.DebugDataQuery) ([]model.DebugData, int, error) {
+	return s.store.List(username, query)
+}
+
+// ChainIds 获取有调试数据的规则链ID列表，用户为空则查询所有用户
+func (s *DebugService) ChainIds(username string) (map[string][]string, error) {
+	return s.store.ChainIds(username)
+}
+
+// Purge 按保留策略清理规则链调试数据，返回删除的条数
+func (s *DebugService) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (int, error) {
+	return s.store.Purge(username, chainId, policy, now)
+}
+
+// DeleteByChainId 删除规则链所有调试数据
+func (s *DebugService) DeleteByChainId(username, chainId string) error {
+	return s.store.DeleteByChainId(username, chainId)
+}
+
+// Dropped 异步写入队列已满丢弃的条数
+func (s *DebugService) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close 写入队列中剩余的数据，并关闭存储
+func (s *DebugService) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	return s.store.Close()
+}
+
+// write 按批量大小或者时间间隔批量写入
+func (s *DebugService) write() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(debugWriteInterval)
+	defer ticker.Stop()
+	var batch []debugRecord
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= debugWriteBatchSize {
+				s.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = nil
+		case <-s.done:
+			for {
+				select {
+				case record := <-s.queue:
+					batch = append(batch, record)
+				default:
+					s.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *DebugService) flush(batch []debugRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	var users = make(map[string][]model.DebugData)
+	for _, record := range batch {
+		users[record.username] = append(users[record.username], record.data)
+	}
+	for username, data := range users {
+		if err := s.store.Add(username, data...); err != nil {
+			logger.Logger.Printf("service/DebugService:flush username=%s error%s", username, err.Error())
+		}
+	}
+}
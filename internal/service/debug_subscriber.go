@@ -0,0 +1,227 @@
+package service
+
+import (
+	"ruleGoProject/internal/model"
+	"sync"
+	"sync/atomic"
+)
+
+// DebugEventDropped 推送队列已满丢弃调试数据后，推送的丢弃通知事件类型
+const DebugEventDropped = "dropped"
+
+// DebugEventSubscription 订阅信息事件类型，订阅或者查询订阅状态时推送
+const DebugEventSubscription = "subscription"
+
+// DebugFilter 调试数据订阅过滤条件，未设置的条件不过滤
+type DebugFilter struct {
+	// 规则链ID
+	ChainId string `json:"chainId,omitempty"`
+	// 节点ID列表
+	NodeIds []string `json:"nodeIds,omitempty"`
+	// 流向 IN/OUT/Log
+	FlowType string `json:"flowType,omitempty"`
+	// 只推送有错误的调试数据
+	ErrorOnly bool `json:"errorOnly,omitempty"`
+	// 消息ID
+	MsgId string `json:"msgId,omitempty"`
+}
+
+// Match 检查调试数据是否满足过滤条件
+func (f DebugFilter) Match(data model.DebugData) bool {
+	if f.ChainId != "" && f.ChainId != data.ChainId {
+		return false
+	}
+	if f.FlowType != "" && f.FlowType != data.FlowType {
+		return false
+	}
+	if f.ErrorOnly && data.Err == "" {
+		return false
+	}
+	if f.MsgId != "" && f.MsgId != data.Msg.Id {
+		return false
+	}
+	if len(f.NodeIds) > 0 {
+		for _, nodeId := range f.NodeIds {
+			if nodeId == data.NodeId {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// DebugSubscriptionStats 订阅推送统计
+type DebugSubscriptionStats struct {
+	// 事件类型，固定为subscription
+	Type string `json:"type"`
+	// 过滤条件
+	Filter DebugFilter `json:"filter"`
+	// 队列中等待推送的条数
+	Queued int `json:"queued"`
+	// 已推送条数
+	Delivered uint64 `json:"delivered"`
+	// 队列已满丢弃的条数
+	Dropped uint64 `json:"dropped"`
+}
+
+// DebugSubscriber 调试数据订阅者，使用有界队列和单独的协程按顺序推送，队列已满则丢弃新数据并计数
+type DebugSubscriber struct {
+	write     func(v interface{})
+	queue     chan interface{}
+	filter    DebugFilter
+	delivered uint64
+	dropped   uint64
+	done      chan struct{}
+	// 保证过滤条件切换、断点续传与实时数据入队的顺序
+	lock sync.Mutex
+}
+
+func newDebugSubscriber(queueSize int, write func(v interface{})) *DebugSubscriber {
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+	sub := &DebugSubscriber{
+		write: write,
+		queue: make(chan interface{}, queueSize),
+		done:  make(chan struct{}),
+	}
+	go sub.loop()
+	return sub
+}
+
+// loop 按入队顺序推送，有丢弃的数据则先推送丢弃通知
+func (sub *DebugSubscriber) loop() {
+	var notified uint64
+	for {
+		select {
+		case <-sub.done:
+			return
+		case v := <-sub.queue:
+			if dropped := atomic.LoadUint64(&sub.dropped); dropped != notified {
+				notified = dropped
+				sub.write(DebugEvent{Type: DebugEventDropped, Dropped: dropped})
+			}
+			sub.write(v)
+			atomic.AddUint64(&sub.delivered, 1)
+		}
+	}
+}
+
+// offer 满足过滤条件则入队，不阻塞
+func (sub *DebugSubscriber) offer(data model.DebugData) {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+	if sub.filter.Match(data) {
+		sub.enqueue(data)
+	}
+}
+
+func (sub *DebugSubscriber) enqueue(v interface{}) {
+	select {
+	case sub.queue <- v:
+	default:
+		atomic.AddUint64(&sub.dropped, 1)
+	}
+}
+
+// Stats 获取订阅推送统计
+func (sub *DebugSubscriber) Stats() DebugSubscriptionStats {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+	return DebugSubscriptionStats{
+		Type:      DebugEventSubscription,
+		Filter:    sub.filter,
+		Queued:    len(sub.queue),
+		Delivered: atomic.LoadUint64(&sub.delivered),
+		Dropped:   atomic.LoadUint64(&sub.dropped),
+	}
+}
+
+func (sub *DebugSubscriber) close() {
+	close(sub.done)
+}
+
+// AddOnDebugObserver 增加调试数据订阅者，clientId已存在则替换，默认不过滤
+func (s *RuleEngineService) AddOnDebugObserver(clientId string, write func(v interface{})) *DebugSubscriber {
+	sub := newDebugSubscriber(s.config.DebugQueueSize, write)
+	s.locker.Lock()
+	old := s.onDebugObserver[clientId]
+	s.onDebugObserver[clientId] = sub
+	s.locker.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return sub
+}
+
+func (s *RuleEngineService) RemoveOnDebugObserver(clientId string) {
+	s.locker.Lock()
+	sub := s.onDebugObserver[clientId]
+	delete(s.onDebugObserver, clientId)
+	s.locker.Unlock()
+	if sub != nil {
+		sub.close()
+	}
+}
+
+// GetDebugObserver 获取调试数据订阅者
+func (s *RuleEngineService) GetDebugObserver(clientId string) (*DebugSubscriber, bool) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	sub, ok := s.onDebugObserver[clientId]
+	return sub, ok
+}
+
+// DebugObserverCount 调试数据订阅者数量
+func (s *RuleEngineService) DebugObserverCount() int {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	return len(s.onDebugObserver)
+}
+
+// Subscribe 修改订阅者过滤条件，since大于0则先从调试数据存储中按时间顺序补推该时间(毫秒)之后满足条件的数据，用于断线重连后续传
+// 补推条数不超过推送队列大小，查询存储时不持有订阅者的锁，不阻塞实时数据推送
+func (s *RuleEngineService) Subscribe(clientId string, filter DebugFilter, since int64) (DebugSubscriptionStats, bool) {
+	sub, ok := s.GetDebugObserver(clientId)
+	if !ok {
+		return DebugSubscriptionStats{}, false
+	}
+	var items []model.DebugData
+	if since > 0 && DebugServiceImpl != nil {
+		//最多补发队列容量条数，不查询所有数据
+		var query = model.DebugDataQuery{
+			ChainId:   filter.ChainId,
+			MsgId:     filter.MsgId,
+			StartTime: since + 1,
+			Asc:       true,
+			Current:   1,
+			Size:      cap(sub.queue),
+		}
+		if len(filter.NodeIds) == 1 {
+			query.NodeId = filter.NodeIds[0]
+		}
+		var err error
+		if items, _, err = DebugServiceImpl.List(s.username, query); err != nil {
+			s.logger.Printf("service/RuleEngineService:Subscribe clientId=%s error%s", clientId, err.Error())
+		}
+	}
+	sub.lock.Lock()
+	sub.filter = filter
+	for i := 0; i < len(items) && len(sub.queue) < cap(sub.queue); i++ {
+		if filter.Match(items[i]) {
+			sub.enqueue(items[i])
+		}
+	}
+	sub.lock.Unlock()
+	return sub.Stats(), true
+}
+
+// publishDebugData 把调试数据推送给所有订阅者
+func (s *RuleEngineService) publishDebugData(data model.DebugData) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	for _, sub := range s.onDebugObserver {
+		sub.offer(data)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+func TestDebugFilterMatch(t *testing.T) {
+	data := model.DebugData{
+		ChainId:  "chain01",
+		NodeId:   "node01",
+		FlowType: "OUT",
+		Msg:      types.RuleMsg{Id: "msg01"},
+	}
+	errData := data
+	errData.Err = "boom"
+
+	tests := []struct {
+		name   string
+		filter DebugFilter
+		data   model.DebugData
+		want   bool
+	}{
+		{name: "empty filter", filter: DebugFilter{}, data: data, want: true},
+		{name: "chain match", filter: DebugFilter{ChainId: "chain01"}, data: data, want: true},
+		{name: "chain mismatch", filter: DebugFilter{ChainId: "chain02"}, data: data, want: false},
+		{name: "flow type match", filter: DebugFilter{FlowType: "OUT"}, data: data, want: true},
+		{name: "flow type mismatch", filter: DebugFilter{FlowType: "IN"}, data: data, want: false},
+		{name: "error only without error", filter: DebugFilter{ErrorOnly: true}, data: data, want: false},
+		{name: "error only with error", filter: DebugFilter{ErrorOnly: true}, data: errData, want: true},
+		{name: "msg id match", filter: DebugFilter{MsgId: "msg01"}, data: data, want: true},
+		{name: "msg id mismatch", filter: DebugFilter{MsgId: "msg02"}, data: data, want: false},
+		{name: "node in list", filter: DebugFilter{NodeIds: []string{"node02", "node01"}}, data: data, want: true},
+		{name: "node not in list", filter: DebugFilter{NodeIds: []string{"node02", "node03"}}, data: data, want: false},
+		{
+			name:   "all conditions match",
+			filter: DebugFilter{ChainId: "chain01", NodeIds: []string{"node01"}, FlowType: "OUT", ErrorOnly: true, MsgId: "msg01"},
+			data:   errData,
+			want:   true,
+		},
+		{
+			name:   "one condition mismatch",
+			filter: DebugFilter{ChainId: "chain01", NodeIds: []string{"node01"}, FlowType: "IN", MsgId: "msg01"},
+			data:   data,
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.data); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// blockingDebugStore 查询时阻塞直到release关闭，用于检查查询期间不阻塞实时推送
+type blockingDebugStore struct {
+	dao.DebugStore
+	entered chan struct{}
+	release chan struct{}
+	items   []model.DebugData
+}
+
+func (s *blockingDebugStore) List(username string, query model.DebugDataQuery) ([]model.DebugData, int, error) {
+	close(s.entered)
+	<-s.release
+	return s.items, len(s.items), nil
+}
+
+func TestSubscribeDoesNotBlockPublish(t *testing.T) {
+	store := &blockingDebugStore{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+		items:   []model.DebugData{{ChainId: "chain01", NodeId: "old"}, {ChainId: "chain02", NodeId: "other"}},
+	}
+	oldDebugService := DebugServiceImpl
+	DebugServiceImpl = &DebugService{store: store}
+	t.Cleanup(func() { DebugServiceImpl = oldDebugService })
+
+	s := &RuleEngineService{
+		config:          config.Config{DebugQueueSize: 10},
+		logger:          log.New(io.Discard, "", 0),
+		onDebugObserver: make(map[string]*DebugSubscriber),
+	}
+	var lock sync.Mutex
+	var written []string
+	s.AddOnDebugObserver("client01", func(v interface{}) {
+		if data, ok := v.(model.DebugData); ok {
+			lock.Lock()
+			written = append(written, data.NodeId)
+			lock.Unlock()
+		}
+	})
+	defer s.RemoveOnDebugObserver("client01")
+
+	done := make(chan DebugSubscriptionStats)
+	go func() {
+		stats, _ := s.Subscribe("client01", DebugFilter{ChainId: "chain01"}, 1)
+		done <- stats
+	}()
+	<-store.entered
+
+	published := make(chan struct{})
+	go func() {
+		s.publishDebugData(model.DebugData{ChainId: "chain01", NodeId: "live"})
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish is blocked by subscribe query")
+	}
+	close(store.release)
+	if stats := <-done; stats.Filter.ChainId != "chain01" {
+		t.Errorf("filter = %+v", stats.Filter)
+	}
+	//切换过滤条件后的数据按新条件推送
+	s.publishDebugData(model.DebugData{ChainId: "chain02", NodeId: "filtered"})
+	s.publishDebugData(model.DebugData{ChainId: "chain01", NodeId: "new"})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		lock.Lock()
+		n := len(written)
+		lock.Unlock()
+		if n >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if want := []string{"live", "old", "new"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+}
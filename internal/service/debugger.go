@@ -0,0 +1,403 @@
+package service
+
+import (
+	"errors"
+	"path"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// 调试命令类型
+const (
+	// DebugCmdBreakpoints 设置规则链断点，覆盖原有断点
+	DebugCmdBreakpoints = "setBreakpoints"
+	// DebugCmdRun 调试模式执行规则链
+	DebugCmdRun = "run"
+	// DebugCmdStep 执行当前节点，并在下一个节点暂停
+	DebugCmdStep = "step"
+	// DebugCmdContinue 继续执行，直到下一个断点
+	DebugCmdContinue = "continue"
+	// DebugCmdAbort 中止执行
+	DebugCmdAbort = "abort"
+	// DebugCmdInspect 重新获取暂停的消息
+	DebugCmdInspect = "inspect"
+	// DebugCmdSubscribe 设置调试数据订阅过滤条件，可以从内存调试数据续传
+	DebugCmdSubscribe = "subscribe"
+	// DebugCmdStats 查询调试数据订阅状态
+	DebugCmdStats = "stats"
+)
+
+// 调试事件类型
+const (
+	DebugEventBreakpoints = "breakpoints"
+	DebugEventStarted     = "started"
+	DebugEventPaused      = "paused"
+	DebugEventResumed     = "resumed"
+	DebugEventCompleted   = "completed"
+	DebugEventError       = "error"
+)
+
+// defaultPauseTimeout 断点暂停超时时间，超时自动中止
+const defaultPauseTimeout = 10 * time.Minute
+
+var (
+	// ErrDebugAborted 调试执行被中止
+	ErrDebugAborted = errors.New("aborted by debugger")
+	// ErrDebugPauseNotFound 暂停点不存在或者已经恢复执行
+	ErrDebugPauseNotFound = errors.New("pause not found")
+	// ErrDebugUnknownCommand 未知调试命令
+	ErrDebugUnknownCommand = errors.New("unknown debug command")
+)
+
+// DebugCommand 客户端调试命令
+type DebugCommand struct {
+	// 命令类型 setBreakpoints/run/step/continue/abort/inspect
+	Type string `json:"type"`
+	// 规则链ID，setBreakpoints、run、subscribe有效
+	ChainId string `json:"chainId,omitempty"`
+	// 断点节点ID，setBreakpoints有效；订阅的节点ID，subscribe有效
+	NodeIds []string `json:"nodeIds,omitempty"`
+	// 订阅的流向，subscribe有效
+	FlowType string `json:"flowType,omitempty"`
+	// 只订阅有错误的调试数据，subscribe有效
+	ErrorOnly bool `json:"errorOnly,omitempty"`
+	// 订阅的消息ID，subscribe有效
+	MsgId string `json:"msgId,omitempty"`
+	// 续传该时间(毫秒)之后的内存调试数据，subscribe有效
+	Since int64 `json:"since,omitempty"`
+	// 暂停点ID，step、continue、abort、inspect有效
+	PauseId string `json:"pauseId,omitempty"`
+	// 输入消息类型，run有效
+	MsgType string `json:"msgType,omitempty"`
+	// 输入消息内容，run有效
+	Data string `json:"data,omitempty"`
+	// 输入消息元数据，run有效
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// 模拟节点，run有效
+	Mocks []model.NodeMock `json:"mocks,omitempty"`
+	// 修改后的消息，step、continue有效
+	Msg *DebugMsg `json:"msg,omitempty"`
+}
+
+// DebugMsg 修改暂停节点的输入消息，未设置的字段保持不变
+type DebugMsg struct {
+	// 消息类型
+	Type *string `json:"type,omitempty"`
+	// 消息内容
+	Data *string `json:"data,omitempty"`
+	// 元数据，替换原有元数据
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// DebugEvent 推送给客户端的调试事件
+type DebugEvent struct {
+	// 事件类型 breakpoints/started/paused/resumed/completed/error
+	Type string `json:"type"`
+	// 暂停点ID
+	PauseId string `json:"pauseId,omitempty"`
+	// 规则链ID
+	ChainId string `json:"chainId,omitempty"`
+	// 节点ID
+	NodeId string `json:"nodeId,omitempty"`
+	// 消息ID
+	MsgId string `json:"msgId,omitempty"`
+	// 规则链断点
+	NodeIds []string `json:"nodeIds,omitempty"`
+	// 暂停节点的输入消息
+	Msg *types.RuleMsg `json:"msg,omitempty"`
+	// 恢复执行的命令
+	Command string `json:"command,omitempty"`
+	// 执行结果
+	Result *TestRunResult `json:"result,omitempty"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+	// 推送队列已满累计丢弃的调试数据条数
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// DebugSession 交互式调试会话，一个WebSocket客户端对应一个会话
+// 只有通过会话启动的调试执行会在断点暂停，调试执行以及调用的子规则链都使用私有规则引擎，不影响正在运行的规则链
+type DebugSession struct {
+	clientId     string
+	service      *RuleEngineService
+	write        func(v interface{})
+	pauseTimeout time.Duration
+	// 规则链ID->断点节点ID
+	breakpoints map[string]map[string]struct{}
+	// 单步执行的消息ID
+	stepping map[string]bool
+	// 已中止的消息ID
+	aborted map[string]bool
+	// 暂停点ID->暂停节点
+	paused map[string]*pausedNode
+	seq    int64
+	closed bool
+	done   chan struct{}
+	lock   sync.Mutex
+}
+
+// pausedNode 在断点暂停的节点
+type pausedNode struct {
+	event  DebugEvent
+	resume chan DebugCommand
+}
+
+// OpenDebugSession 创建调试会话，clientId已存在则关闭原会话
+func (s *RuleEngineService) OpenDebugSession(clientId string, write func(v interface{})) *DebugSession {
+	session := &DebugSession{
+		clientId:     clientId,
+		service:      s,
+		write:        write,
+		pauseTimeout: defaultPauseTimeout,
+		breakpoints:  make(map[string]map[string]struct{}),
+		stepping:     make(map[string]bool),
+		aborted:      make(map[string]bool),
+		paused:       make(map[string]*pausedNode),
+		done:         make(chan struct{}),
+	}
+	s.locker.Lock()
+	old := s.debugSessions[clientId]
+	s.debugSessions[clientId] = session
+	s.locker.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return session
+}
+
+// GetDebugSession 获取调试会话
+func (s *RuleEngineService) GetDebugSession(clientId string) (*DebugSession, bool) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	session, ok := s.debugSessions[clientId]
+	return session, ok
+}
+
+// CloseDebugSession 关闭调试会话，暂停中的调试执行会被中止
+func (s *RuleEngineService) CloseDebugSession(clientId string) {
+	s.locker.Lock()
+	session := s.debugSessions[clientId]
+	delete(s.debugSessions, clientId)
+	s.locker.Unlock()
+	if session != nil {
+		session.Close()
+	}
+}
+
+// Close 关闭调试会话，暂停中的节点以及后续节点都会中止执行
+func (d *DebugSession) Close() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if !d.closed {
+		d.closed = true
+		close(d.done)
+	}
+}
+
+// Handle 处理客户端调试命令，命令执行失败推送error事件
+func (d *DebugSession) Handle(cmd DebugCommand) {
+	var err error
+	switch cmd.Type {
+	case DebugCmdBreakpoints:
+		err = d.setBreakpoints(cmd.ChainId, cmd.NodeIds)
+	case DebugCmdRun:
+		err = d.run(cmd)
+	case DebugCmdStep, DebugCmdContinue, DebugCmdAbort:
+		err = d.resume(cmd)
+	case DebugCmdSubscribe:
+		filter := DebugFilter{ChainId: cmd.ChainId, NodeIds: cmd.NodeIds, FlowType: cmd.FlowType, ErrorOnly: cmd.ErrorOnly, MsgId: cmd.MsgId}
+		if stats, ok := d.service.Subscribe(d.clientId, filter, cmd.Since); ok {
+			d.write(stats)
+		} else {
+			err = constants.ErrNotFound
+		}
+	case DebugCmdStats:
+		if sub, ok := d.service.GetDebugObserver(d.clientId); ok {
+			d.write(sub.Stats())
+		} else {
+			err = constants.ErrNotFound
+		}
+	case DebugCmdInspect:
+		d.lock.Lock()
+		p, ok := d.paused[cmd.PauseId]
+		d.lock.Unlock()
+		if ok {
+			d.write(p.event)
+		} else {
+			err = ErrDebugPauseNotFound
+		}
+	default:
+		err = ErrDebugUnknownCommand
+	}
+	if err != nil {
+		d.write(DebugEvent{Type: DebugEventError, PauseId: cmd.PauseId, ChainId: cmd.ChainId, Command: cmd.Type, Err: err.Error()})
+	}
+}
+
+func (d *DebugSession) setBreakpoints(chainId string, nodeIds []string) error {
+	if _, ok := d.service.Pool.Get(chainId); !ok {
+		return constants.ErrNotFound
+	}
+	var breakpoints = make(map[string]struct{})
+	for _, nodeId := range nodeIds {
+		breakpoints[nodeId] = struct{}{}
+	}
+	d.lock.Lock()
+	if len(breakpoints) == 0 {
+		delete(d.breakpoints, chainId)
+	} else {
+		d.breakpoints[chainId] = breakpoints
+	}
+	d.lock.Unlock()
+	d.write(DebugEvent{Type: DebugEventBreakpoints, ChainId: chainId, NodeIds: nodeIds})
+	return nil
+}
+
+// run 调试模式异步执行规则链，执行结束推送completed事件
+func (d *DebugSession) run(cmd DebugCommand) error {
+	if _, ok := d.service.Pool.Get(cmd.ChainId); !ok {
+		return constants.ErrNotFound
+	}
+	if err := ValidateMocks(cmd.Mocks); err != nil {
+		return err
+	}
+	metadata := types.NewMetadata()
+	for k, v := range cmd.Metadata {
+		metadata.PutValue(k, v)
+	}
+	metadata.PutValue(constants.KeyWorkDir, path.Join(d.service.config.DataDir, constants.DirWorkflows, d.service.username, constants.DirWorkflowsRule))
+	dataType := types.TEXT
+	var v interface{}
+	if json.Unmarshal([]byte(cmd.Data), &v) == nil {
+		dataType = types.JSON
+	}
+	msg := types.NewMsg(0, cmd.MsgType, dataType, metadata, cmd.Data)
+	d.write(DebugEvent{Type: DebugEventStarted, ChainId: cmd.ChainId, MsgId: msg.Id})
+	go func() {
+		result, err := d.service.testRun(cmd.ChainId, msg, cmd.Mocks, types.WithAspects(&breakpointAspect{session: d}))
+		d.lock.Lock()
+		delete(d.stepping, msg.Id)
+		delete(d.aborted, msg.Id)
+		d.lock.Unlock()
+		event := DebugEvent{Type: DebugEventCompleted, ChainId: cmd.ChainId, MsgId: msg.Id}
+		if err != nil {
+			event.Err = err.Error()
+		} else {
+			event.Result = &result
+		}
+		d.write(event)
+	}()
+	return nil
+}
+
+// resume 恢复暂停的节点
+func (d *DebugSession) resume(cmd DebugCommand) error {
+	d.lock.Lock()
+	p, ok := d.paused[cmd.PauseId]
+	if ok {
+		delete(d.paused, cmd.PauseId)
+	}
+	d.lock.Unlock()
+	if !ok {
+		return ErrDebugPauseNotFound
+	}
+	p.resume <- cmd
+	return nil
+}
+
+// pause 节点执行前检查断点，命中断点或者单步执行时暂停，直到客户端恢复执行
+// 返回false表示不再由引擎执行该节点：节点已经使用修改后的消息执行，或者执行被中止
+func (d *DebugSession) pause(ctx types.RuleContext, msg types.RuleMsg) (types.RuleMsg, bool) {
+	chainId := ctx.RuleChain().GetNodeId().Id
+	nodeId := ctx.GetSelfId()
+	d.lock.Lock()
+	if d.closed || d.aborted[msg.Id] {
+		d.lock.Unlock()
+		ctx.DoOnEnd(msg, ErrDebugAborted, types.Failure)
+		return msg, false
+	}
+	_, hit := d.breakpoints[chainId][nodeId]
+	if !hit && !d.stepping[msg.Id] {
+		d.lock.Unlock()
+		return msg, true
+	}
+	d.seq++
+	pauseId := strconv.FormatInt(d.seq, 10)
+	msgCopy := msg.Copy()
+	p := &pausedNode{
+		event:  DebugEvent{Type: DebugEventPaused, PauseId: pauseId, ChainId: chainId, NodeId: nodeId, MsgId: msg.Id, Msg: &msgCopy},
+		resume: make(chan DebugCommand, 1),
+	}
+	d.paused[pauseId] = p
+	d.lock.Unlock()
+	d.write(p.event)
+
+	var cmd DebugCommand
+	select {
+	case cmd = <-p.resume:
+	case <-time.After(d.pauseTimeout):
+		cmd = DebugCommand{Type: DebugCmdAbort}
+	case <-d.done:
+		cmd = DebugCommand{Type: DebugCmdAbort}
+	}
+	d.lock.Lock()
+	delete(d.paused, pauseId)
+	switch cmd.Type {
+	case DebugCmdStep:
+		d.stepping[msg.Id] = true
+	case DebugCmdContinue:
+		delete(d.stepping, msg.Id)
+	default:
+		d.aborted[msg.Id] = true
+	}
+	d.lock.Unlock()
+	d.write(DebugEvent{Type: DebugEventResumed, PauseId: pauseId, ChainId: chainId, NodeId: nodeId, MsgId: msg.Id, Command: cmd.Type})
+
+	if cmd.Type == DebugCmdAbort {
+		ctx.DoOnEnd(msg, ErrDebugAborted, types.Failure)
+		return msg, false
+	}
+	if cmd.Msg != nil {
+		edited := msg.Copy()
+		if cmd.Msg.Type != nil {
+			edited.Type = *cmd.Msg.Type
+		}
+		if cmd.Msg.Data != nil {
+			edited.Data = *cmd.Msg.Data
+		}
+		if cmd.Msg.Metadata != nil {
+			edited.Metadata = types.BuildMetadata(cmd.Msg.Metadata)
+		}
+		//环绕切面返回的消息不会作为节点入参，使用修改后的消息手动执行节点
+		ctx.Self().OnMsg(ctx, edited)
+		return edited, false
+	}
+	return msg, true
+}
+
+// breakpointAspect 断点切面，只添加到调试执行的私有规则引擎
+type breakpointAspect struct {
+	session *DebugSession
+}
+
+func (a *breakpointAspect) Order() int {
+	return 900
+}
+
+func (a *breakpointAspect) New() types.Aspect {
+	return &breakpointAspect{session: a.session}
+}
+
+func (a *breakpointAspect) PointCut(ctx types.RuleContext, msg types.RuleMsg, relationType string) bool {
+	return true
+}
+
+func (a *breakpointAspect) Around(ctx types.RuleContext, msg types.RuleMsg, relationType string) (types.RuleMsg, bool) {
+	return a.session.pause(ctx, msg)
+}
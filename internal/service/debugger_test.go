@@ -0,0 +1,98 @@
+package service
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// subTransformDsl 原样输出消息的子规则链
+const subTransformDsl = `{"ruleChain":{"id":"sub","name":"sub"},"metadata":{"nodes":[
+	{"id":"s1","type":"jsTransform","debugMode":true,"configuration":{"jsScript":"return {msg:msg,metadata:metadata,msgType:msgType};"}}],
+	"connections":[]}}`
+
+// waitDebugEvent 等待指定类型的调试事件，忽略其他事件
+func waitDebugEvent(t *testing.T, events <-chan DebugEvent, eventType string) DebugEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == DebugEventError {
+				t.Fatalf("debug error: %+v", event)
+			}
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("wait %s event timeout", eventType)
+		}
+	}
+}
+
+func TestDebugSessionBreakpointInSubChain(t *testing.T) {
+	var liveCalls int64
+	s := newTestEngineService(t, &liveCalls, "root", rootChainDsl, "sub", subTransformDsl)
+	events := make(chan DebugEvent, 100)
+	session := s.OpenDebugSession("client01", func(v interface{}) {
+		if event, ok := v.(DebugEvent); ok {
+			events <- event
+		}
+	})
+	defer s.CloseDebugSession("client01")
+
+	session.Handle(DebugCommand{Type: DebugCmdBreakpoints, ChainId: "sub", NodeIds: []string{"s1"}})
+	session.Handle(DebugCommand{Type: DebugCmdRun, ChainId: "root", MsgType: "TEST", Data: `{"v":1}`})
+
+	paused := waitDebugEvent(t, events, DebugEventPaused)
+	if paused.ChainId != "sub" || paused.NodeId != "s1" {
+		t.Fatalf("paused at %s/%s, want sub/s1", paused.ChainId, paused.NodeId)
+	}
+	if paused.Msg == nil || paused.Msg.Data != `{"v":1}` {
+		t.Errorf("paused msg = %+v", paused.Msg)
+	}
+	edited := `{"v":2}`
+	session.Handle(DebugCommand{Type: DebugCmdContinue, PauseId: paused.PauseId, Msg: &DebugMsg{Data: &edited}})
+
+	completed := waitDebugEvent(t, events, DebugEventCompleted)
+	if completed.Result == nil || completed.Result.Err != "" || completed.Result.Msg == nil {
+		t.Fatalf("completed = %+v", completed)
+	}
+	var outputs []struct {
+		Msg struct {
+			Data string `json:"data"`
+		} `json:"msg"`
+	}
+	if err := json.Unmarshal([]byte(completed.Result.Msg.Data), &outputs); err != nil || len(outputs) != 1 || outputs[0].Msg.Data != edited {
+		t.Errorf("output = %s, want sub chain data %s", completed.Result.Msg.Data, edited)
+	}
+	if got := atomic.LoadInt64(&liveCalls); got != 0 {
+		t.Errorf("live rule chains called %d times", got)
+	}
+}
+
+func TestDebugSessionAbortInSubChain(t *testing.T) {
+	var liveCalls int64
+	s := newTestEngineService(t, &liveCalls, "root", rootChainDsl, "sub", subTransformDsl)
+	events := make(chan DebugEvent, 100)
+	session := s.OpenDebugSession("client01", func(v interface{}) {
+		if event, ok := v.(DebugEvent); ok {
+			events <- event
+		}
+	})
+	defer s.CloseDebugSession("client01")
+
+	session.Handle(DebugCommand{Type: DebugCmdBreakpoints, ChainId: "sub", NodeIds: []string{"s1"}})
+	session.Handle(DebugCommand{Type: DebugCmdRun, ChainId: "root", MsgType: "TEST", Data: `{"v":1}`})
+	paused := waitDebugEvent(t, events, DebugEventPaused)
+	session.Handle(DebugCommand{Type: DebugCmdAbort, PauseId: paused.PauseId})
+
+	completed := waitDebugEvent(t, events, DebugEventCompleted)
+	if completed.Result == nil || completed.Result.Err == "" {
+		t.Fatalf("completed = %+v, want aborted error", completed)
+	}
+	if got := atomic.LoadInt64(&liveCalls); got != 0 {
+		t.Errorf("live rule chains called %d times", got)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+var DedupeServiceImpl *DedupeService
+
+// KeyIdempotencyWindow 规则链configuration配置幂等窗口，例如：10m，数字表示秒，不配置则不去重
+const KeyIdempotencyWindow = "idempotencyWindow"
+
+// defaultDedupeLease 默认处理中租约时间
+const defaultDedupeLease = 5 * time.Minute
+
+// DedupeService 按msgId对消息去重，幂等窗口内相同msgId只处理一次
+type DedupeService struct {
+	store dao.DedupeStore
+	//处理中租约时间，请求中断没有完成时，超过该时间相同msgId可以再次处理
+	lease time.Duration
+}
+
+func NewDedupeService(config config.Config) (*DedupeService, error) {
+	store, err := dao.NewDedupeStore(config)
+	if err != nil {
+		return nil, err
+	}
+	lease := config.DedupeLease
+	if lease <= 0 {
+		lease = defaultDedupeLease
+	}
+	s := &DedupeService{store: store, lease: lease}
+	go s.cleanup()
+	return s, nil
+}
+
+// Window 获取规则链幂等窗口，0表示不去重
+func (s *DedupeService) Window(username, chainId string) time.Duration {
+	if UserRuleEngineServiceImpl == nil {
+		return 0
+	}
+	ruleEngineService, ok := UserRuleEngineServiceImpl.Get(username)
+	if !ok {
+		return 0
+	}
+	def, ok := ruleEngineService.Get(chainId)
+	if !ok {
+		return 0
+	}
+	return parseWindow(def.RuleChain.Configuration)
+}
+
+// Acquire 占用msgId，返回true表示需要处理，否则返回已存在的记录
+// msgId为空或者规则链没有配置幂等窗口时总是返回true
+func (s *DedupeService) Acquire(username, chainId, msgId string) (*model.MsgDedupe, bool, error) {
+	if msgId == "" {
+		return nil, true, nil
+	}
+	window := s.Window(username, chainId)
+	if window <= 0 {
+		return nil, true, nil
+	}
+	lease := s.lease
+	if lease > window {
+		lease = window
+	}
+	return s.store.Acquire(username, chainId, msgId, window, lease)
+}
+
+// Complete 保存处理结果，相同msgId重复请求时返回该结果
+// 处理失败时释放msgId，重试时重新处理
+func (s *DedupeService) Complete(username, chainId, msgId, result string, err error) error {
+	if msgId == "" || s.Window(username, chainId) <= 0 {
+		return nil
+	}
+	if err != nil {
+		return s.store.Release(username, chainId, msgId)
+	}
+	return s.store.Complete(username, chainId, msgId, result)
+}
+
+// DeleteByChainId 删除规则链所有去重记录
+func (s *DedupeService) DeleteByChainId(username, chainId string) error {
+	return s.store.DeleteByChainId(username, chainId)
+}
+
+// cleanup 定时清除过期的去重记录
+func (s *DedupeService) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if err := s.store.DeleteExpired(now); err != nil {
+			logger.Logger.Printf("service/DedupeService:cleanup error%s", err.Error())
+		}
+	}
+}
+
+// parseWindow 解析幂等窗口，支持时间字符串和秒数
+func parseWindow(configuration types.Configuration) time.Duration {
+	if configuration == nil {
+		return 0
+	}
+	switch v := configuration[KeyIdempotencyWindow].(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	case int:
+		return time.Duration(v) * time.Second
+	case int64:
+		return time.Duration(v) * time.Second
+	}
+	return 0
+}
@@ -9,6 +9,7 @@ import (
 	"ruleGoProject/config/logger"
 	"ruleGoProject/internal/constants"
 	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
 	"sort"
 	"sync"
 	"time"
@@ -18,6 +19,7 @@ import (
 	luaEngine "github.com/rulego/rulego-components/pkg/lua_engine"
 	"github.com/rulego/rulego/api/types"
 	"github.com/rulego/rulego/components/action"
+	"github.com/rulego/rulego/engine"
 	"github.com/rulego/rulego/utils/fs"
 	"github.com/rulego/rulego/utils/json"
 )
@@ -26,15 +28,25 @@ var UserRuleEngineServiceImpl *UserRuleEngineService
 
 // UserRuleEngineService 用户规则引擎池
 type UserRuleEngineService struct {
-	Pool   map[string]*RuleEngineService
-	config config.Config
-	locker sync.RWMutex
+	Pool map[string]*RuleEngineService
+	//规则引擎池初始化失败的用户，用户名->失败原因
+	initErrors map[string]string
+	config     config.Config
+	//规则链持久化存储
+	ruleStore dao.RuleStore
+	locker    sync.RWMutex
 }
 
 func NewUserRuleEngineServiceImpl(c config.Config) (*UserRuleEngineService, error) {
+	ruleStore, err := dao.NewRuleStore(c)
+	if err != nil {
+		return nil, err
+	}
 	s := &UserRuleEngineService{
-		Pool:   make(map[string]*RuleEngineService),
-		config: c,
+		Pool:       make(map[string]*RuleEngineService),
+		initErrors: make(map[string]string),
+		config:     c,
+		ruleStore:  ruleStore,
 	}
 	userPath := path.Join(c.DataDir, constants.DirWorkflows)
 	//创建文件夹
@@ -51,7 +63,20 @@ func NewUserRuleEngineServiceImpl(c config.Config) (*UserRuleEngineService, erro
 			}
 		}
 	}
-	return s, err
+	//初始化存储中拥有规则链的用户
+	owners, err := ruleStore.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		if _, ok := s.Pool[owner]; ok || owner == "" {
+			continue
+		}
+		if _, err := s.Init(owner); err != nil {
+			logger.Logger.Println("Init "+owner+" error:", err.Error())
+		}
+	}
+	return s, nil
 }
 
 // Get 根据用户获取规则引擎池
@@ -67,50 +92,99 @@ func (s *UserRuleEngineService) Get(username string) (*RuleEngineService, bool)
 	return v, ok
 }
 
+// Load 获取已初始化的用户规则引擎池，不会自动初始化
+func (s *UserRuleEngineService) Load(username string) (*RuleEngineService, bool) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	v, ok := s.Pool[username]
+	return v, ok
+}
+
+// Users 获取已初始化的用户列表
+func (s *UserRuleEngineService) Users() []string {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	var users []string
+	for username := range s.Pool {
+		users = append(users, username)
+	}
+	return users
+}
+
 func (s *UserRuleEngineService) Init(username string) (*RuleEngineService, error) {
-	if v, err := NewRuleEngineService(s.config, username); err == nil {
+	if v, err := NewRuleEngineService(s.config, username, s.ruleStore); err == nil {
 		s.locker.Lock()
 		s.Pool[username] = v
+		delete(s.initErrors, username)
 		s.locker.Unlock()
 		return v, nil
 	} else {
+		s.locker.Lock()
+		s.initErrors[username] = err.Error()
+		s.locker.Unlock()
 		return nil, err
 	}
 }
 
+// Remove 停止并移除用户规则引擎池，例如：用户被删除
+func (s *UserRuleEngineService) Remove(username string) {
+	s.locker.Lock()
+	v, ok := s.Pool[username]
+	delete(s.Pool, username)
+	delete(s.initErrors, username)
+	s.locker.Unlock()
+	if ok {
+		v.Stop()
+	}
+}
+
+// InitErrors 规则引擎池初始化失败的用户和失败原因
+func (s *UserRuleEngineService) InitErrors() map[string]string {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	var result = make(map[string]string, len(s.initErrors))
+	for username, err := range s.initErrors {
+		result[username] = err
+	}
+	return result
+}
+
+// Owners 规则链存储中拥有规则链的用户
+func (s *UserRuleEngineService) Owners() ([]string, error) {
+	return s.ruleStore.ListUsers()
+}
+
 type RuleEngineService struct {
-	Pool       *rulego.RuleGo
+	Pool       *engine.Pool
 	username   string
 	config     config.Config
 	ruleConfig types.Config
 	logger     *log.Logger
-	//基于内存的节点调试数据管理器
-	//如果需要查询历史数据，请把调试日志数据存放数据库等可以持久化载体
-	ruleChainDebugData *RuleChainDebugData
-	onDebugObserver    map[string]func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error)
-	ruleDao            *dao.RuleDao
-	locker             sync.RWMutex
+	//WebSocket客户端ID->调试数据订阅者
+	onDebugObserver map[string]*DebugSubscriber
+	ruleStore       dao.RuleStore
+	//WebSocket客户端ID->交互式调试会话
+	debugSessions map[string]*DebugSession
+	//加载失败的规则链，规则链ID->失败原因
+	failedChains map[string]string
+	//组件插件加载结果
+	plugins []model.LoadResult
+	//js自定义函数文件加载结果
+	udfs   []model.LoadResult
+	locker sync.RWMutex
 }
 
-func NewRuleEngineService(c config.Config, username string) (*RuleEngineService, error) {
-	var pool = &rulego.RuleGo{}
-	ruleDao, err := dao.NewRuleDao(c)
-	if err != nil {
-		return nil, err
-	}
-	maxNodeLogSize := c.MaxNodeLogSize
-	if maxNodeLogSize == 0 {
-		maxNodeLogSize = 40
-	}
+func NewRuleEngineService(c config.Config, username string, ruleStore dao.RuleStore) (*RuleEngineService, error) {
+	var pool = engine.NewPool()
 	service := &RuleEngineService{
 		Pool:            pool,
 		username:        username,
 		logger:          logger.Logger,
 		config:          c,
-		onDebugObserver: make(map[string]func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error)),
-		//基于内存的节点调试数据管理器
-		ruleChainDebugData: NewRuleChainDebugData(maxNodeLogSize),
-		ruleDao:            ruleDao,
+		onDebugObserver: make(map[string]*DebugSubscriber),
+		ruleStore:       ruleStore,
+		debugSessions:   make(map[string]*DebugSession),
+		failedChains:    make(map[string]string),
 	}
 	service.initRuleGo(logger.Logger, c.DataDir, username)
 	return service, nil
@@ -147,8 +221,8 @@ func (s *RuleEngineService) GetDsl(chainId, nodeId string) ([]byte, error) {
 	return nil, constants.ErrNotFound
 }
 
-// SaveDsl 保存或者更新DSL
-func (s *RuleEngineService) SaveDsl(chainId, nodeId string, def []byte) error {
+// SaveDsl 保存或者更新DSL，并生成历史版本
+func (s *RuleEngineService) SaveDsl(chainId, nodeId string, def []byte, author, message string) error {
 	var err error
 	if chainId != "" {
 		ruleEngine, ok := s.Pool.Get(chainId)
@@ -159,7 +233,7 @@ func (s *RuleEngineService) SaveDsl(chainId, nodeId string, def []byte) error {
 				err = ruleEngine.ReloadChild(nodeId, def)
 			}
 		} else {
-			ruleEngine, err = s.Pool.New(chainId, def, rulego.WithConfig(s.ruleConfig))
+			ruleEngine, err = s.Pool.New(chainId, def, s.engineOptions()...)
 		}
 		if err != nil {
 			return err
@@ -168,8 +242,7 @@ func (s *RuleEngineService) SaveDsl(chainId, nodeId string, def []byte) error {
 		//修改更新时间
 		s.fillAdditionalInfo(self)
 		//持久化规则链
-		return s.ruleDao.SaveToDataBase(chainId, def)
-		// return s.ruleDao.Save(s.username, chainId, def)
+		return s.persist(chainId, ruleEngine, author, message)
 	}
 
 	return err
@@ -206,16 +279,26 @@ func (s *RuleEngineService) List() []types.RuleChain {
 // Delete 删除规则链
 func (s *RuleEngineService) Delete(chainId string) error {
 	s.Pool.Del(chainId)
-	if err := s.ruleDao.DeleteToDataBase(chainId); err != nil {
-		// if err := s.ruleDao.Delete(s.username, chainId); err != nil {
+	s.setFailedChain(chainId, nil)
+	if err := s.ruleStore.Delete(s.username, chainId); err != nil {
+		return err
+	} else if err := dao.DeleteRuleRevisionByRuleChainId(s.username, chainId); err != nil {
+		return err
+	} else if err := dao.DeleteRuleTestCaseByRuleChainId(s.username, chainId); err != nil {
+		return err
+	} else if err := DedupeServiceImpl.DeleteByChainId(s.username, chainId); err != nil {
+		return err
+	} else if err := ScheduleServiceImpl.DeleteByChainId(s.username, chainId); err != nil {
+		return err
+	} else if err := DebugServiceImpl.DeleteByChainId(s.username, chainId); err != nil {
 		return err
 	} else {
 		return EventServiceImpl.DeleteByChainId(s.username, chainId)
 	}
 }
 
-// SaveBaseInfo 保存规则链基本信息
-func (s *RuleEngineService) SaveBaseInfo(chainId string, baseInfo types.RuleChainBaseInfo) error {
+// SaveBaseInfo 保存规则链基本信息，并生成历史版本
+func (s *RuleEngineService) SaveBaseInfo(chainId string, baseInfo types.RuleChainBaseInfo, author, message string) error {
 	if chainId != "" {
 		ruleEngine, ok := s.Pool.Get(chainId)
 		if ok {
@@ -233,23 +316,34 @@ func (s *RuleEngineService) SaveBaseInfo(chainId string, baseInfo types.RuleChai
 			//修改更新时间
 			s.fillAdditionalInfo(&def)
 			jsonStr, _ := json.Marshal(def)
-			if e, err := s.Pool.New(chainId, jsonStr, rulego.WithConfig(s.ruleConfig)); nil != err {
+			if e, err := s.Pool.New(chainId, jsonStr, s.engineOptions()...); nil != err {
 				return err
 			} else {
 				ruleEngine = e
 			}
 		}
-		def, _ := json.Format(ruleEngine.DSL())
-		return s.ruleDao.SaveToDataBase(chainId, def)
-		// return s.ruleDao.Save(s.username, chainId, def)
+		return s.persist(chainId, ruleEngine, author, message)
 	} else {
 		return errors.New("not found for" + chainId)
 	}
 }
 
-// SaveConfiguration 保存规则链配置
-func (s *RuleEngineService) SaveConfiguration(chainId string, key string, configuration interface{}) error {
+// SaveConfiguration 保存规则链配置，并生成历史版本
+func (s *RuleEngineService) SaveConfiguration(chainId string, key string, configuration interface{}, author, message string) error {
 	if chainId != "" {
+		if key == KeySchedules {
+			if _, err := ParseSchedules(types.Configuration{KeySchedules: configuration}); err != nil {
+				return err
+			}
+		} else if key == KeyRetention {
+			if _, err := ParseRetention(types.Configuration{KeyRetention: configuration}); err != nil {
+				return err
+			}
+		} else if key == KeyMocks {
+			if _, err := ParseMocks(types.Configuration{KeyMocks: configuration}); err != nil {
+				return err
+			}
+		}
 		ruleEngine, ok := s.Pool.Get(chainId)
 		if ok {
 			self := ruleEngine.RootRuleChainCtx().Definition()
@@ -264,9 +358,7 @@ func (s *RuleEngineService) SaveConfiguration(chainId string, key string, config
 			if err := ruleEngine.ReloadSelf(ruleEngine.DSL()); err != nil {
 				return err
 			}
-			def, _ := json.Format(ruleEngine.DSL())
-			// return s.ruleDao.Save(s.username, chainId, def)
-			return s.ruleDao.SaveToDataBase(chainId, def)
+			return s.persist(chainId, ruleEngine, author, message)
 		} else {
 			return errors.New("not found for" + chainId)
 		}
@@ -275,29 +367,149 @@ func (s *RuleEngineService) SaveConfiguration(chainId string, key string, config
 	}
 }
 
-// OnDebug 调试日志
-func (s *RuleEngineService) OnDebug(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error) {
-	s.locker.RLock()
-	defer s.locker.RUnlock()
-	for _, f := range s.onDebugObserver {
-		go f(chainId, flowType, nodeId, msg, relationType, err)
+// persist 持久化规则链，并生成一个不可修改的历史版本
+// 数据库存储在同一个事务中保存，其他存储先创建历史版本，保存规则链失败则删除该版本
+func (s *RuleEngineService) persist(chainId string, ruleEngine types.RuleEngine, author, message string) error {
+	def, _ := json.Format(ruleEngine.DSL())
+	revision := &model.RuleRevision{
+		Owner:       s.username,
+		RuleChainId: chainId,
+		RuleConfig:  string(def),
+		Author:      author,
+		Message:     message,
 	}
+	if store, ok := s.ruleStore.(dao.RevisionRuleStore); ok {
+		if err := store.SaveWithRevision(s.username, chainId, def, revision); err != nil {
+			return err
+		}
+	} else if err := dao.CreateRuleRevision(revision); err != nil {
+		return err
+	} else if err := s.ruleStore.Save(s.username, chainId, def); err != nil {
+		if rollbackErr := dao.DeleteRuleRevision(s.username, chainId, revision.Version); rollbackErr != nil {
+			s.logger.Printf("service/RuleEngineService:persist delete revision chainId=%s version=%d error%s", chainId, revision.Version, rollbackErr.Error())
+		}
+		return err
+	}
+	//重新注册定时任务
+	s.registerSchedules(chainId, ruleEngine)
+	s.setFailedChain(chainId, nil)
+	return nil
 }
 
-func (s *RuleEngineService) AddOnDebugObserver(clientId string, f func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error)) {
+// Stop 注销定时任务，关闭调试会话和调试数据订阅，并停止所有规则链
+func (s *RuleEngineService) Stop() {
+	s.Pool.Range(func(key, value any) bool {
+		if chainId, ok := key.(string); ok && ScheduleServiceImpl != nil {
+			ScheduleServiceImpl.Unregister(s.username, chainId)
+		}
+		return true
+	})
 	s.locker.Lock()
-	defer s.locker.Unlock()
-	s.onDebugObserver[clientId] = f
+	var clientIds []string
+	for clientId := range s.debugSessions {
+		clientIds = append(clientIds, clientId)
+	}
+	for clientId := range s.onDebugObserver {
+		clientIds = append(clientIds, clientId)
+	}
+	s.locker.Unlock()
+	for _, clientId := range clientIds {
+		s.RemoveOnDebugObserver(clientId)
+		s.CloseDebugSession(clientId)
+	}
+	s.Pool.Stop()
 }
 
-func (s *RuleEngineService) RemoveOnDebugObserver(clientId string) {
+// setFailedChain 记录规则链加载失败原因，err为nil则移除
+func (s *RuleEngineService) setFailedChain(chainId string, err error) {
 	s.locker.Lock()
 	defer s.locker.Unlock()
-	delete(s.onDebugObserver, clientId)
+	if err == nil {
+		delete(s.failedChains, chainId)
+	} else {
+		s.failedChains[chainId] = err.Error()
+	}
 }
 
-func (s *RuleEngineService) DebugData() *RuleChainDebugData {
-	return s.ruleChainDebugData
+// LoadStatus 规则链数量、加载失败的规则链以及插件和js自定义函数文件加载结果
+func (s *RuleEngineService) LoadStatus() model.UserStatus {
+	status := model.UserStatus{
+		Username:     s.username,
+		Initialized:  true,
+		FailedChains: make([]model.LoadResult, 0),
+		Plugins:      make([]model.LoadResult, 0),
+		Udfs:         make([]model.LoadResult, 0),
+	}
+	s.Pool.Range(func(key, value any) bool {
+		status.Chains++
+		return true
+	})
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	for chainId, err := range s.failedChains {
+		status.FailedChains = append(status.FailedChains, model.LoadResult{Name: chainId, Err: err})
+	}
+	sort.Slice(status.FailedChains, func(i, j int) bool {
+		return status.FailedChains[i].Name < status.FailedChains[j].Name
+	})
+	status.Plugins = append(status.Plugins, s.plugins...)
+	status.Udfs = append(status.Udfs, s.udfs...)
+	return status
+}
+
+// registerSchedules 注册规则链定时任务
+func (s *RuleEngineService) registerSchedules(chainId string, ruleEngine types.RuleEngine) {
+	if ScheduleServiceImpl == nil {
+		return
+	}
+	if err := ScheduleServiceImpl.Register(s.username, chainId, ruleEngine.Definition()); err != nil {
+		s.logger.Printf("register schedules chain=%s error=%s", chainId, err.Error())
+	}
+}
+
+// addDebugData 记录节点调试数据，并通知调试观察者
+func (s *RuleEngineService) addDebugData(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error, mocked bool) {
+	var errStr = ""
+	if err != nil {
+		errStr = err.Error()
+	}
+	if s.config.Debug {
+		s.logger.Printf("chainId=%s,flowType=%s,nodeId=%s,data=%s,err=%s", chainId, flowType, nodeId, msg.Data, err)
+	}
+	data := model.DebugData{
+		//规则链ID
+		ChainId: chainId,
+		Ts:      time.Now().UnixMilli(),
+		//节点ID
+		NodeId: nodeId,
+		//流向OUT/IN
+		FlowType: flowType,
+		//消息
+		Msg: msg,
+		//关系
+		RelationType: relationType,
+		//Err 错误
+		Err: errStr,
+		//是否模拟节点
+		Mocked: mocked,
+	}
+	//把日志记录到调试数据存储，用于界面查询和断线续传
+	if DebugServiceImpl != nil {
+		DebugServiceImpl.Add(s.username, data)
+	}
+	if MetricsImpl != nil {
+		MetricsImpl.observeDebugData(s.username, data)
+	}
+	s.publishDebugData(data)
+}
+
+// engineOptions 用户规则链池中规则引擎的创建参数
+func (s *RuleEngineService) engineOptions() []types.RuleEngineOption {
+	aspects := []types.Aspect{&metricsAspect{username: s.username}}
+	if TracingImpl != nil {
+		aspects = append(aspects, &tracingAspect{username: s.username})
+	}
+	return []types.RuleEngineOption{rulego.WithConfig(s.ruleConfig), types.WithAspects(aspects...)}
 }
 
 // 初始化规则链池
@@ -313,28 +525,7 @@ func (s *RuleEngineService) initRuleGo(logger *log.Logger, workspacePath string,
 	ruleConfig.Properties.PutValue(action.KeyExecNodeWhitelist, s.config.CmdWhiteList)
 	ruleConfig.Properties.PutValue(action.KeyWorkDir, s.config.DataDir)
 	ruleConfig.OnDebug = func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error) {
-		var errStr = ""
-		if err != nil {
-			errStr = err.Error()
-		}
-		if s.config.Debug {
-			logger.Printf("chainId=%s,flowType=%s,nodeId=%s,data=%s,err=%s", chainId, flowType, nodeId, msg.Data, err)
-		}
-		//把日志记录到内存管理器，用于界面显示
-		s.ruleChainDebugData.Add(chainId, nodeId, DebugData{
-			Ts: time.Now().UnixMilli(),
-			//节点ID
-			NodeId: nodeId,
-			//流向OUT/IN
-			FlowType: flowType,
-			//消息
-			Msg: msg,
-			//关系
-			RelationType: relationType,
-			//Err 错误
-			Err: errStr,
-		})
-		s.OnDebug(chainId, flowType, nodeId, msg, relationType, err)
+		s.addDebugData(chainId, flowType, nodeId, msg, relationType, err, false)
 	}
 	s.ruleConfig = ruleConfig
 
@@ -351,18 +542,8 @@ func (s *RuleEngineService) initRuleGo(logger *log.Logger, workspacePath string,
 	if err != nil {
 		logger.Fatal("parser plugin file error:", err)
 	}
-	allRuleList, aErr := dao.GetAllLoadRegulation()
-	if aErr != nil {
-		logger.Fatal("parser plugin file error:", aErr)
-	}
-	ruleStrList := make([]string, 0)
-	for _, itme := range allRuleList {
-		ruleStrList = append(ruleStrList, itme.RuleConfig)
-	}
-	//加载规则链
-	rulesPath := path.Join(workspacePath, constants.DirWorkflows, username, constants.DirWorkflowsRule)
 	// 加载所有持久化规则链
-	err = s.loadRulesByPersisted(rulesPath, ruleStrList)
+	err = s.loadRulesByPersisted(username)
 	if err != nil {
 		logger.Fatal("parser rule file error:", err)
 	}
@@ -381,7 +562,9 @@ func (s *RuleEngineService) loadJs(folderPath string) error {
 		if b := fs.LoadFile(file); b != nil {
 			if p, err := goja.Compile(file, string(b), true); err != nil {
 				s.logger.Printf("Compile js file=%s err=%s", file, err.Error())
+				s.udfs = append(s.udfs, model.LoadResult{Name: path.Base(file), Err: err.Error()})
 			} else {
+				s.udfs = append(s.udfs, model.LoadResult{Name: path.Base(file), Ok: true})
 				s.ruleConfig.RegisterUdf(path.Base(file), types.Script{
 					Type:    types.Js,
 					Content: p,
@@ -405,6 +588,9 @@ func (s *RuleEngineService) loadPlugins(folderPath string) error {
 	for _, file := range paths {
 		if err := rulego.Registry.RegisterPlugin(path.Base(file), file); err != nil {
 			s.logger.Printf("load plugin=%s error=%s", file, err.Error())
+			s.plugins = append(s.plugins, model.LoadResult{Name: path.Base(file), Err: err.Error()})
+		} else {
+			s.plugins = append(s.plugins, model.LoadResult{Name: path.Base(file), Ok: true})
 		}
 	}
 	return nil
@@ -415,7 +601,7 @@ func (s *RuleEngineService) loadPlugins(folderPath string) error {
 // 	//创建文件夹
 // 	_ = fs.CreateDirs(folderPath)
 // 	//遍历所有文件
-// 	err := s.Pool.Load(folderPath, rulego.WithConfig(s.ruleConfig))
+// 	err := s.Pool.Load(folderPath, s.engineOptions()...)
 // 	if err != nil {
 // 		s.logger.Fatal("parser rule file error:", err)
 // 	}
@@ -436,37 +622,20 @@ func (s *RuleEngineService) fillAdditionalInfo(def *types.RuleChain) {
 	def.RuleChain.AdditionalInfo["updateTime"] = nowStr
 }
 
-/**
- * 加载规则链
- *
- * @folderPath 空路径 为了可以使用Load中的g.ruleEnginePool = engine.NewPool()赋值
- * @ruleList 持久化查出来的所有需要加载的规则
- */
-func (s *RuleEngineService) loadRulesByPersisted(folderPath string, ruleList []string) error {
-	var err error
-	err = s.Pool.Load(folderPath, rulego.WithConfig(s.ruleConfig))
+// loadRulesByPersisted 从规则链存储加载用户所有规则链
+// 单条规则链加载失败只记录日志，不影响其他规则链加载
+func (s *RuleEngineService) loadRulesByPersisted(username string) error {
+	ruleList, err := s.ruleStore.List(username)
 	if err != nil {
-		s.logger.Fatal("初始化规则引擎异常:", err)
 		return err
 	}
-	// 遍历所有的需要加载的规则链
 	for _, item := range ruleList {
-		var ruleTree RuleTree
-		json.Unmarshal([]byte(item), &ruleTree)
-		if _, err = s.Pool.New(ruleTree.RuleChain.Id, []byte(item), rulego.WithConfig(s.ruleConfig)); err != nil {
-			s.logger.Fatal("加载规则链异常:", err)
-			return err
+		if ruleEngine, err := s.Pool.New(item.ChainId, item.Def, s.engineOptions()...); err != nil {
+			s.logger.Printf("load rule chain=%s error=%s", item.ChainId, err.Error())
+			s.setFailedChain(item.ChainId, err)
+		} else {
+			s.registerSchedules(item.ChainId, ruleEngine)
 		}
 	}
-	return err
-}
-
-type RuleTree struct {
-	RuleChain RuleChainTree `json:"ruleChain"`
-	MetaData  interface{}   `json:"metadata"`
-}
-
-type RuleChainTree struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
+	return nil
 }
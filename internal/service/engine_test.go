@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"testing"
+)
+
+// newTestDB 使用临时目录的sqlite数据库替换model.DBClient，测试结束后恢复
+func newTestDB(t *testing.T) {
+	t.Helper()
+	db, err := model.NewDBWithStruct(&model.ORMConfig{
+		Driver:  model.DriverSqlite,
+		DBname:  filepath.Join(t.TempDir(), "test.db"),
+		LogMode: model.LogModeSilent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldClient := model.DBClient
+	model.DBClient = db
+	t.Cleanup(func() {
+		model.DBClient = oldClient
+		db.Close()
+	})
+}
+
+// failingRuleStore 保存规则链总是失败的存储
+type failingRuleStore struct {
+	dao.RuleStore
+}
+
+func (s *failingRuleStore) Save(username, chainId string, def []byte) error {
+	return errors.New("save failed")
+}
+
+func TestPersistRevision(t *testing.T) {
+	tests := []struct {
+		name         string
+		store        dao.RuleStore
+		wantErr      bool
+		wantVersions int
+	}{
+		{name: "sql store", store: dao.NewSqlRuleStore(), wantVersions: 1},
+		{name: "memory store", store: dao.NewMemoryRuleStore(), wantVersions: 1},
+		{name: "save failed", store: &failingRuleStore{RuleStore: dao.NewMemoryRuleStore()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t)
+			var liveCalls int64
+			s := newTestEngineService(t, &liveCalls, "sub", subTransformDsl)
+			s.ruleStore = tt.store
+			ruleEngine, _ := s.Pool.Get("sub")
+			err := s.persist("sub", ruleEngine, "test", "message")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("persist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			revisions, err := dao.ListRuleRevision("test", "sub")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(revisions) != tt.wantVersions {
+				t.Errorf("revisions = %d, want %d", len(revisions), tt.wantVersions)
+			}
+			if _, err := tt.store.Load("test", "sub"); (err == nil) != (tt.wantVersions > 0) {
+				t.Errorf("Load() error = %v", err)
+			}
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"os"
+	"path/filepath"
 	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
 	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"strings"
+	"time"
 
 	"github.com/rulego/rulego/api/types"
 )
@@ -10,37 +17,118 @@ import (
 var EventServiceImpl *EventService
 
 type EventService struct {
-	EventDao *dao.EventDao
-	config   config.Config
+	store  dao.SnapshotStore
+	config config.Config
 }
 
 func NewEventService(config config.Config) (*EventService, error) {
-	if eventDao, err := dao.NewEventDao(config); err != nil {
+	if store, err := dao.NewSnapshotStore(config); err != nil {
 		return nil, err
 	} else {
 		return &EventService{
-			EventDao: eventDao,
-			config:   config,
+			store:  store,
+			config: config,
 		}, nil
 	}
 }
 
 // SaveRunLog 保存工作流运行日志快照
 func (s *EventService) SaveRunLog(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) error {
-	return s.EventDao.SaveRunLog(ctx, snapshot)
+	_, err := s.saveRunLog(ctx, snapshot)
+	return err
+}
+
+// saveRunLog 保存工作流运行日志快照，返回快照ID
+func (s *EventService) saveRunLog(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) (string, error) {
+	username := s.getUserNameFromSnapshot(snapshot)
+	chainId := ctx.RuleChain().GetNodeId().Id
+	//快照ID：{毫秒时间}_{msgId}
+	snapshot.Id = snapshotIdTime(time.Now()) + "_" + snapshot.Id
+	if err := s.store.Save(username, chainId, snapshot); err != nil {
+		logger.Logger.Printf("service/EventService:SaveRunLog chainId=%s error%s", chainId, err.Error())
+		return "", err
+	}
+	return snapshot.Id, nil
+}
+
+// snapshotIdTime 快照ID时间部分，精确到毫秒，例如：20240601120000123
+func snapshotIdTime(t time.Time) string {
+	return strings.Replace(t.Format("20060102150405.000"), ".", "", 1)
 }
 
 func (s *EventService) Delete(username, chainId, id string) error {
-	return s.EventDao.Delete(username, chainId, id)
+	return s.store.Delete(username, chainId, id)
 }
 func (s *EventService) DeleteByChainId(username, chainId string) error {
-	return s.EventDao.DeleteByChainId(username, chainId)
+	return s.store.DeleteByChainId(username, chainId)
 }
 
-func (s *EventService) List(username, chainId string, current, size int) ([]types.RuleChainRunSnapshot, int, error) {
-	return s.EventDao.List(username, chainId, current, size)
+// List 按查询条件分页查询运行快照
+func (s *EventService) List(username string, query model.RunSnapshotQuery) ([]types.RuleChainRunSnapshot, int, error) {
+	return s.store.List(username, query)
 }
 
 func (s *EventService) Get(username, chainId, snapshotId string) (types.RuleChainRunSnapshot, error) {
-	return s.EventDao.Get(username, chainId, snapshotId)
+	return s.store.Get(username, chainId, snapshotId)
+}
+
+// Usage 按规则链统计运行快照存储占用，用户为空则统计所有用户
+func (s *EventService) Usage(username string) ([]model.SnapshotUsage, error) {
+	return s.store.Usage(username)
+}
+
+// Purge 按保留策略清理规则链运行快照
+func (s *EventService) Purge(username, chainId string, policy model.RetentionPolicy, now time.Time) (model.PurgeResult, error) {
+	return s.store.Purge(username, chainId, policy, now)
+}
+
+func (s *EventService) getUserNameFromSnapshot(snapshot types.RuleChainRunSnapshot) string {
+	if v, ok := snapshot.RuleChain.RuleChain.AdditionalInfo[constants.KeyUsername]; ok {
+		return v
+	}
+	return s.config.DefaultUsername
+}
+
+// MigrateRunLogFiles 把运行快照文件迁移到数据库，需要先调用StartDB连接数据库
+// 已迁移过的快照会跳过，removeFile 迁移成功后是否删除快照文件
+func MigrateRunLogFiles(config config.Config, removeFile bool) (MigrateResult, error) {
+	fileStore := dao.NewFileSnapshotStore(config)
+	sqlStore := dao.NewSqlSnapshotStore()
+	var result MigrateResult
+	err := fileStore.Range(func(username, chainId, filePath string) bool {
+		snapshot, err := dao.ReadSnapshotFile(filePath)
+		var inserted bool
+		if err == nil {
+			//快照ID与文件名一致
+			snapshot.Id = filepath.Base(filePath)
+			inserted, err = sqlStore.Import(username, chainId, snapshot)
+		}
+		if err != nil {
+			result.Failed++
+			logger.Logger.Printf("migrate run log file=%s error=%s", filePath, err.Error())
+			return true
+		}
+		if inserted {
+			result.Migrated++
+		} else {
+			result.Skipped++
+		}
+		if removeFile {
+			if err := os.Remove(filePath); err != nil {
+				logger.Logger.Printf("remove run log file=%s error=%s", filePath, err.Error())
+			}
+		}
+		return true
+	})
+	return result, err
+}
+
+// MigrateResult 运行快照迁移结果
+type MigrateResult struct {
+	// 迁移成功数量
+	Migrated int
+	// 已存在跳过数量
+	Skipped int
+	// 失败数量
+	Failed int
 }
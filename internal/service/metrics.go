@@ -0,0 +1,307 @@
+package service
+
+import (
+	"net/http"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/model"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rulego/rulego/api/types"
+)
+
+// MetricsImpl prometheus指标，未开启metrics时为nil
+var MetricsImpl *Metrics
+
+const (
+	metricsNamespace = "rulego"
+	// defaultMetricsMaxLabelValues 每个标签默认最多取值个数
+	defaultMetricsMaxLabelValues = 100
+	// metricsOtherLabel 超过标签取值个数后使用的标签值
+	metricsOtherLabel = "other"
+	// maxPendingNodeSpans 等待配对的节点IN/OUT调试数据最大条数，超过则清空，避免没有OUT数据的节点占用内存
+	maxPendingNodeSpans = 10000
+)
+
+// 标签名称
+const (
+	labelUser    = "user"
+	labelChain   = "chain"
+	labelNode    = "node"
+	labelMsgType = "msg_type"
+	labelMethod  = "method"
+	labelRoute   = "route"
+	labelCode    = "code"
+	labelLabel   = "label"
+)
+
+// Metrics prometheus指标
+// 规则链执行次数通过规则引擎切面统计，节点执行次数、错误和耗时通过节点调试数据统计，只统计开启debugMode的节点
+// 用户、规则链、节点、消息类型等标签取值个数有上限，超过后使用other，保证指标数量有上限
+type Metrics struct {
+	registry        *prometheus.Registry
+	chainExecutions *prometheus.CounterVec
+	nodeInvocations *prometheus.CounterVec
+	nodeErrors      *prometheus.CounterVec
+	nodeDuration    *prometheus.HistogramVec
+	httpDuration    *prometheus.HistogramVec
+	labelOverflow   *prometheus.CounterVec
+	maxLabelValues  int
+	labelValues     map[string]map[string]struct{}
+	labelLock       sync.Mutex
+	//节点IN/OUT调试数据配对，key:用户+规则链ID+节点ID+消息ID
+	pending     map[string]nodeSpan
+	pendingLock sync.Mutex
+}
+
+// nodeSpan 等待配对的节点调试数据
+type nodeSpan struct {
+	ts int64
+	//是否OUT数据，调试数据异步记录，OUT数据可能先到
+	out bool
+}
+
+func NewMetrics(config config.Config) *Metrics {
+	maxLabelValues := config.MetricsMaxLabelValues
+	if maxLabelValues <= 0 {
+		maxLabelValues = defaultMetricsMaxLabelValues
+	}
+	m := &Metrics{
+		registry:       prometheus.NewRegistry(),
+		maxLabelValues: maxLabelValues,
+		labelValues:    make(map[string]map[string]struct{}),
+		pending:        make(map[string]nodeSpan),
+		chainExecutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "chain_executions_total",
+			Help:      "Rule chain executions by chain and msg type.",
+		}, []string{labelUser, labelChain, labelMsgType}),
+		nodeInvocations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_invocations_total",
+			Help:      "Node invocations, only nodes with debugMode enabled.",
+		}, []string{labelUser, labelChain, labelNode}),
+		nodeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_errors_total",
+			Help:      "Node errors, only nodes with debugMode enabled.",
+		}, []string{labelUser, labelChain, labelNode}),
+		nodeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_duration_seconds",
+			Help:      "Node duration from IN to OUT debug data, millisecond precision.",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+		}, []string{labelUser, labelChain, labelNode}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{labelMethod, labelRoute, labelCode}),
+		labelOverflow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "metrics_label_overflow_total",
+			Help:      "Label values replaced by other after reaching metrics_max_label_values.",
+		}, []string{labelLabel}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.chainExecutions,
+		m.nodeInvocations,
+		m.nodeErrors,
+		m.nodeDuration,
+		m.httpDuration,
+		m.labelOverflow,
+		&stateCollector{metrics: m},
+	)
+	return m
+}
+
+// Handler 指标http处理器
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHttp 记录http请求耗时，route为注册路由时的路径模板
+func (m *Metrics) ObserveHttp(method, route string, code int, duration time.Duration) {
+	m.httpDuration.WithLabelValues(m.label(labelMethod, method), m.label(labelRoute, route), strconv.Itoa(code)).Observe(duration.Seconds())
+}
+
+// observeExecution 记录规则链执行
+func (m *Metrics) observeExecution(username, chainId, msgType string) {
+	m.chainExecutions.WithLabelValues(m.label(labelUser, username), m.label(labelChain, chainId), m.label(labelMsgType, msgType)).Inc()
+}
+
+// observeDebugData 根据节点调试数据记录节点执行次数、错误和耗时
+func (m *Metrics) observeDebugData(username string, data model.DebugData) {
+	if data.FlowType != types.In && data.FlowType != types.Out {
+		return
+	}
+	user := m.label(labelUser, username)
+	chain := m.label(labelChain, data.ChainId)
+	node := m.nodeLabel(data.ChainId, data.NodeId)
+	if data.FlowType == types.In {
+		m.nodeInvocations.WithLabelValues(user, chain, node).Inc()
+	} else if data.Err != "" {
+		m.nodeErrors.WithLabelValues(user, chain, node).Inc()
+	}
+	if duration, ok := m.pairSpan(username+"\x00"+data.ChainId+"\x00"+data.NodeId+"\x00"+data.Msg.Id, data); ok {
+		m.nodeDuration.WithLabelValues(user, chain, node).Observe(float64(duration) / 1000)
+	}
+}
+
+// pairSpan 配对同一条消息在同一个节点的IN和OUT调试数据，配对成功返回耗时(毫秒)
+func (m *Metrics) pairSpan(key string, data model.DebugData) (int64, bool) {
+	out := data.FlowType == types.Out
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	if span, ok := m.pending[key]; ok && span.out != out {
+		delete(m.pending, key)
+		if out {
+			return max(data.Ts-span.ts, 0), true
+		}
+		return max(span.ts-data.Ts, 0), true
+	}
+	if len(m.pending) >= maxPendingNodeSpans {
+		m.pending = make(map[string]nodeSpan)
+	}
+	m.pending[key] = nodeSpan{ts: data.Ts, out: out}
+	return 0, false
+}
+
+// nodeLabel 节点标签，不同规则链的同名节点分别计数
+func (m *Metrics) nodeLabel(chainId, nodeId string) string {
+	if m.label(labelNode, chainId+"\x00"+nodeId) == metricsOtherLabel {
+		return metricsOtherLabel
+	}
+	return nodeId
+}
+
+// label 限制标签取值个数，超过后返回other
+func (m *Metrics) label(name, value string) string {
+	m.labelLock.Lock()
+	defer m.labelLock.Unlock()
+	values, ok := m.labelValues[name]
+	if !ok {
+		values = make(map[string]struct{})
+		m.labelValues[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= m.maxLabelValues {
+		m.labelOverflow.WithLabelValues(name).Inc()
+		return metricsOtherLabel
+	}
+	values[value] = struct{}{}
+	return value
+}
+
+var (
+	debugClientsDesc = prometheus.NewDesc(metricsNamespace+"_websocket_debug_clients",
+		"Active WebSocket debug clients.", []string{labelUser}, nil)
+	ruleChainsDesc = prometheus.NewDesc(metricsNamespace+"_rule_chains",
+		"Rule chains loaded in the user rule engine pool.", []string{labelUser}, nil)
+	debugDroppedDesc = prometheus.NewDesc(metricsNamespace+"_debug_store_dropped_total",
+		"Node debug data dropped because the persistent store write queue was full.", nil, nil)
+	dbMaxOpenDesc = prometheus.NewDesc(metricsNamespace+"_db_max_open_connections",
+		"Maximum number of open connections to the database.", nil, nil)
+	dbOpenDesc = prometheus.NewDesc(metricsNamespace+"_db_open_connections",
+		"The number of established connections both in use and idle.", nil, nil)
+	dbInUseDesc = prometheus.NewDesc(metricsNamespace+"_db_in_use_connections",
+		"The number of connections currently in use.", nil, nil)
+	dbIdleDesc = prometheus.NewDesc(metricsNamespace+"_db_idle_connections",
+		"The number of idle connections.", nil, nil)
+	dbWaitCountDesc = prometheus.NewDesc(metricsNamespace+"_db_wait_count_total",
+		"The total number of connections waited for.", nil, nil)
+	dbWaitDurationDesc = prometheus.NewDesc(metricsNamespace+"_db_wait_duration_seconds_total",
+		"The total time blocked waiting for a new connection.", nil, nil)
+)
+
+// stateCollector 抓取时读取当前状态的指标：WebSocket调试客户端数、用户规则链数、调试数据丢弃数、数据库连接池状态
+type stateCollector struct {
+	metrics *Metrics
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- debugClientsDesc
+	ch <- ruleChainsDesc
+	ch <- debugDroppedDesc
+	ch <- dbMaxOpenDesc
+	ch <- dbOpenDesc
+	ch <- dbInUseDesc
+	ch <- dbIdleDesc
+	ch <- dbWaitCountDesc
+	ch <- dbWaitDurationDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	if UserRuleEngineServiceImpl != nil {
+		var clients = make(map[string]int)
+		var chains = make(map[string]int)
+		for _, username := range UserRuleEngineServiceImpl.Users() {
+			s, ok := UserRuleEngineServiceImpl.Load(username)
+			if !ok {
+				continue
+			}
+			user := c.metrics.label(labelUser, username)
+			clients[user] += s.DebugObserverCount()
+			s.Pool.Range(func(key, value any) bool {
+				chains[user]++
+				return true
+			})
+		}
+		for user, count := range clients {
+			ch <- prometheus.MustNewConstMetric(debugClientsDesc, prometheus.GaugeValue, float64(count), user)
+		}
+		for user, count := range chains {
+			ch <- prometheus.MustNewConstMetric(ruleChainsDesc, prometheus.GaugeValue, float64(count), user)
+		}
+	}
+	if DebugServiceImpl != nil {
+		ch <- prometheus.MustNewConstMetric(debugDroppedDesc, prometheus.CounterValue, float64(DebugServiceImpl.Dropped()))
+	}
+	if model.DBClient != nil {
+		if state := model.DBClient.GetState(); state != nil {
+			ch <- prometheus.MustNewConstMetric(dbMaxOpenDesc, prometheus.GaugeValue, float64(state.MaxOpenConnections))
+			ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(state.OpenConnections))
+			ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(state.InUse))
+			ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(state.Idle))
+			ch <- prometheus.MustNewConstMetric(dbWaitCountDesc, prometheus.CounterValue, float64(state.WaitCount))
+			ch <- prometheus.MustNewConstMetric(dbWaitDurationDesc, prometheus.CounterValue, state.WaitDuration.Seconds())
+		}
+	}
+}
+
+// metricsAspect 统计规则链执行次数的切面
+type metricsAspect struct {
+	username string
+}
+
+func (a *metricsAspect) Order() int {
+	return 10
+}
+
+func (a *metricsAspect) New() types.Aspect {
+	return &metricsAspect{username: a.username}
+}
+
+func (a *metricsAspect) PointCut(ctx types.RuleContext, msg types.RuleMsg, relationType string) bool {
+	return true
+}
+
+func (a *metricsAspect) Start(ctx types.RuleContext, msg types.RuleMsg) types.RuleMsg {
+	if MetricsImpl != nil {
+		var chainId string
+		if chainCtx := ctx.RuleChain(); chainCtx != nil {
+			chainId = chainCtx.GetNodeId().Id
+		}
+		MetricsImpl.observeExecution(a.username, chainId, msg.Type)
+	}
+	return msg
+}
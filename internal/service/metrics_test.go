@@ -0,0 +1,69 @@
+package service
+
+import (
+	"ruleGoProject/config"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsLabel(t *testing.T) {
+	m := NewMetrics(config.Config{MetricsMaxLabelValues: 2})
+	tests := []struct {
+		name  string
+		label string
+		value string
+		want  string
+	}{
+		{name: "first value", label: labelUser, value: "u1", want: "u1"},
+		{name: "second value", label: labelUser, value: "u2", want: "u2"},
+		{name: "overflow", label: labelUser, value: "u3", want: metricsOtherLabel},
+		{name: "known value after overflow", label: labelUser, value: "u1", want: "u1"},
+		{name: "overflow again", label: labelUser, value: "u4", want: metricsOtherLabel},
+		{name: "other label counted separately", label: labelChain, value: "c1", want: "c1"},
+		{name: "empty value counted", label: labelChain, value: "", want: ""},
+		{name: "other label overflow", label: labelChain, value: "c2", want: metricsOtherLabel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.label(tt.label, tt.value); got != tt.want {
+				t.Errorf("label(%s, %s) = %s, want %s", tt.label, tt.value, got, tt.want)
+			}
+		})
+	}
+	for label, want := range map[string]float64{labelUser: 2, labelChain: 1, labelNode: 0} {
+		if got := testutil.ToFloat64(m.labelOverflow.WithLabelValues(label)); got != want {
+			t.Errorf("overflow[%s] = %v, want %v", label, got, want)
+		}
+	}
+}
+
+func TestMetricsNodeLabel(t *testing.T) {
+	m := NewMetrics(config.Config{MetricsMaxLabelValues: 2})
+	tests := []struct {
+		name    string
+		chainId string
+		nodeId  string
+		want    string
+	}{
+		{name: "first node", chainId: "c1", nodeId: "n1", want: "n1"},
+		{name: "same node id in other chain", chainId: "c2", nodeId: "n1", want: "n1"},
+		{name: "overflow", chainId: "c1", nodeId: "n2", want: metricsOtherLabel},
+		{name: "known node after overflow", chainId: "c2", nodeId: "n1", want: "n1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.nodeLabel(tt.chainId, tt.nodeId); got != tt.want {
+				t.Errorf("nodeLabel(%s, %s) = %s, want %s", tt.chainId, tt.nodeId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsDefaultMaxLabelValues(t *testing.T) {
+	for _, maxLabelValues := range []int{0, -1} {
+		if m := NewMetrics(config.Config{MetricsMaxLabelValues: maxLabelValues}); m.maxLabelValues != defaultMetricsMaxLabelValues {
+			t.Errorf("maxLabelValues = %d, want %d", m.maxLabelValues, defaultMetricsMaxLabelValues)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/engine"
+	"github.com/rulego/rulego/utils/json"
+	"github.com/rulego/rulego/utils/maps"
+)
+
+const (
+	// KeyMocks 规则链configuration模拟节点配置
+	KeyMocks = "mocks"
+	// mockNodeType 模拟节点组件类型，只在测试模式的私有规则引擎中注册
+	mockNodeType = "test/mock"
+	// keyMockOf 模拟节点配置中记录的原节点类型
+	keyMockOf = "mockOf"
+)
+
+// TestRunResult 测试模式执行结果
+type TestRunResult struct {
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 消息ID
+	MsgId string `json:"msgId"`
+	// 运行快照ID
+	SnapshotId string `json:"snapshotId"`
+	// 被模拟的节点ID
+	Mocked []string `json:"mocked"`
+	// 最终输出消息，规则链有多个结束分支时取最后结束的分支
+	Msg *types.RuleMsg `json:"msg,omitempty"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+}
+
+// ParseMocks 从规则链configuration解析模拟节点配置
+func ParseMocks(configuration types.Configuration) ([]model.NodeMock, error) {
+	if configuration == nil || configuration[KeyMocks] == nil {
+		return nil, nil
+	}
+	var mocks []model.NodeMock
+	if v, err := json.Marshal(configuration[KeyMocks]); err != nil {
+		return nil, err
+	} else if err = json.Unmarshal(v, &mocks); err != nil {
+		return nil, err
+	}
+	return mocks, ValidateMocks(mocks)
+}
+
+// ValidateMocks 校验模拟节点配置，nodeId和type至少配置一个
+func ValidateMocks(mocks []model.NodeMock) error {
+	for i, item := range mocks {
+		if item.NodeId == "" && item.Type == "" {
+			return fmt.Errorf("mocks[%d] nodeId or type is required", i)
+		}
+	}
+	return nil
+}
+
+// TestRun 测试模式执行规则链，并等待执行结束
+// 请求模拟配置优先于规则链保存的模拟配置，匹配的节点不执行原组件，直接返回配置的输出
+// 使用私有规则引擎池执行，调用的子规则链同样替换模拟节点，不会调用正在运行的规则链
+// 保存运行快照和节点调试数据，模拟节点在运行快照和调试数据中都有标记
+func (s *RuleEngineService) TestRun(chainId string, msg types.RuleMsg, mocks []model.NodeMock) (TestRunResult, error) {
+	return s.testRun(chainId, msg, mocks)
+}
+
+// testRun 测试模式执行规则链，opts用于给私有规则引擎增加切面等配置
+func (s *RuleEngineService) testRun(chainId string, msg types.RuleMsg, mocks []model.NodeMock, opts ...types.RuleEngineOption) (TestRunResult, error) {
+	var result = TestRunResult{ChainId: chainId, MsgId: msg.Id, Mocked: []string{}}
+	if err := ValidateMocks(mocks); err != nil {
+		return result, err
+	}
+	liveEngine, ok := s.Pool.Get(chainId)
+	if !ok {
+		return result, constants.ErrNotFound
+	}
+	ruleChain := liveEngine.Definition()
+	if _, err := ParseMocks(ruleChain.RuleChain.Configuration); err != nil {
+		return result, err
+	}
+	var mocked = make(map[string]map[string]bool)
+	pool, ruleEngine, err := s.newPrivatePool(chainId, ruleChain, mockTransform(chainId, mocks, true, mocked), s.mockConfig(mocked), opts...)
+	if err != nil {
+		return result, err
+	}
+	defer pool.Stop()
+	result.Mocked = mockedNodeIds(chainId, pool, mocked)
+
+	var lock sync.Mutex
+	var lastErr error
+	ruleEngine.OnMsgAndWait(msg, types.WithOnEnd(func(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) {
+		lock.Lock()
+		defer lock.Unlock()
+		result.Msg = &msg
+		if err != nil {
+			lastErr = err
+		}
+	}), types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
+		id, _ := EventServiceImpl.saveRunLog(ctx, snapshot)
+		lock.Lock()
+		defer lock.Unlock()
+		result.SnapshotId = id
+	}))
+	lock.Lock()
+	defer lock.Unlock()
+	if lastErr != nil {
+		result.Err = lastErr.Error()
+	}
+	return result, nil
+}
+
+// mockConfig 测试模式规则引擎配置，注册模拟节点组件，模拟节点的调试数据增加模拟标记
+// mocked为规则链ID->被模拟的节点ID，创建私有规则引擎池时填充
+func (s *RuleEngineService) mockConfig(mocked map[string]map[string]bool) types.Config {
+	config := s.ruleConfig
+	config.ComponentsRegistry = &mockRegistry{ComponentRegistry: s.ruleConfig.ComponentsRegistry}
+	config.OnDebug = func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error) {
+		s.addDebugData(chainId, flowType, nodeId, msg, relationType, err, mocked[chainId][nodeId])
+	}
+	return config
+}
+
+// mockTransform 创建私有规则引擎池时替换每个规则链的模拟节点，被模拟的节点记录到mocked
+// 节点ID格式为"规则链ID:节点ID"的配置只匹配该规则链，只有节点ID的配置只匹配根规则链，节点类型的配置匹配所有规则链
+// withStored 是否追加规则链自身保存的模拟配置，优先级低于请求的模拟配置
+func mockTransform(rootId string, mocks []model.NodeMock, withStored bool, mocked map[string]map[string]bool) func(chainId string, ruleChain types.RuleChain) types.RuleChain {
+	return func(chainId string, ruleChain types.RuleChain) types.RuleChain {
+		chainMocks := make([]model.NodeMock, 0, len(mocks))
+		for _, mock := range mocks {
+			switch {
+			case mock.NodeId == "":
+			case strings.HasPrefix(mock.NodeId, chainId+":"):
+				mock.NodeId = strings.TrimPrefix(mock.NodeId, chainId+":")
+			case chainId != rootId:
+				continue
+			}
+			chainMocks = append(chainMocks, mock)
+		}
+		if withStored {
+			//保存时已经校验，子规则链配置错误则忽略
+			stored, _ := ParseMocks(ruleChain.RuleChain.Configuration)
+			chainMocks = append(chainMocks, stored...)
+		}
+		ruleChain, mocked[chainId] = mockRuleChain(ruleChain, chainMocks)
+		return ruleChain
+	}
+}
+
+// mockedNodeIds 被模拟的节点ID，根规则链节点在前，子规则链节点格式为"规则链ID:节点ID"
+func mockedNodeIds(rootId string, pool *engine.Pool, mocked map[string]map[string]bool) []string {
+	var chainIds []string
+	for chainId := range mocked {
+		if chainId != rootId {
+			chainIds = append(chainIds, chainId)
+		}
+	}
+	sort.Strings(chainIds)
+	var ids = []string{}
+	for _, chainId := range append([]string{rootId}, chainIds...) {
+		e, ok := pool.Get(chainId)
+		if !ok {
+			continue
+		}
+		for _, node := range e.Definition().Metadata.Nodes {
+			if !mocked[chainId][node.Id] {
+				continue
+			}
+			if chainId == rootId {
+				ids = append(ids, node.Id)
+			} else {
+				ids = append(ids, chainId+":"+node.Id)
+			}
+		}
+	}
+	return ids
+}
+
+// mockRuleChain 把匹配模拟配置的节点替换成模拟节点组件，节点ID和连接关系不变，返回被模拟的节点ID
+// 按配置顺序匹配，节点ID匹配优先于节点类型匹配，禁用的配置不生效
+func mockRuleChain(ruleChain types.RuleChain, mocks []model.NodeMock) (types.RuleChain, map[string]bool) {
+	var mocked = make(map[string]bool)
+	if len(mocks) == 0 {
+		return ruleChain, mocked
+	}
+	var nodes = make([]*types.RuleNode, 0, len(ruleChain.Metadata.Nodes))
+	for _, node := range ruleChain.Metadata.Nodes {
+		if mock, ok := matchMock(node, mocks); ok {
+			mockNode := *node
+			mockNode.Type = mockNodeType
+			mockNode.Configuration = types.Configuration{
+				keyMockOf:      node.Type,
+				"data":         mock.Data,
+				"metadata":     mock.Metadata,
+				"relationType": mock.RelationType,
+				"err":          mock.Err,
+			}
+			node = &mockNode
+			mocked[node.Id] = true
+		}
+		nodes = append(nodes, node)
+	}
+	ruleChain.Metadata.Nodes = nodes
+	return ruleChain, mocked
+}
+
+func matchMock(node *types.RuleNode, mocks []model.NodeMock) (model.NodeMock, bool) {
+	for _, mock := range mocks {
+		if !mock.Disabled && mock.NodeId != "" && mock.NodeId == node.Id {
+			return mock, true
+		}
+	}
+	for _, mock := range mocks {
+		if !mock.Disabled && mock.NodeId == "" && mock.Type == node.Type {
+			return mock, true
+		}
+	}
+	return model.NodeMock{}, false
+}
+
+// mockRegistry 在原组件注册器基础上增加模拟节点组件，不影响全局组件列表
+type mockRegistry struct {
+	types.ComponentRegistry
+}
+
+func (r *mockRegistry) NewNode(nodeType string) (types.Node, error) {
+	if nodeType == mockNodeType {
+		return &mockNode{}, nil
+	}
+	return r.ComponentRegistry.NewNode(nodeType)
+}
+
+// mockNode 模拟节点组件，不执行原组件逻辑，直接返回配置的输出
+// 执行时记录一条模拟日志，运行快照的节点日志和调试数据都可以看到
+type mockNode struct {
+	config model.NodeMock
+	mockOf string
+}
+
+func (n *mockNode) Type() string {
+	return mockNodeType
+}
+
+func (n *mockNode) New() types.Node {
+	return &mockNode{}
+}
+
+func (n *mockNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+	if v, ok := configuration[keyMockOf].(string); ok {
+		n.mockOf = v
+	}
+	return maps.Map2Struct(configuration, &n.config)
+}
+
+func (n *mockNode) OnMsg(ctx types.RuleContext, msg types.RuleMsg) {
+	relationType := n.config.RelationType
+	if n.config.Err != "" {
+		relationType = types.Failure
+	} else if relationType == "" {
+		relationType = types.Success
+	}
+	logMsg := msg.Copy()
+	logMsg.Data = fmt.Sprintf("mocked %s: relationType=%s", n.mockOf, relationType)
+	if n.config.Err != "" {
+		logMsg.Data += " err=" + n.config.Err
+	}
+	ctx.OnDebug(ctx.RuleChain().GetNodeId().Id, types.Log, ctx.GetSelfId(), logMsg, relationType, nil)
+
+	if n.config.Err != "" {
+		ctx.TellFailure(msg, errors.New(n.config.Err))
+		return
+	}
+	msg.Metadata = msg.Metadata.Copy()
+	if n.config.Data != "" {
+		msg.Data = n.config.Data
+	}
+	for k, v := range n.config.Metadata {
+		msg.Metadata.PutValue(k, v)
+	}
+	ctx.TellNext(msg, relationType)
+}
+
+func (n *mockNode) Destroy() {
+}
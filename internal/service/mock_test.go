@@ -0,0 +1,177 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"ruleGoProject/config"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rulego/rulego"
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/engine"
+)
+
+// newTestEngineService 创建只包含内存规则引擎池的用户规则引擎服务，运行快照保存到临时目录
+// 正在运行的规则链每执行一个节点，liveCalls加1，用于检查私有规则引擎是否调用了正在运行的规则链
+func newTestEngineService(t *testing.T, liveCalls *int64, chains ...string) *RuleEngineService {
+	t.Helper()
+	c := config.Config{DataDir: t.TempDir()}
+	oldEventService := EventServiceImpl
+	EventServiceImpl = &EventService{store: dao.NewFileSnapshotStore(c), config: c}
+	t.Cleanup(func() { EventServiceImpl = oldEventService })
+
+	s := &RuleEngineService{
+		Pool:            engine.NewPool(),
+		username:        "test",
+		config:          c,
+		ruleConfig:      rulego.NewConfig(),
+		logger:          log.New(io.Discard, "", 0),
+		onDebugObserver: make(map[string]*DebugSubscriber),
+		debugSessions:   make(map[string]*DebugSession),
+		failedChains:    make(map[string]string),
+	}
+	liveConfig := rulego.NewConfig()
+	liveConfig.OnDebug = func(chainId, flowType string, nodeId string, msg types.RuleMsg, relationType string, err error) {
+		if flowType == types.In {
+			atomic.AddInt64(liveCalls, 1)
+		}
+	}
+	for i := 0; i+1 < len(chains); i += 2 {
+		if _, err := s.Pool.New(chains[i], []byte(chains[i+1]), rulego.WithConfig(liveConfig)); err != nil {
+			t.Fatalf("create rule chain %s error: %v", chains[i], err)
+		}
+	}
+	t.Cleanup(s.Pool.Stop)
+	return s
+}
+
+// rootChainDsl 调用子规则链sub的根规则链
+const rootChainDsl = `{"ruleChain":{"id":"root","name":"root"},"metadata":{"nodes":[
+	{"id":"t1","type":"jsTransform","debugMode":true,"configuration":{"jsScript":"return {msg:msg,metadata:metadata,msgType:msgType};"}},
+	{"id":"f1","type":"flow","debugMode":true,"configuration":{"targetId":"sub"}}],
+	"connections":[{"fromId":"t1","toId":"f1","type":"Success"}]}}`
+
+// subChainDsl 调用http接口的子规则链
+func subChainDsl(url string) string {
+	return fmt.Sprintf(`{"ruleChain":{"id":"sub","name":"sub"},"metadata":{"nodes":[
+	{"id":"call","type":"restApiCall","debugMode":true,"configuration":{"restEndpointUrlPattern":"%s","requestMethod":"POST"}}],
+	"connections":[]}}`, url)
+}
+
+func TestTestRunMocksSubChain(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		_, _ = w.Write([]byte(`{"from":"server"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		mocks        []model.NodeMock
+		wantRequests int64
+		wantMocked   []string
+		wantData     string
+	}{
+		{
+			name:         "type mock applies to sub chain",
+			mocks:        []model.NodeMock{{Type: "restApiCall", Data: `{"from":"mock"}`}},
+			wantMocked:   []string{"sub:call"},
+			wantData:     `{"from":"mock"}`,
+			wantRequests: 0,
+		},
+		{
+			name:         "sub chain node id mock",
+			mocks:        []model.NodeMock{{NodeId: "sub:call", Data: `{"from":"node"}`}},
+			wantMocked:   []string{"sub:call"},
+			wantData:     `{"from":"node"}`,
+			wantRequests: 0,
+		},
+		{
+			name:         "root node id mock does not match sub chain",
+			mocks:        []model.NodeMock{{NodeId: "call", Data: `{"from":"mock"}`}},
+			wantMocked:   []string{},
+			wantData:     `{"from":"server"}`,
+			wantRequests: 1,
+		},
+		{
+			name:         "root and sub chain mocks",
+			mocks:        []model.NodeMock{{NodeId: "t1"}, {Type: "restApiCall", Data: `{"from":"mock"}`}},
+			wantMocked:   []string{"t1", "sub:call"},
+			wantData:     `{"from":"mock"}`,
+			wantRequests: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var liveCalls int64
+			atomic.StoreInt64(&requests, 0)
+			s := newTestEngineService(t, &liveCalls, "root", rootChainDsl, "sub", subChainDsl(server.URL))
+			msg := types.NewMsg(0, "TEST", types.JSON, types.NewMetadata(), `{"v":1}`)
+			result, err := s.TestRun("root", msg, tt.mocks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Err != "" {
+				t.Fatalf("result err = %s", result.Err)
+			}
+			if got := atomic.LoadInt64(&requests); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if !reflect.DeepEqual(result.Mocked, tt.wantMocked) {
+				t.Errorf("mocked = %v, want %v", result.Mocked, tt.wantMocked)
+			}
+			//flow节点输出子规则链每个结束分支的消息
+			var outputs []struct {
+				Msg types.RuleMsg `json:"msg"`
+			}
+			if result.Msg == nil || json.Unmarshal([]byte(result.Msg.Data), &outputs) != nil || len(outputs) != 1 || outputs[0].Msg.Data != tt.wantData {
+				t.Errorf("msg = %+v, want sub chain data %s", result.Msg, tt.wantData)
+			}
+			if result.SnapshotId == "" {
+				t.Error("snapshot is not saved")
+			}
+			if got := atomic.LoadInt64(&liveCalls); got != 0 {
+				t.Errorf("live rule chains called %d times", got)
+			}
+		})
+	}
+}
+
+func TestSubChainIds(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain types.RuleChain
+		want  []string
+	}{
+		{name: "no sub chain", chain: types.RuleChain{Metadata: types.RuleMetadata{Nodes: []*types.RuleNode{{Id: "a", Type: "log"}}}}, want: nil},
+		{
+			name: "flow ref and rule chain connections",
+			chain: types.RuleChain{Metadata: types.RuleMetadata{
+				Nodes: []*types.RuleNode{
+					{Id: "f", Type: "flow", Configuration: types.Configuration{"targetId": "s1"}},
+					{Id: "r1", Type: "ref", Configuration: types.Configuration{"targetId": "s2:n1"}},
+					{Id: "r2", Type: "ref", Configuration: types.Configuration{"targetId": "n1"}},
+					nil,
+				},
+				RuleChainConnections: []types.RuleChainConnection{{FromId: "f", ToId: "s3", Type: "Success"}},
+			}},
+			want: []string{"s1", "s2", "s3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subChainIds(tt.chain); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subChainIds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
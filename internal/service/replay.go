@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/dao"
+	"strings"
+	"sync"
+
+	"github.com/rulego/rulego"
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/engine"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// ErrReplayNodeNotFound 重放指定的开始节点不存在
+var ErrReplayNodeNotFound = errors.New("replay start node not found")
+
+// ReplayOptions 重放参数
+type ReplayOptions struct {
+	// 原运行快照所属规则链ID，为空则从快照中获取
+	ChainId string
+	// 使用指定历史版本执行，0表示使用当前版本
+	Version int
+	// 从指定节点开始执行，并使用原运行中该节点的输入消息，为空则从第一个节点开始执行
+	NodeId string
+}
+
+// ReplayResult 重放结果
+type ReplayResult struct {
+	// 原运行快照ID
+	ReplayOf string `json:"replayOf"`
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 使用的历史版本，0表示当前版本
+	Version int `json:"version,omitempty"`
+	// 开始节点
+	NodeId string `json:"nodeId,omitempty"`
+	// 重放消息ID
+	MsgId string `json:"msgId"`
+	// 新的运行快照ID
+	SnapshotId string `json:"snapshotId"`
+	// 最终输出消息，规则链有多个结束分支时取最后结束的分支
+	Msg *types.RuleMsg `json:"msg,omitempty"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+}
+
+// Replay 使用运行快照的输入消息重新执行规则链，并等待执行结束
+// 重放消息使用新的消息ID，元数据replayOf记录原运行快照ID，新的运行快照可以通过replayOf查询
+func (s *RuleEngineService) Replay(snapshotId string, opts ReplayOptions) (ReplayResult, error) {
+	var result = ReplayResult{ReplayOf: snapshotId, Version: opts.Version, NodeId: opts.NodeId}
+	snapshot, err := EventServiceImpl.Get(s.username, opts.ChainId, snapshotId)
+	if err != nil {
+		return result, err
+	}
+	chainId := opts.ChainId
+	if chainId == "" {
+		chainId = snapshot.RuleChain.RuleChain.ID
+	}
+	result.ChainId = chainId
+
+	var ruleEngine types.RuleEngine
+	if opts.Version > 0 {
+		revision, err := s.GetRevision(chainId, opts.Version)
+		if err != nil {
+			return result, err
+		}
+		var def types.RuleChain
+		if err := json.Unmarshal([]byte(revision.RuleConfig), &def); err != nil {
+			return result, err
+		}
+		pool, e, err := s.newPrivatePool(chainId, def, nil, s.ruleConfig)
+		if err != nil {
+			return result, err
+		}
+		defer pool.Stop()
+		ruleEngine = e
+	} else if e, ok := s.Pool.Get(chainId); ok {
+		ruleEngine = e
+	} else {
+		return result, constants.ErrNotFound
+	}
+
+	inMsg, ok := dao.SnapshotInMsg(snapshot, opts.NodeId)
+	if opts.NodeId != "" {
+		if !hasNode(ruleEngine.Definition(), opts.NodeId) {
+			return result, ErrReplayNodeNotFound
+		}
+		//原运行没有执行该节点，使用原运行的输入消息
+		if !ok {
+			inMsg, _ = dao.SnapshotInMsg(snapshot, "")
+		}
+	}
+	dataType := inMsg.DataType
+	if dataType == "" {
+		dataType = types.JSON
+	}
+	metadata := inMsg.Metadata.Copy()
+	metadata.PutValue(constants.KeyReplayOf, snapshotId)
+	msg := types.NewMsg(0, inMsg.Type, dataType, metadata, inMsg.Data)
+	result.MsgId = msg.Id
+
+	var lock sync.Mutex
+	var lastErr error
+	ruleEngine.OnMsgAndWait(msg, types.WithStartNode(opts.NodeId), types.WithOnEnd(func(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) {
+		lock.Lock()
+		defer lock.Unlock()
+		result.Msg = &msg
+		if err != nil {
+			lastErr = err
+		}
+	}), types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
+		id, _ := EventServiceImpl.saveRunLog(ctx, snapshot)
+		lock.Lock()
+		defer lock.Unlock()
+		result.SnapshotId = id
+	}))
+	lock.Lock()
+	defer lock.Unlock()
+	if lastErr != nil {
+		result.Err = lastErr.Error()
+	}
+	return result, nil
+}
+
+// newPrivatePool 创建不放入用户规则引擎池的私有规则引擎池，用于执行历史版本、测试和调试等场景，使用完需要调用Stop释放
+// 私有规则引擎池包含根规则链以及通过flow、ref节点和ruleChainConnections可以到达的所有子规则链，子规则链使用用户规则引擎池中的当前定义，
+// 子规则链也在私有规则引擎中执行，不会调用正在运行的规则链；transform不为空则用于修改每个规则链的定义，例如替换模拟节点
+func (s *RuleEngineService) newPrivatePool(chainId string, ruleChain types.RuleChain, transform func(chainId string, ruleChain types.RuleChain) types.RuleChain,
+	config types.Config, opts ...types.RuleEngineOption) (*engine.Pool, *engine.RuleEngine, error) {
+	var defs = map[string]types.RuleChain{chainId: ruleChain}
+	var ids = []string{chainId}
+	for i := 0; i < len(ids); i++ {
+		for _, subId := range subChainIds(defs[ids[i]]) {
+			if _, ok := defs[subId]; ok {
+				continue
+			}
+			//子规则链不存在则不创建，执行时返回规则链不存在
+			if e, ok := s.Pool.Get(subId); ok {
+				defs[subId] = e.Definition()
+				ids = append(ids, subId)
+			}
+		}
+	}
+	pool := engine.NewPool()
+	opts = append([]types.RuleEngineOption{rulego.WithConfig(config)}, opts...)
+	for _, id := range ids {
+		def := defs[id]
+		if transform != nil {
+			def = transform(id, def)
+		}
+		v, err := json.Marshal(def)
+		if err != nil {
+			pool.Stop()
+			return nil, nil, err
+		}
+		e, err := pool.New(id, v, opts...)
+		if err != nil {
+			pool.Stop()
+			return nil, nil, err
+		}
+		//运行快照按规则链所属用户保存
+		s.fillAdditionalInfo(e.(*engine.RuleEngine).RootRuleChainCtx().Definition())
+	}
+	root, _ := pool.Get(chainId)
+	return pool, root.(*engine.RuleEngine), nil
+}
+
+// subChainIds 规则链直接调用的子规则链ID，包括flow节点、指定规则链的ref节点和ruleChainConnections
+func subChainIds(ruleChain types.RuleChain) []string {
+	var ids []string
+	for _, node := range ruleChain.Metadata.Nodes {
+		if node == nil {
+			continue
+		}
+		targetId, _ := node.Configuration["targetId"].(string)
+		switch node.Type {
+		case "flow":
+			ids = append(ids, targetId)
+		case "ref":
+			if chainId, _, ok := strings.Cut(targetId, ":"); ok {
+				ids = append(ids, chainId)
+			}
+		}
+	}
+	for _, conn := range ruleChain.Metadata.RuleChainConnections {
+		ids = append(ids, conn.ToId)
+	}
+	return ids
+}
+
+// hasNode 规则链是否存在指定节点
+func hasNode(def types.RuleChain, nodeId string) bool {
+	for _, node := range def.Metadata.Nodes {
+		if node.Id == nodeId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"sync"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+var RetentionServiceImpl *RetentionService
+
+// KeyRetention 规则链configuration运行快照保留策略，例如：{"maxAge":"72h","maxCount":100,"maxBytes":10485760}
+const KeyRetention = "retention"
+
+// defaultRetentionInterval 默认清理任务执行间隔
+const defaultRetentionInterval = 10 * time.Minute
+
+// RetentionService 按保留策略定时清理运行快照和节点调试数据
+// 保留策略优先级：规则链configuration > 用户[retention.{username}] > 全局[retention]，0表示继承上一级，-1表示不限制
+type RetentionService struct {
+	config config.Config
+	// 同一时间只执行一个清理任务
+	lock sync.Mutex
+}
+
+func NewRetentionService(config config.Config) *RetentionService {
+	s := &RetentionService{config: config}
+	go s.run()
+	return s
+}
+
+// ParseRetention 从规则链configuration解析运行快照保留策略
+func ParseRetention(configuration types.Configuration) (model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+	if configuration == nil || configuration[KeyRetention] == nil {
+		return policy, nil
+	}
+	if v, err := json.Marshal(configuration[KeyRetention]); err != nil {
+		return policy, err
+	} else if err = json.Unmarshal(v, &policy); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// Policy 获取规则链生效的保留策略
+func (s *RetentionService) Policy(username, chainId string) model.RetentionPolicy {
+	policy := toRetentionPolicy(s.config.Retention)
+	if v, ok := s.config.UserRetention[username]; ok {
+		policy = policy.Merge(toRetentionPolicy(v))
+	}
+	if UserRuleEngineServiceImpl == nil {
+		return policy
+	}
+	if ruleEngineService, ok := UserRuleEngineServiceImpl.Load(username); ok {
+		if def, ok := ruleEngineService.Get(chainId); ok {
+			if v, err := ParseRetention(def.RuleChain.Configuration); err == nil {
+				policy = policy.Merge(v)
+			}
+		}
+	}
+	return policy
+}
+
+// Usage 按规则链统计运行快照存储占用，并返回生效的保留策略，只有管理员可以操作
+// username为空则统计所有用户
+func (s *RetentionService) Usage(operator, username string) ([]model.SnapshotUsage, error) {
+	if !UserServiceImpl.IsAdmin(operator) {
+		return nil, constants.ErrForbidden
+	}
+	usages, err := EventServiceImpl.Usage(username)
+	if err != nil {
+		return nil, err
+	}
+	for i := range usages {
+		policy := s.Policy(usages[i].Username, usages[i].ChainId)
+		usages[i].Retention = &policy
+	}
+	return usages, nil
+}
+
+// Purge 手动清理运行快照和节点调试数据，只有管理员可以操作
+// username或者chainId为空则清理所有用户或者所有规则链，policy为空则使用各规则链生效的保留策略
+func (s *RetentionService) Purge(operator, username, chainId string, policy *model.RetentionPolicy) (model.PurgeResult, error) {
+	if !UserServiceImpl.IsAdmin(operator) {
+		return model.PurgeResult{}, constants.ErrForbidden
+	}
+	return s.purge(username, chainId, policy)
+}
+
+func (s *RetentionService) purge(username, chainId string, policy *model.RetentionPolicy) (model.PurgeResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var result model.PurgeResult
+	var getPolicy = func(username, chainId string) model.RetentionPolicy {
+		if policy != nil {
+			return *policy
+		}
+		return s.Policy(username, chainId)
+	}
+	now := time.Now()
+	usages, err := EventServiceImpl.Usage(username)
+	if err != nil {
+		return result, err
+	}
+	for _, usage := range usages {
+		if chainId != "" && usage.ChainId != chainId {
+			continue
+		}
+		v, err := EventServiceImpl.Purge(usage.Username, usage.ChainId, getPolicy(usage.Username, usage.ChainId), now)
+		result.Add(v)
+		if err != nil {
+			return result, err
+		}
+	}
+	//节点调试数据使用相同的保留策略清理，内存存储每个节点的条数同时受max_node_log_size限制
+	if DebugServiceImpl == nil {
+		return result, nil
+	}
+	users, err := DebugServiceImpl.ChainIds(username)
+	if err != nil {
+		return result, err
+	}
+	for user, chainIds := range users {
+		for _, id := range chainIds {
+			if chainId != "" && id != chainId {
+				continue
+			}
+			n, err := DebugServiceImpl.Purge(user, id, getPolicy(user, id), now)
+			result.DebugDeleted += n
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// run 定时按各规则链生效的保留策略清理
+func (s *RetentionService) run() {
+	interval := s.config.Retention.Interval
+	if interval <= 0 {
+		interval = defaultRetentionInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if result, err := s.purge("", "", nil); err != nil {
+			logger.Logger.Printf("service/RetentionService:run error%s", err.Error())
+		} else if result.Deleted > 0 || result.DebugDeleted > 0 {
+			logger.Logger.Printf("service/RetentionService:run deleted=%d bytes=%d debugDeleted=%d", result.Deleted, result.Bytes, result.DebugDeleted)
+		}
+	}
+}
+
+func toRetentionPolicy(v config.Retention) model.RetentionPolicy {
+	return model.RetentionPolicy{
+		MaxAge:   v.MaxAge,
+		MaxCount: v.MaxCount,
+		MaxBytes: v.MaxBytes,
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/utils/diff"
+)
+
+// RevisionDiff 两个历史版本差异
+type RevisionDiff struct {
+	// From 旧版本号
+	From int `json:"from"`
+	// To 新版本号
+	To int `json:"to"`
+	// Changed 是否有差异
+	Changed bool `json:"changed"`
+	// Lines 逐行差异
+	Lines []diff.Line `json:"lines"`
+}
+
+// ListRevisions 获取规则链所有历史版本，按版本号降序，不返回DSL
+func (s *RuleEngineService) ListRevisions(chainId string) ([]model.RuleRevision, error) {
+	return dao.ListRuleRevision(s.username, chainId)
+}
+
+// GetRevision 获取规则链指定历史版本
+func (s *RuleEngineService) GetRevision(chainId string, version int) (*model.RuleRevision, error) {
+	r, err := dao.FindRuleRevision(s.username, chainId, version)
+	if err != nil {
+		return nil, err
+	}
+	if r.ID == 0 {
+		return nil, constants.ErrNotFound
+	}
+	return r, nil
+}
+
+// DiffRevisions 比较规则链两个历史版本DSL差异
+func (s *RuleEngineService) DiffRevisions(chainId string, from, to int) (RevisionDiff, error) {
+	var result = RevisionDiff{From: from, To: to}
+	fromRevision, err := s.GetRevision(chainId, from)
+	if err != nil {
+		return result, err
+	}
+	toRevision, err := s.GetRevision(chainId, to)
+	if err != nil {
+		return result, err
+	}
+	result.Lines = diff.Lines(fromRevision.RuleConfig, toRevision.RuleConfig)
+	result.Changed = diff.HasChange(result.Lines)
+	return result, nil
+}
+
+// Rollback 回滚规则链到指定历史版本
+// 使用该版本DSL重新加载规则链，并生成一个新的版本作为当前版本
+func (s *RuleEngineService) Rollback(chainId string, version int, author, message string) error {
+	revision, err := s.GetRevision(chainId, version)
+	if err != nil {
+		return err
+	}
+	def := []byte(revision.RuleConfig)
+	ruleEngine, ok := s.Pool.Get(chainId)
+	if ok {
+		err = ruleEngine.ReloadSelf(def)
+	} else {
+		ruleEngine, err = s.Pool.New(chainId, def, s.engineOptions()...)
+	}
+	if err != nil {
+		return err
+	}
+	//修改更新时间
+	s.fillAdditionalInfo(ruleEngine.RootRuleChainCtx().Definition())
+	if message == "" {
+		message = fmt.Sprintf("rollback to version %d", version)
+	}
+	return s.persist(chainId, ruleEngine, author, message)
+}
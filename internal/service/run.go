@@ -0,0 +1,244 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var RunServiceImpl *RunService
+
+// ErrCallbackUrl 回调地址不合法
+var ErrCallbackUrl = errors.New("callback must be a http or https url")
+
+// 默认异步执行参数
+const (
+	defaultAsyncWorkers   = 10
+	defaultAsyncQueueSize = 1000
+	defaultAsyncRunTtl    = time.Hour
+	// callbackTimeout 回调请求超时时间
+	callbackTimeout = 10 * time.Second
+)
+
+// RunService 异步执行规则链，提交后立即返回执行ID，通过执行ID查询执行状态和结果
+// 执行记录保存在内存，执行结束超过保留时间后清除
+type RunService struct {
+	config config.Config
+	queue  chan *runTask
+	runs   map[string]*model.Run
+	lock   sync.RWMutex
+	ttl    time.Duration
+	client *http.Client
+	//回调地址校验
+	guard *callbackGuard
+}
+
+// runTask 等待执行的任务
+type runTask struct {
+	runId      string
+	ruleEngine types.RuleEngine
+	msg        types.RuleMsg
+	//提交请求的span，规则链执行span以此为父span，不继承请求的取消
+	ctx context.Context
+}
+
+func NewRunService(config config.Config) *RunService {
+	workers := config.AsyncWorkers
+	if workers <= 0 {
+		workers = defaultAsyncWorkers
+	}
+	queueSize := config.AsyncQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultAsyncQueueSize
+	}
+	ttl := config.AsyncRunTtl
+	if ttl <= 0 {
+		ttl = defaultAsyncRunTtl
+	}
+	guard := newCallbackGuard(config.CallbackAllowPrivate, config.CallbackAllowHosts)
+	s := &RunService{
+		config: config,
+		queue:  make(chan *runTask, queueSize),
+		runs:   make(map[string]*model.Run),
+		ttl:    ttl,
+		client: guard.newClient(),
+		guard:  guard,
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	go s.cleanup()
+	return s
+}
+
+// Submit 提交异步执行，返回排队中的执行记录，ctx为提交请求的上下文，只用于链路追踪
+func (s *RunService) Submit(ctx context.Context, username, chainId string, msg types.RuleMsg, callbackUrl string) (model.Run, error) {
+	if callbackUrl != "" {
+		if err := s.guard.validate(context.Background(), callbackUrl); err != nil {
+			return model.Run{}, err
+		}
+	}
+	ruleEngine, ok := s.getRuleEngine(username, chainId)
+	if !ok {
+		return model.Run{}, constants.ErrNotFound
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return model.Run{}, err
+	}
+	run := &model.Run{
+		Id:          id.String(),
+		Username:    username,
+		ChainId:     chainId,
+		MsgId:       msg.Id,
+		Status:      model.RunStatusQueued,
+		CallbackUrl: callbackUrl,
+		CreateTime:  time.Now().UnixMilli(),
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	select {
+	case s.queue <- &runTask{runId: run.Id, ruleEngine: ruleEngine, msg: msg, ctx: trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))}:
+		s.runs[run.Id] = run
+		return *run, nil
+	default:
+		return model.Run{}, constants.ErrRunQueueFull
+	}
+}
+
+// Get 获取执行记录，只能获取自己提交的执行记录
+func (s *RunService) Get(username, id string) (model.Run, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if run, ok := s.runs[id]; ok && run.Username == username {
+		return *run, nil
+	}
+	return model.Run{}, constants.ErrNotFound
+}
+
+func (s *RunService) getRuleEngine(username, chainId string) (types.RuleEngine, bool) {
+	if UserRuleEngineServiceImpl == nil {
+		return nil, false
+	}
+	if ruleEngineService, ok := UserRuleEngineServiceImpl.Get(username); ok {
+		return ruleEngineService.Pool.Get(chainId)
+	}
+	return nil, false
+}
+
+// work 从队列获取任务并执行，每个worker同一时间只执行一个规则链
+func (s *RunService) work() {
+	for task := range s.queue {
+		s.execute(task)
+	}
+}
+
+func (s *RunService) execute(task *runTask) {
+	s.update(task.runId, func(run *model.Run) {
+		run.Status = model.RunStatusRunning
+		run.StartTime = time.Now().UnixMilli()
+	})
+	var lock sync.Mutex
+	var lastMsg *types.RuleMsg
+	var lastErr error
+	task.ruleEngine.OnMsgAndWait(task.msg, types.WithContext(task.ctx), types.WithOnEnd(func(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) {
+		lock.Lock()
+		defer lock.Unlock()
+		lastMsg = &msg
+		if err != nil {
+			lastErr = err
+		}
+	}), types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
+		if EventServiceImpl != nil {
+			_ = EventServiceImpl.SaveRunLog(ctx, snapshot)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		s.finish(task.runId, lastMsg, lastErr)
+	}))
+	//规则链为空或者上下文取消时只触发onEnd，规则引擎未初始化时不会触发任何回调
+	lock.Lock()
+	defer lock.Unlock()
+	if lastMsg == nil && lastErr == nil {
+		lastErr = errors.New("rule engine not initialized")
+	}
+	s.finish(task.runId, lastMsg, lastErr)
+}
+
+// finish 标记执行结束并触发回调，重复调用只生效第一次
+func (s *RunService) finish(runId string, msg *types.RuleMsg, err error) {
+	var done bool
+	var result model.Run
+	s.update(runId, func(run *model.Run) {
+		if run.IsDone() {
+			return
+		}
+		done = true
+		run.EndTime = time.Now().UnixMilli()
+		run.Msg = msg
+		if err != nil {
+			run.Status = model.RunStatusFailed
+			run.Err = err.Error()
+		} else {
+			run.Status = model.RunStatusSucceeded
+		}
+		result = *run
+	})
+	if done && result.CallbackUrl != "" {
+		go s.callback(result)
+	}
+}
+
+func (s *RunService) update(runId string, f func(run *model.Run)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if run, ok := s.runs[runId]; ok {
+		f(run)
+	}
+}
+
+// callback 把执行结果POST到回调地址
+func (s *RunService) callback(run model.Run) {
+	body, err := json.Marshal(run)
+	if err != nil {
+		logger.Logger.Printf("service/RunService:callback marshal error%s", err.Error())
+		return
+	}
+	resp, err := s.client.Post(run.CallbackUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Logger.Printf("service/RunService:callback runId=%s url=%s error%s", run.Id, run.CallbackUrl, err.Error())
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		logger.Logger.Printf("service/RunService:callback runId=%s url=%s status=%d", run.Id, run.CallbackUrl, resp.StatusCode)
+	}
+}
+
+// cleanup 定时清除超过保留时间的执行记录
+func (s *RunService) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		expired := time.Now().Add(-s.ttl).UnixMilli()
+		s.lock.Lock()
+		for id, run := range s.runs {
+			if run.IsDone() && run.EndTime < expired {
+				delete(s.runs, id)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrCallbackAddress 回调地址解析到内网、本机等不允许访问的地址
+var ErrCallbackAddress = errors.New("callback address is not allowed")
+
+// cgnatNet 运营商级NAT地址段，net.IP.IsPrivate不包含
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// callbackGuard 回调地址校验，防止通过回调访问内网服务
+// 白名单中的主机不校验解析后的地址，否则拒绝解析到本机、内网、链路本地等地址的主机
+type callbackGuard struct {
+	//是否允许回调内网地址
+	allowPrivate bool
+	//允许的主机，小写
+	allowHosts map[string]bool
+	dialer     *net.Dialer
+	resolver   *net.Resolver
+}
+
+func newCallbackGuard(allowPrivate bool, allowHosts string) *callbackGuard {
+	g := &callbackGuard{
+		allowPrivate: allowPrivate,
+		allowHosts:   make(map[string]bool),
+		dialer:       &net.Dialer{Timeout: callbackTimeout},
+		resolver:     net.DefaultResolver,
+	}
+	for _, host := range strings.Split(allowHosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			g.allowHosts[host] = true
+		}
+	}
+	return g
+}
+
+// newClient 创建回调http客户端，建立连接时校验解析后的地址，重定向和DNS重绑定也会被校验
+func (g *callbackGuard) newClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = g.dialContext
+	return &http.Client{Timeout: callbackTimeout, Transport: transport}
+}
+
+// validate 提交时校验回调地址，尽早返回错误
+func (g *callbackGuard) validate(ctx context.Context, callbackUrl string) error {
+	u, err := url.Parse(callbackUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrCallbackUrl
+	}
+	_, err = g.resolve(ctx, u.Hostname())
+	return err
+}
+
+func (g *callbackGuard) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := g.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// resolve 解析主机地址，不允许的地址返回ErrCallbackAddress
+func (g *callbackGuard) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, callbackTimeout)
+	defer cancel()
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address found for host: %s", host)
+	}
+	trusted := g.allowPrivate || g.allowHosts[strings.ToLower(host)]
+	var ips = make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if !trusted && isInternalIP(addr.IP) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrCallbackAddress, host, addr.IP)
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// isInternalIP 是否是本机、内网、链路本地、组播或者未指定地址
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatNet.Contains(ip)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/utils/diff"
+	"sort"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+// 节点差异状态
+const (
+	// NodeDiffSame 两次运行该节点关系、错误、输入输出消息都相同，不比较耗时
+	NodeDiffSame = "same"
+	// NodeDiffChanged 两次运行都执行了该节点，但是结果不同
+	NodeDiffChanged = "changed"
+	// NodeDiffOnlyFrom 只有旧运行执行了该节点
+	NodeDiffOnlyFrom = "onlyFrom"
+	// NodeDiffOnlyTo 只有新运行执行了该节点
+	NodeDiffOnlyTo = "onlyTo"
+)
+
+// RunDiff 两次运行差异
+type RunDiff struct {
+	// From 旧运行概要
+	From RunDiffSummary `json:"from"`
+	// To 新运行概要
+	To RunDiffSummary `json:"to"`
+	// Changed 是否有差异
+	Changed bool `json:"changed"`
+	// DivergedAt 按执行顺序第一个出现差异的节点ID
+	DivergedAt string `json:"divergedAt,omitempty"`
+	// Nodes 逐节点差异，按旧运行执行顺序排列，只有新运行执行的节点排在最后
+	Nodes []NodeDiff `json:"nodes"`
+}
+
+// RunDiffSummary 运行概要
+type RunDiffSummary struct {
+	// 运行快照ID
+	Id string `json:"id"`
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 状态 succeeded/failed
+	Status string `json:"status"`
+	// 开始时间，毫秒
+	StartTs int64 `json:"startTs"`
+	// 结束时间，毫秒
+	EndTs int64 `json:"endTs"`
+	// 耗时，毫秒
+	Duration int64 `json:"duration"`
+	// 按执行顺序排列的节点ID，节点执行多次则出现多次
+	NodeIds []string `json:"nodeIds"`
+}
+
+// NodeDiff 节点差异，同一个节点执行多次时按执行顺序分别比较
+type NodeDiff struct {
+	// 节点ID
+	NodeId string `json:"nodeId"`
+	// 该节点第几次执行，从0开始
+	Index int `json:"index"`
+	// 差异状态 same/changed/onlyFrom/onlyTo
+	Status string `json:"status"`
+	// 旧运行节点执行情况
+	From *NodeRunSummary `json:"from,omitempty"`
+	// 新运行节点执行情况
+	To *NodeRunSummary `json:"to,omitempty"`
+	// 耗时差，新运行减旧运行，毫秒
+	DurationDelta int64 `json:"durationDelta"`
+	// 关系类型是否不同
+	RelationChanged bool `json:"relationChanged"`
+	// 错误是否不同
+	ErrChanged bool `json:"errChanged"`
+	// 输入消息类型是否不同
+	InTypeChanged bool `json:"inTypeChanged"`
+	// 输入消息内容差异
+	InData []diff.Value `json:"inData,omitempty"`
+	// 输入消息元数据差异
+	InMetadata []diff.Value `json:"inMetadata,omitempty"`
+	// 输出消息类型是否不同
+	OutTypeChanged bool `json:"outTypeChanged"`
+	// 输出消息内容差异
+	OutData []diff.Value `json:"outData,omitempty"`
+	// 输出消息元数据差异
+	OutMetadata []diff.Value `json:"outMetadata,omitempty"`
+}
+
+// NodeRunSummary 节点执行情况
+type NodeRunSummary struct {
+	// 关系类型
+	RelationType string `json:"relationType"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+	// 开始时间，毫秒
+	StartTs int64 `json:"startTs"`
+	// 结束时间，毫秒
+	EndTs int64 `json:"endTs"`
+	// 耗时，毫秒
+	Duration int64 `json:"duration"`
+}
+
+// Diff 逐节点比较两次运行
+// 消息ID、时间戳等每次运行都不同的字段以及重放标记replayOf不参与比较
+func (s *EventService) Diff(username, chainId, fromId, toId string) (RunDiff, error) {
+	var result RunDiff
+	from, err := s.Get(username, chainId, fromId)
+	if err != nil {
+		return result, err
+	}
+	to, err := s.Get(username, chainId, toId)
+	if err != nil {
+		return result, err
+	}
+	fromLogs := sortRunLogs(from.Logs)
+	toLogs := sortRunLogs(to.Logs)
+	result.From = runDiffSummary(from, fromLogs)
+	result.To = runDiffSummary(to, toLogs)
+
+	type nodeKey struct {
+		nodeId string
+		index  int
+	}
+	var index = func(logs []types.RuleNodeRunLog) ([]nodeKey, map[nodeKey]types.RuleNodeRunLog) {
+		var keys []nodeKey
+		var byKey = make(map[nodeKey]types.RuleNodeRunLog)
+		var count = make(map[string]int)
+		for _, item := range logs {
+			key := nodeKey{nodeId: item.Id, index: count[item.Id]}
+			count[item.Id]++
+			keys = append(keys, key)
+			byKey[key] = item
+		}
+		return keys, byKey
+	}
+	fromKeys, fromByKey := index(fromLogs)
+	toKeys, toByKey := index(toLogs)
+	var keys = fromKeys
+	for _, key := range toKeys {
+		if _, ok := fromByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		fromLog, fromOk := fromByKey[key]
+		toLog, toOk := toByKey[key]
+		nodeDiff := NodeDiff{NodeId: key.nodeId, Index: key.index}
+		switch {
+		case !toOk:
+			nodeDiff.Status = NodeDiffOnlyFrom
+			nodeDiff.From = nodeRunSummary(fromLog)
+		case !fromOk:
+			nodeDiff.Status = NodeDiffOnlyTo
+			nodeDiff.To = nodeRunSummary(toLog)
+		default:
+			nodeDiff = diffNodeRunLog(nodeDiff, fromLog, toLog)
+		}
+		if nodeDiff.Status != NodeDiffSame {
+			result.Changed = true
+			if result.DivergedAt == "" {
+				result.DivergedAt = key.nodeId
+			}
+		}
+		result.Nodes = append(result.Nodes, nodeDiff)
+	}
+	return result, nil
+}
+
+// diffNodeRunLog 比较同一个节点的两次执行
+func diffNodeRunLog(nodeDiff NodeDiff, from, to types.RuleNodeRunLog) NodeDiff {
+	nodeDiff.From = nodeRunSummary(from)
+	nodeDiff.To = nodeRunSummary(to)
+	nodeDiff.DurationDelta = nodeDiff.To.Duration - nodeDiff.From.Duration
+	nodeDiff.RelationChanged = from.RelationType != to.RelationType
+	nodeDiff.ErrChanged = from.Err != to.Err
+	nodeDiff.InTypeChanged = from.InMsg.Type != to.InMsg.Type
+	nodeDiff.InData = diff.JSON(from.InMsg.Data, to.InMsg.Data)
+	nodeDiff.InMetadata = diff.Map(diffMetadata(from.InMsg.Metadata), diffMetadata(to.InMsg.Metadata))
+	nodeDiff.OutTypeChanged = from.OutMsg.Type != to.OutMsg.Type
+	nodeDiff.OutData = diff.JSON(from.OutMsg.Data, to.OutMsg.Data)
+	nodeDiff.OutMetadata = diff.Map(diffMetadata(from.OutMsg.Metadata), diffMetadata(to.OutMsg.Metadata))
+	if nodeDiff.RelationChanged || nodeDiff.ErrChanged || nodeDiff.InTypeChanged || nodeDiff.OutTypeChanged ||
+		len(nodeDiff.InData) > 0 || len(nodeDiff.InMetadata) > 0 || len(nodeDiff.OutData) > 0 || len(nodeDiff.OutMetadata) > 0 {
+		nodeDiff.Status = NodeDiffChanged
+	} else {
+		nodeDiff.Status = NodeDiffSame
+	}
+	return nodeDiff
+}
+
+// diffMetadata 去掉不参与比较的元数据
+func diffMetadata(metadata types.Metadata) map[string]string {
+	var result = make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if k != constants.KeyReplayOf {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// sortRunLogs 节点运行日志按开始时间升序排列
+func sortRunLogs(logs []types.RuleNodeRunLog) []types.RuleNodeRunLog {
+	var sorted = append([]types.RuleNodeRunLog(nil), logs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTs < sorted[j].StartTs
+	})
+	return sorted
+}
+
+func runDiffSummary(snapshot types.RuleChainRunSnapshot, logs []types.RuleNodeRunLog) RunDiffSummary {
+	var nodeIds = make([]string, 0, len(logs))
+	for _, item := range logs {
+		nodeIds = append(nodeIds, item.Id)
+	}
+	return RunDiffSummary{
+		Id:       snapshot.Id,
+		ChainId:  snapshot.RuleChain.RuleChain.ID,
+		Status:   dao.SnapshotStatus(snapshot),
+		StartTs:  snapshot.StartTs,
+		EndTs:    snapshot.EndTs,
+		Duration: snapshot.EndTs - snapshot.StartTs,
+		NodeIds:  nodeIds,
+	}
+}
+
+func nodeRunSummary(log types.RuleNodeRunLog) *NodeRunSummary {
+	return &NodeRunSummary{
+		RelationType: log.RelationType,
+		Err:          log.Err,
+		StartTs:      log.StartTs,
+		EndTs:        log.EndTs,
+		Duration:     log.EndTs - log.StartTs,
+	}
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+var ScheduleServiceImpl *ScheduleService
+
+const (
+	// KeySchedules 规则链configuration定时触发配置
+	KeySchedules = "schedules"
+	// KeyScheduleId 定时触发消息元数据中的定时任务ID
+	KeyScheduleId = "scheduleId"
+	// defaultScheduleMsgType 定时触发默认消息类型
+	defaultScheduleMsgType = "CRON"
+	// scheduleFiringKeepSize 每个规则链保留的触发记录数
+	scheduleFiringKeepSize = 200
+)
+
+// ErrScheduleIdDuplicate 定时任务ID重复
+var ErrScheduleIdDuplicate = errors.New("schedule id duplicate")
+
+// ErrScheduleIdEmpty 定时任务ID为空
+var ErrScheduleIdEmpty = errors.New("schedule id is empty")
+
+// cronParser cron表达式解析器，秒可选
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ScheduleService 规则链定时触发，规则链保存或删除时自动注册或注销定时任务
+// 同一个定时任务上一次触发未执行完，则跳过本次触发
+type ScheduleService struct {
+	config config.Config
+	cron   *cron.Cron
+	// 用户/规则链ID->定时任务
+	entries map[string][]scheduleEntry
+	lock    sync.RWMutex
+}
+
+// scheduleEntry 已注册的定时任务
+type scheduleEntry struct {
+	entryId  cron.EntryID
+	chainId  string
+	schedule model.Schedule
+	spec     cron.Schedule
+}
+
+func NewScheduleService(config config.Config) *ScheduleService {
+	s := &ScheduleService{
+		config:  config,
+		entries: make(map[string][]scheduleEntry),
+		cron: cron.New(cron.WithParser(cronParser),
+			cron.WithChain(cron.Recover(cron.PrintfLogger(logger.Logger)), cron.SkipIfStillRunning(cron.PrintfLogger(logger.Logger)))),
+	}
+	s.cron.Start()
+	return s
+}
+
+// ParseSchedules 从规则链configuration解析定时触发配置，并校验cron表达式
+func ParseSchedules(configuration types.Configuration) ([]model.Schedule, error) {
+	if configuration == nil || configuration[KeySchedules] == nil {
+		return nil, nil
+	}
+	var schedules []model.Schedule
+	if v, err := json.Marshal(configuration[KeySchedules]); err != nil {
+		return nil, err
+	} else if err = json.Unmarshal(v, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, ValidateSchedules(schedules)
+}
+
+// ValidateSchedules 校验定时任务ID是否为空、是否重复以及cron表达式是否合法
+func ValidateSchedules(schedules []model.Schedule) error {
+	ids := make(map[string]struct{})
+	for i, item := range schedules {
+		if item.Id == "" {
+			return fmt.Errorf("%w: index=%d", ErrScheduleIdEmpty, i)
+		}
+		if _, ok := ids[item.Id]; ok {
+			return fmt.Errorf("%w: %s", ErrScheduleIdDuplicate, item.Id)
+		}
+		ids[item.Id] = struct{}{}
+		if _, err := cronParser.Parse(item.Cron); err != nil {
+			return fmt.Errorf("schedule=%s cron=%s error: %w", item.Id, item.Cron, err)
+		}
+	}
+	return nil
+}
+
+// Register 根据规则链定义注册定时任务，已注册的定时任务会先注销
+func (s *ScheduleService) Register(username, chainId string, def types.RuleChain) error {
+	schedules, err := ParseSchedules(def.RuleChain.Configuration)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.unregister(username, chainId)
+	if err != nil {
+		return err
+	}
+	var entries []scheduleEntry
+	for _, item := range schedules {
+		if item.Disabled {
+			continue
+		}
+		spec, _ := cronParser.Parse(item.Cron)
+		schedule := item
+		entryId := s.cron.Schedule(spec, cron.FuncJob(func() {
+			s.fire(username, chainId, schedule)
+		}))
+		entries = append(entries, scheduleEntry{entryId: entryId, chainId: chainId, schedule: schedule, spec: spec})
+	}
+	if len(entries) > 0 {
+		s.entries[s.key(username, chainId)] = entries
+	}
+	return nil
+}
+
+// Unregister 注销规则链所有定时任务
+func (s *ScheduleService) Unregister(username, chainId string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.unregister(username, chainId)
+}
+
+func (s *ScheduleService) unregister(username, chainId string) {
+	key := s.key(username, chainId)
+	for _, entry := range s.entries[key] {
+		s.cron.Remove(entry.entryId)
+	}
+	delete(s.entries, key)
+}
+
+// Upcoming 查询即将触发的时间，按触发时间升序，chainId为空则查询用户所有规则链
+func (s *ScheduleService) Upcoming(username, chainId string, size int) []model.UpcomingFiring {
+	var entries []scheduleEntry
+	s.lock.RLock()
+	if chainId != "" {
+		entries = append(entries, s.entries[s.key(username, chainId)]...)
+	} else {
+		prefix := s.key(username, "")
+		for k, v := range s.entries {
+			if len(k) > len(prefix) && k[:len(prefix)] == prefix {
+				entries = append(entries, v...)
+			}
+		}
+	}
+	s.lock.RUnlock()
+
+	var list = make([]model.UpcomingFiring, 0)
+	now := time.Now()
+	for _, entry := range entries {
+		next := now
+		for i := 0; i < size; i++ {
+			next = entry.spec.Next(next)
+			if next.IsZero() {
+				break
+			}
+			list = append(list, model.UpcomingFiring{
+				ChainId:    entry.chainId,
+				ScheduleId: entry.schedule.Id,
+				Cron:       entry.schedule.Cron,
+				FireTime:   next,
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].FireTime.Before(list[j].FireTime)
+	})
+	if len(list) > size {
+		list = list[:size]
+	}
+	return list
+}
+
+// History 查询触发记录，按触发时间降序，chainId为空则查询用户所有规则链
+func (s *ScheduleService) History(username, chainId string, size int) ([]model.ScheduleFiring, error) {
+	return dao.ListScheduleFiring(username, chainId, size)
+}
+
+// DeleteByChainId 注销规则链定时任务，并删除触发记录
+func (s *ScheduleService) DeleteByChainId(username, chainId string) error {
+	s.Unregister(username, chainId)
+	return dao.DeleteScheduleFiringByRuleChainId(username, chainId)
+}
+
+// fire 使用合成消息触发规则链，并等待执行结束记录执行结果
+func (s *ScheduleService) fire(username, chainId string, schedule model.Schedule) {
+	msgType := schedule.MsgType
+	if msgType == "" {
+		msgType = defaultScheduleMsgType
+	}
+	data := schedule.Data
+	if data == "" {
+		data = "{}"
+	}
+	metadata := types.BuildMetadata(schedule.Metadata)
+	metadata.PutValue(constants.KeyUsername, username)
+	metadata.PutValue(constants.KeyChainId, chainId)
+	metadata.PutValue(KeyScheduleId, schedule.Id)
+	var paths = []string{s.config.DataDir, constants.DirWorkflows, username, constants.DirWorkflowsRule}
+	metadata.PutValue(constants.KeyWorkDir, path.Join(paths...))
+	msg := types.NewMsg(0, msgType, types.JSON, metadata, data)
+
+	firing := &model.ScheduleFiring{
+		Owner:       username,
+		RuleChainId: chainId,
+		ScheduleId:  schedule.Id,
+		Cron:        schedule.Cron,
+		MsgId:       msg.Id,
+		Status:      model.RunStatusRunning,
+		FireTime:    time.Now(),
+	}
+	if err := dao.CreateScheduleFiring(firing); err != nil {
+		logger.Logger.Printf("service/ScheduleService:fire save firing error%s", err.Error())
+	} else if err := dao.TrimScheduleFiring(username, chainId, scheduleFiringKeepSize); err != nil {
+		logger.Logger.Printf("service/ScheduleService:fire trim firing error%s", err.Error())
+	}
+
+	var lock sync.Mutex
+	var lastErr error
+	if ruleEngine, ok := s.getRuleEngine(username, chainId); !ok {
+		lastErr = constants.ErrNotFound
+	} else {
+		ruleEngine.OnMsgAndWait(msg, types.WithOnEnd(func(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) {
+			if err != nil {
+				lock.Lock()
+				lastErr = err
+				lock.Unlock()
+			}
+		}), types.WithOnRuleChainCompleted(func(ctx types.RuleContext, snapshot types.RuleChainRunSnapshot) {
+			if EventServiceImpl != nil {
+				_ = EventServiceImpl.SaveRunLog(ctx, snapshot)
+			}
+		}))
+	}
+
+	status := model.RunStatusSucceeded
+	var errStr string
+	if lastErr != nil {
+		status = model.RunStatusFailed
+		errStr = lastErr.Error()
+	}
+	if firing.ID != 0 {
+		if err := dao.UpdateScheduleFiringResult(firing.ID, status, errStr, time.Now()); err != nil {
+			logger.Logger.Printf("service/ScheduleService:fire update firing error%s", err.Error())
+		}
+	}
+}
+
+func (s *ScheduleService) getRuleEngine(username, chainId string) (types.RuleEngine, bool) {
+	if UserRuleEngineServiceImpl == nil {
+		return nil, false
+	}
+	if ruleEngineService, ok := UserRuleEngineServiceImpl.Get(username); ok {
+		return ruleEngineService.Pool.Get(chainId)
+	}
+	return nil, false
+}
+
+func (s *ScheduleService) key(username, chainId string) string {
+	return username + "/" + chainId
+}
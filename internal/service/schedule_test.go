@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"ruleGoProject/internal/model"
+	"testing"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+func TestValidateSchedules(t *testing.T) {
+	tests := []struct {
+		name      string
+		schedules []model.Schedule
+		wantErr   error
+		wantAny   bool
+	}{
+		{name: "empty", schedules: nil},
+		{name: "valid", schedules: []model.Schedule{{Id: "s1", Cron: "0 0 2 * * *"}, {Id: "s2", Cron: "@every 1h"}}},
+		{name: "empty id", schedules: []model.Schedule{{Id: "", Cron: "@daily"}}, wantErr: ErrScheduleIdEmpty},
+		{name: "empty id after valid", schedules: []model.Schedule{{Id: "s1", Cron: "@daily"}, {Cron: "@daily"}}, wantErr: ErrScheduleIdEmpty},
+		{name: "duplicate id", schedules: []model.Schedule{{Id: "s1", Cron: "@daily"}, {Id: "s1", Cron: "@hourly"}}, wantErr: ErrScheduleIdDuplicate},
+		{name: "invalid cron", schedules: []model.Schedule{{Id: "s1", Cron: "not a cron"}}, wantAny: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchedules(tt.schedules)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ValidateSchedules() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAny:
+				if err == nil {
+					t.Error("ValidateSchedules() error = nil, want error")
+				}
+			case err != nil:
+				t.Errorf("ValidateSchedules() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSchedulesEmptyId(t *testing.T) {
+	configuration := types.Configuration{KeySchedules: []interface{}{
+		map[string]interface{}{"cron": "@daily"},
+	}}
+	if _, err := ParseSchedules(configuration); !errors.Is(err, ErrScheduleIdEmpty) {
+		t.Errorf("ParseSchedules() error = %v, want %v", err, ErrScheduleIdEmpty)
+	}
+}
@@ -1,14 +1,33 @@
 package service
 
 import (
+	"path/filepath"
 	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/dao"
 	"ruleGoProject/internal/model"
+
+	"github.com/rulego/rulego/utils/fs"
 )
 
 func Setup(config config.Config) error {
+	//记录启动时间，并提供就绪检查
+	StatusServiceImpl = NewStatusService()
 
-	if err := model.StartDB(); err != nil {
+	if err := StartDB(config); err != nil {
+		return err
+	}
+	//历史规则链没有所属用户，迁移到默认用户
+	if n, err := dao.MigrateRegulationOwner(config.DefaultUsername); err != nil {
 		return err
+	} else if n > 0 {
+		logger.Logger.Printf("migrate %d regulations to owner=%s", n, config.DefaultUsername)
+	}
+	//历史运行快照没有记录字节数
+	if n, err := dao.MigrateSnapshotSize(); err != nil {
+		return err
+	} else if n > 0 {
+		logger.Logger.Printf("migrate %d run snapshots size", n)
 	}
 	if s, err := NewUserService(config); err != nil {
 		return err
@@ -16,6 +35,28 @@ func Setup(config config.Config) error {
 		UserServiceImpl = s
 	}
 
+	//规则链加载后就可能产生指标数据，需要先初始化
+	if config.Metrics {
+		MetricsImpl = NewMetrics(config)
+	}
+
+	//规则链加载时使用链路追踪切面，需要先初始化
+	if t, err := NewTracing(config); err != nil {
+		return err
+	} else {
+		TracingImpl = t
+	}
+
+	//规则链加载后就可能产生调试数据，需要先初始化
+	if s, err := NewDebugService(config); err != nil {
+		return err
+	} else {
+		DebugServiceImpl = s
+	}
+
+	//规则链加载时注册定时任务，需要先初始化
+	ScheduleServiceImpl = NewScheduleService(config)
+
 	if s, err := NewUserRuleEngineServiceImpl(config); err != nil {
 		return err
 	} else {
@@ -28,5 +69,51 @@ func Setup(config config.Config) error {
 		EventServiceImpl = s
 	}
 
+	if s, err := NewDedupeService(config); err != nil {
+		return err
+	} else {
+		DedupeServiceImpl = s
+	}
+
+	RunServiceImpl = NewRunService(config)
+
+	RetentionServiceImpl = NewRetentionService(config)
+
 	return nil
 }
+
+// StartDB 连接数据库，并自动创建表结构
+func StartDB(config config.Config) error {
+	return model.StartDB(newORMConfig(config))
+}
+
+// newORMConfig 把数据库配置转换成orm配置
+func newORMConfig(config config.Config) *model.ORMConfig {
+	c := config.Database
+	dbName := c.DBName
+	if c.Driver == model.DriverSqlite {
+		//sqlite数据库文件默认存放在数据目录
+		if dbName == "" {
+			dbName = "rulego.db"
+		}
+		if !filepath.IsAbs(dbName) {
+			dbName = filepath.Join(config.DataDir, dbName)
+		}
+		_ = fs.CreateDirs(filepath.Dir(dbName))
+	}
+	return &model.ORMConfig{
+		Driver:          c.Driver,
+		User:            c.User,
+		Password:        c.Password,
+		Host:            c.Host,
+		Port:            c.Port,
+		DBname:          dbName,
+		SSlMode:         c.SSLMode,
+		TimeZone:        c.TimeZone,
+		MaxIdleConns:    c.MaxIdleConns,
+		MaxOpenConns:    c.MaxOpenConns,
+		ConnMaxLifeTime: c.ConnMaxLifeTime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+		LogMode:         c.LogMode,
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var StatusServiceImpl *StatusService
+
+// 就绪检查项
+const (
+	CheckDB    = "db"
+	CheckPools = "pools"
+	CheckMqtt  = "mqtt"
+)
+
+// ReadyCheck 就绪检查，返回nil表示通过
+type ReadyCheck func(ctx context.Context) error
+
+// StatusService 服务运行状态和就绪检查
+type StatusService struct {
+	startTime time.Time
+	//就绪检查项，按添加顺序执行
+	checks []namedCheck
+	locker sync.RWMutex
+}
+
+type namedCheck struct {
+	name  string
+	check ReadyCheck
+}
+
+func NewStatusService() *StatusService {
+	s := &StatusService{
+		startTime: time.Now(),
+	}
+	s.AddReadyCheck(CheckDB, checkDB)
+	s.AddReadyCheck(CheckPools, checkPools)
+	return s
+}
+
+// AddReadyCheck 添加就绪检查项，例如：开启mqtt时检查mqtt是否已连接
+func (s *StatusService) AddReadyCheck(name string, check ReadyCheck) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Ready 执行所有就绪检查
+func (s *StatusService) Ready(ctx context.Context) model.Readiness {
+	s.locker.RLock()
+	checks := append([]namedCheck(nil), s.checks...)
+	s.locker.RUnlock()
+	readiness := model.Readiness{Ready: true, Checks: make([]model.CheckResult, 0, len(checks))}
+	for _, item := range checks {
+		result := model.CheckResult{Name: item.name, Ok: true}
+		if err := item.check(ctx); err != nil {
+			result.Ok = false
+			result.Err = err.Error()
+			readiness.Ready = false
+		}
+		readiness.Checks = append(readiness.Checks, result)
+	}
+	return readiness
+}
+
+// Status 服务运行状态，管理员返回所有用户的规则引擎池状态，否则只返回当前用户
+// operator为空表示匿名访问，不具有管理员权限
+func (s *StatusService) Status(ctx context.Context, username, operator string) model.Status {
+	uptime := time.Since(s.startTime)
+	status := model.Status{
+		Version:       constants.Version,
+		StartTime:     s.startTime.UnixMilli(),
+		Uptime:        uptime.Truncate(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Readiness:     s.Ready(ctx),
+		Users:         make([]model.UserStatus, 0),
+	}
+	if UserRuleEngineServiceImpl != nil {
+		initErrors := UserRuleEngineServiceImpl.InitErrors()
+		var users []string
+		if UserServiceImpl != nil && UserServiceImpl.IsAdmin(operator) {
+			users = UserRuleEngineServiceImpl.Users()
+			for username := range initErrors {
+				users = append(users, username)
+			}
+		} else {
+			users = []string{username}
+		}
+		for _, username := range users {
+			if v, ok := UserRuleEngineServiceImpl.Load(username); ok {
+				status.Users = append(status.Users, v.LoadStatus())
+			} else if err, ok := initErrors[username]; ok {
+				status.Users = append(status.Users, model.UserStatus{Username: username, Err: err})
+			}
+		}
+		sort.Slice(status.Users, func(i, j int) bool {
+			return status.Users[i].Username < status.Users[j].Username
+		})
+	}
+	if model.DBClient != nil {
+		status.DB = model.NewDBStatus(model.DBClient.Driver(), model.DBClient.GetHealthStatus(ctx), model.DBClient.GetState())
+	}
+	return status
+}
+
+// checkDB 数据库是否可以访问
+func checkDB(ctx context.Context) error {
+	if model.DBClient == nil {
+		return errors.New("database not connected")
+	}
+	if health := model.DBClient.GetHealthStatus(ctx); health != model.DBHealth {
+		return fmt.Errorf("database is %s", health)
+	}
+	return nil
+}
+
+// checkPools 拥有规则链的用户规则引擎池是否都已初始化
+func checkPools(ctx context.Context) error {
+	if UserRuleEngineServiceImpl == nil {
+		return errors.New("user rule engine pools not initialized")
+	}
+	owners, err := UserRuleEngineServiceImpl.Owners()
+	if err != nil {
+		return err
+	}
+	initErrors := UserRuleEngineServiceImpl.InitErrors()
+	var failed []string
+	for username := range initErrors {
+		failed = append(failed, username)
+	}
+	for _, owner := range owners {
+		if _, ok := UserRuleEngineServiceImpl.Load(owner); !ok && owner != "" {
+			if _, ok := initErrors[owner]; !ok {
+				failed = append(failed, owner)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("user rule engine pools not initialized: %s", strings.Join(failed, ","))
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/utils/jsonpath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+const (
+	// defaultTestMsgType 测试用例默认输入消息类型
+	defaultTestMsgType = "TEST"
+	// defaultTestTimeout 测试用例默认超时时间
+	defaultTestTimeout = 10 * time.Second
+)
+
+var (
+	// ErrTestCaseInvalid 测试用例配置不合法
+	ErrTestCaseInvalid = errors.New("invalid test case")
+	// ErrTestCaseNameDuplicate 测试用例名称重复
+	ErrTestCaseNameDuplicate = errors.New("test case name duplicate")
+)
+
+// ValidateTestCases 校验测试用例，名称不能为空且不能重复，正则表达式和jsonPath必须合法
+func ValidateTestCases(cases []model.RuleTestCase) error {
+	names := make(map[string]struct{})
+	for _, item := range cases {
+		if strings.TrimSpace(item.Name) == "" {
+			return fmt.Errorf("%w: name is required", ErrTestCaseInvalid)
+		}
+		if _, ok := names[item.Name]; ok {
+			return fmt.Errorf("%w: %s", ErrTestCaseNameDuplicate, item.Name)
+		}
+		names[item.Name] = struct{}{}
+		if item.Timeout < 0 {
+			return fmt.Errorf("%w: case=%s timeout must not be negative", ErrTestCaseInvalid, item.Name)
+		}
+		if err := ValidateMocks(item.Mocks); err != nil {
+			return fmt.Errorf("%w: case=%s %s", ErrTestCaseInvalid, item.Name, err.Error())
+		}
+		if item.Expect.Err != "" {
+			if _, err := regexp.Compile(item.Expect.Err); err != nil {
+				return fmt.Errorf("%w: case=%s expect.err: %s", ErrTestCaseInvalid, item.Name, err.Error())
+			}
+		}
+		for i, m := range item.Expect.Data {
+			if err := validateMatcher(m, true); err != nil {
+				return fmt.Errorf("%w: case=%s expect.data[%d]: %s", ErrTestCaseInvalid, item.Name, i, err.Error())
+			}
+		}
+		for k, m := range item.Expect.Metadata {
+			if err := validateMatcher(m, false); err != nil {
+				return fmt.Errorf("%w: case=%s expect.metadata.%s: %s", ErrTestCaseInvalid, item.Name, k, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func validateMatcher(m model.Matcher, allowJsonPath bool) error {
+	switch m.Mode {
+	case "", model.MatchExact:
+		return nil
+	case model.MatchJsonPath:
+		if !allowJsonPath {
+			return fmt.Errorf("mode %s is not supported", m.Mode)
+		}
+		_, err := jsonpath.Compile(m.Path)
+		return err
+	case model.MatchRegex:
+		pattern, ok := m.Value.(string)
+		if !ok {
+			return errors.New("regex value must be a string")
+		}
+		_, err := regexp.Compile(pattern)
+		return err
+	default:
+		return fmt.Errorf("unknown mode %s", m.Mode)
+	}
+}
+
+// ListTests 获取规则链所有测试用例
+func (s *RuleEngineService) ListTests(chainId string) ([]model.RuleTestCase, error) {
+	return dao.ListRuleTestCase(s.username, chainId)
+}
+
+// SaveTests 保存规则链测试用例，覆盖原有用例
+func (s *RuleEngineService) SaveTests(chainId string, cases []model.RuleTestCase) error {
+	if _, ok := s.Pool.Get(chainId); !ok {
+		return constants.ErrNotFound
+	}
+	if err := ValidateTestCases(cases); err != nil {
+		return err
+	}
+	return dao.SaveRuleTestCases(s.username, chainId, cases)
+}
+
+// RunTests 使用规则链DSL创建私有规则引擎执行测试用例，返回测试报告
+// def为空则使用规则链当前DSL，names为空则执行所有用例
+// 执行测试用例不影响正在运行的规则链，不保存运行快照，也不记录节点调试数据
+func (s *RuleEngineService) RunTests(chainId string, def []byte, names ...string) (model.TestReport, error) {
+	var report = model.TestReport{ChainId: chainId, Results: []model.TestResult{}}
+	if len(def) == 0 {
+		dsl, err := s.GetDsl(chainId, "")
+		if err != nil {
+			return report, err
+		}
+		def = dsl
+	}
+	var ruleChain types.RuleChain
+	if err := json.Unmarshal(def, &ruleChain); err != nil {
+		return report, err
+	}
+	cases, err := s.ListTests(chainId)
+	if err != nil {
+		return report, err
+	}
+	var filter = make(map[string]struct{})
+	for _, name := range names {
+		filter[name] = struct{}{}
+	}
+	start := time.Now()
+	for _, item := range cases {
+		if _, ok := filter[item.Name]; len(filter) > 0 && !ok {
+			continue
+		}
+		result := s.runTestCase(chainId, ruleChain, item)
+		report.Total++
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(start).Milliseconds()
+	return report, nil
+}
+
+// runTestCase 执行单个测试用例，每个用例使用独立的私有规则引擎
+func (s *RuleEngineService) runTestCase(chainId string, ruleChain types.RuleChain, tc model.RuleTestCase) (result model.TestResult) {
+	result = model.TestResult{Name: tc.Name, Path: []string{}}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start).Milliseconds()
+	}()
+	var mocked = make(map[string]map[string]bool)
+	config := s.mockConfig(mocked)
+	//不记录节点调试数据
+	config.OnDebug = nil
+	pool, ruleEngine, err := s.newPrivatePool(chainId, ruleChain, mockTransform(chainId, tc.Mocks, false, mocked), config)
+	if err != nil {
+		result.Err = err.Error()
+		result.Failures = append(result.Failures, "create rule engine error: "+err.Error())
+		return result
+	}
+	defer pool.Stop()
+
+	msgType := tc.MsgType
+	if msgType == "" {
+		msgType = defaultTestMsgType
+	}
+	dataType := types.TEXT
+	var v interface{}
+	if json.Unmarshal([]byte(tc.Data), &v) == nil {
+		dataType = types.JSON
+	}
+	metadata := types.NewMetadata()
+	for k, v := range tc.Metadata {
+		metadata.PutValue(k, v)
+	}
+	msg := types.NewMsg(0, msgType, dataType, metadata, tc.Data)
+
+	var lock sync.Mutex
+	var outMsg *types.RuleMsg
+	var lastErr error
+	var path []string
+	done := make(chan struct{})
+	ruleEngine.OnMsg(msg, types.WithOnEnd(func(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) {
+		lock.Lock()
+		defer lock.Unlock()
+		outMsg = &msg
+		if err != nil {
+			lastErr = err
+		}
+	}), types.WithOnNodeCompleted(func(ctx types.RuleContext, nodeRunLog types.RuleNodeRunLog) {
+		lock.Lock()
+		defer lock.Unlock()
+		path = append(path, nodeRunLog.Id)
+	}), types.WithOnAllNodeCompleted(func() {
+		close(done)
+	}))
+
+	timeout := defaultTestTimeout
+	if tc.Timeout > 0 {
+		timeout = time.Duration(tc.Timeout) * time.Millisecond
+	}
+	var timedOut bool
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		timedOut = true
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	result.Path = append(result.Path, path...)
+	if outMsg != nil {
+		out := outMsg.Copy()
+		result.Msg = &out
+	}
+	if lastErr != nil {
+		result.Err = lastErr.Error()
+	}
+	if timedOut {
+		result.Failures = append(result.Failures, fmt.Sprintf("timeout after %s", timeout))
+	}
+	result.Failures = append(result.Failures, checkExpect(tc.Expect, result.Msg, result.Err, result.Path)...)
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// checkExpect 检查执行结果是否满足期望，返回不满足的断言
+func checkExpect(expect model.TestExpect, msg *types.RuleMsg, errStr string, path []string) []string {
+	var failures []string
+	if expect.Err == "" {
+		if errStr != "" {
+			failures = append(failures, fmt.Sprintf("err: unexpected error %q", errStr))
+		}
+	} else if errStr == "" {
+		failures = append(failures, fmt.Sprintf("err: expected error matching %q, got none", expect.Err))
+	} else if ok, _ := regexp.MatchString(expect.Err, errStr); !ok {
+		failures = append(failures, fmt.Sprintf("err: %q does not match %q", errStr, expect.Err))
+	}
+	if len(expect.Path) > 0 && !reflect.DeepEqual(expect.Path, path) {
+		failures = append(failures, fmt.Sprintf("path: expected %v, got %v", expect.Path, path))
+	}
+	if expect.MsgType == "" && len(expect.Data) == 0 && len(expect.Metadata) == 0 {
+		return failures
+	}
+	if msg == nil {
+		return append(failures, "msg: no output message")
+	}
+	if expect.MsgType != "" && expect.MsgType != msg.Type {
+		failures = append(failures, fmt.Sprintf("msgType: expected %q, got %q", expect.MsgType, msg.Type))
+	}
+	for i, m := range expect.Data {
+		if err := matchData(m, msg.Data); err != nil {
+			failures = append(failures, fmt.Sprintf("data[%d]: %s", i, err.Error()))
+		}
+	}
+	var keys []string
+	for k := range expect.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := msg.Metadata[k]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("metadata.%s: missing", k))
+		} else if err := matchText(expect.Metadata[k], v); err != nil {
+			failures = append(failures, fmt.Sprintf("metadata.%s: %s", k, err.Error()))
+		}
+	}
+	return failures
+}
+
+// matchData 匹配消息内容，json消息按字段比较
+func matchData(m model.Matcher, data string) error {
+	switch m.Mode {
+	case model.MatchJsonPath:
+		var v interface{}
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return fmt.Errorf("data is not json: %s", err.Error())
+		}
+		p, err := jsonpath.Compile(m.Path)
+		if err != nil {
+			return err
+		}
+		actual, ok := p.Get(v)
+		if !ok {
+			return fmt.Errorf("%s not found", m.Path)
+		}
+		if !jsonEqual(m.Value, actual) {
+			return fmt.Errorf("%s expected %s, got %s", m.Path, toText(m.Value), toText(actual))
+		}
+		return nil
+	case model.MatchRegex:
+		return matchText(m, data)
+	default:
+		if expected, ok := m.Value.(string); ok {
+			if expected == data {
+				return nil
+			}
+			var a, b interface{}
+			if json.Unmarshal([]byte(expected), &a) == nil && json.Unmarshal([]byte(data), &b) == nil && reflect.DeepEqual(a, b) {
+				return nil
+			}
+			return fmt.Errorf("expected %q, got %q", expected, data)
+		}
+		var actual interface{}
+		if err := json.Unmarshal([]byte(data), &actual); err != nil || !jsonEqual(m.Value, actual) {
+			return fmt.Errorf("expected %s, got %q", toText(m.Value), data)
+		}
+		return nil
+	}
+}
+
+// matchText 按文本匹配，支持exact和regex
+func matchText(m model.Matcher, text string) error {
+	if m.Mode == model.MatchRegex {
+		pattern, _ := m.Value.(string)
+		if ok, err := regexp.MatchString(pattern, text); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("%q does not match %q", text, pattern)
+		}
+		return nil
+	}
+	if expected := toText(m.Value); expected != text {
+		return fmt.Errorf("expected %q, got %q", expected, text)
+	}
+	return nil
+}
+
+// jsonEqual 比较两个json值，统一数值类型后比较
+func jsonEqual(a, b interface{}) bool {
+	var normalize = func(v interface{}) interface{} {
+		var result interface{}
+		if b, err := json.Marshal(v); err != nil || json.Unmarshal(b, &result) != nil {
+			return v
+		}
+		return result
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// toText 期望值转换成文本，字符串保持不变，其他类型转换成json
+func toText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
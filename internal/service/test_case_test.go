@@ -0,0 +1,115 @@
+package service
+
+import (
+	"reflect"
+	"ruleGoProject/internal/model"
+	"testing"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+func TestCheckExpect(t *testing.T) {
+	msg := &types.RuleMsg{
+		Type:     "TELEMETRY",
+		Data:     `{"temperature":41,"items":[{"name":"a"}],"ok":true}`,
+		Metadata: types.Metadata{"deviceId": "dev01", "level": "high"},
+	}
+	path := []string{"n1", "n2"}
+	tests := []struct {
+		name   string
+		expect model.TestExpect
+		msg    *types.RuleMsg
+		err    string
+		want   []string
+	}{
+		{name: "empty expect", expect: model.TestExpect{}, msg: msg, want: nil},
+		{name: "unexpected error", expect: model.TestExpect{}, msg: msg, err: "boom", want: []string{`err: unexpected error "boom"`}},
+		{name: "error matched", expect: model.TestExpect{Err: "^bo+m$"}, err: "boom", want: nil},
+		{name: "error not matched", expect: model.TestExpect{Err: "timeout"}, err: "boom", want: []string{`err: "boom" does not match "timeout"`}},
+		{name: "expected error missing", expect: model.TestExpect{Err: "boom"}, msg: msg, want: []string{`err: expected error matching "boom", got none`}},
+		{name: "path matched", expect: model.TestExpect{Path: []string{"n1", "n2"}}, msg: msg, want: nil},
+		{name: "path not matched", expect: model.TestExpect{Path: []string{"n1"}}, msg: msg, want: []string{"path: expected [n1], got [n1 n2]"}},
+		{name: "no output message", expect: model.TestExpect{MsgType: "TELEMETRY"}, want: []string{"msg: no output message"}},
+		{name: "msg type not matched", expect: model.TestExpect{MsgType: "ALARM"}, msg: msg, want: []string{`msgType: expected "ALARM", got "TELEMETRY"`}},
+		{
+			name:   "exact data ignores json formatting",
+			expect: model.TestExpect{Data: []model.Matcher{{Value: `{"ok":true, "items":[{"name":"a"}], "temperature":41}`}}},
+			msg:    msg,
+			want:   nil,
+		},
+		{
+			name:   "exact data with json value",
+			expect: model.TestExpect{Data: []model.Matcher{{Value: map[string]interface{}{"temperature": 41, "items": []interface{}{map[string]interface{}{"name": "a"}}, "ok": true}}}},
+			msg:    msg,
+			want:   nil,
+		},
+		{
+			name:   "exact data not matched",
+			expect: model.TestExpect{Data: []model.Matcher{{Mode: model.MatchExact, Value: `{"temperature":40}`}}},
+			msg:    msg,
+			want:   []string{`data[0]: expected "{\"temperature\":40}", got "{\"temperature\":41,\"items\":[{\"name\":\"a\"}],\"ok\":true}"`},
+		},
+		{
+			name: "json path matched",
+			expect: model.TestExpect{Data: []model.Matcher{
+				{Mode: model.MatchJsonPath, Path: "$.temperature", Value: 41},
+				{Mode: model.MatchJsonPath, Path: "$.items[0].name", Value: "a"},
+				{Mode: model.MatchJsonPath, Path: "$.items[0]", Value: map[string]interface{}{"name": "a"}},
+			}},
+			msg:  msg,
+			want: nil,
+		},
+		{
+			name: "json path not matched",
+			expect: model.TestExpect{Data: []model.Matcher{
+				{Mode: model.MatchJsonPath, Path: "$.temperature", Value: 40},
+				{Mode: model.MatchJsonPath, Path: "$.humidity", Value: 1},
+				{Mode: model.MatchJsonPath, Path: "temperature", Value: 41},
+			}},
+			msg: msg,
+			want: []string{
+				"data[0]: $.temperature expected 40, got 41",
+				"data[1]: $.humidity not found",
+				"data[2]: invalid json path: temperature must start with $",
+			},
+		},
+		{
+			name:   "json path on non json data",
+			expect: model.TestExpect{Data: []model.Matcher{{Mode: model.MatchJsonPath, Path: "$.a", Value: 1}}},
+			msg:    &types.RuleMsg{Data: "plain"},
+			want:   []string{"data[0]: data is not json: invalid character 'p' looking for beginning of value"},
+		},
+		{
+			name:   "regex data",
+			expect: model.TestExpect{Data: []model.Matcher{{Mode: model.MatchRegex, Value: `"temperature":4\d`}, {Mode: model.MatchRegex, Value: `"humidity"`}}},
+			msg:    msg,
+			want:   []string{`data[1]: "{\"temperature\":41,\"items\":[{\"name\":\"a\"}],\"ok\":true}" does not match "\"humidity\""`},
+		},
+		{
+			name: "metadata sorted by key",
+			expect: model.TestExpect{Metadata: map[string]model.Matcher{
+				"level":    {Mode: model.MatchRegex, Value: "^(high|low)$"},
+				"deviceId": {Value: "dev02"},
+				"area":     {Value: "a1"},
+			}},
+			msg: msg,
+			want: []string{
+				"metadata.area: missing",
+				`metadata.deviceId: expected "dev02", got "dev01"`,
+			},
+		},
+		{
+			name:   "metadata non string value",
+			expect: model.TestExpect{Metadata: map[string]model.Matcher{"count": {Value: 3}}},
+			msg:    &types.RuleMsg{Metadata: types.Metadata{"count": "3"}},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkExpect(tt.expect, tt.msg, tt.err, path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkExpect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/utils/diff"
+	"sort"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+const (
+	// flowNodeType 子规则链节点类型
+	flowNodeType = "flow"
+	// flowTargetId 子规则链节点配置中的目标规则链ID
+	flowTargetId = "targetId"
+)
+
+// Trace 消息执行轨迹，根据节点调试数据还原消息经过的所有节点
+// 只有开启调试模式的节点才会记录调试数据，未开启的节点不会出现在轨迹中
+type Trace struct {
+	// 消息ID
+	MsgId string `json:"msgId"`
+	// 异步执行ID，通过执行ID查询时返回
+	RunId string `json:"runId,omitempty"`
+	// 入口规则链ID
+	ChainId string `json:"chainId"`
+	// 开始时间，毫秒
+	StartTs int64 `json:"startTs"`
+	// 结束时间，毫秒
+	EndTs int64 `json:"endTs"`
+	// 耗时，毫秒
+	Duration int64 `json:"duration"`
+	// 节点执行次数，包括子规则链节点
+	StepCount int `json:"stepCount"`
+	// 执行出错的节点数
+	ErrCount int `json:"errCount"`
+	// 按开始时间排列的节点执行步骤，子规则链的步骤嵌套在调用它的节点下
+	Steps []*TraceStep `json:"steps"`
+}
+
+// TraceStep 节点一次执行
+type TraceStep struct {
+	// 规则链ID
+	ChainId string `json:"chainId"`
+	// 节点ID
+	NodeId string `json:"nodeId"`
+	// 节点类型，规则链已删除或者节点不存在则为空
+	NodeType string `json:"nodeType,omitempty"`
+	// 节点名称
+	NodeName string `json:"nodeName,omitempty"`
+	// 开始时间，毫秒
+	InTs int64 `json:"inTs"`
+	// 结束时间，毫秒，未结束为0
+	OutTs int64 `json:"outTs,omitempty"`
+	// 相对轨迹开始时间的偏移，毫秒
+	Offset int64 `json:"offset"`
+	// 耗时，毫秒
+	Duration int64 `json:"duration"`
+	// 是否已结束，没有OUT调试数据则未结束，例如执行中、超时或者OUT数据已被清理
+	Completed bool `json:"completed"`
+	// 关系类型，节点通过多个关系输出时按顺序列出
+	Relations []string `json:"relations,omitempty"`
+	// 错误信息
+	Err string `json:"err,omitempty"`
+	// 是否模拟节点
+	Mocked bool `json:"mocked,omitempty"`
+	// 输入消息
+	InMsg types.RuleMsg `json:"inMsg"`
+	// 输出消息
+	OutMsg *types.RuleMsg `json:"outMsg,omitempty"`
+	// 节点是否修改了消息类型
+	TypeChanged bool `json:"typeChanged"`
+	// 输出消息相对输入消息的内容差异
+	Data []diff.Value `json:"data,omitempty"`
+	// 输出消息相对输入消息的元数据差异
+	Metadata []diff.Value `json:"metadata,omitempty"`
+	// 节点执行期间打印的日志
+	Logs []string `json:"logs,omitempty"`
+	// 该节点调用的子规则链执行步骤
+	Children []*TraceStep `json:"children,omitempty"`
+	// 该节点调用的子规则链ID
+	subChains map[string]bool
+	// 节点在规则链中的深度，用于同一毫秒开始的步骤排序
+	depth int
+}
+
+// Trace 根据消息ID还原消息的执行轨迹
+func (s *RuleEngineService) Trace(msgId string) (Trace, error) {
+	var result = Trace{MsgId: msgId, Steps: make([]*TraceStep, 0)}
+	if DebugServiceImpl == nil {
+		return result, constants.ErrNotFound
+	}
+	items, _, err := DebugServiceImpl.List(s.username, model.DebugDataQuery{MsgId: msgId, Asc: true})
+	if err != nil {
+		return result, err
+	}
+	steps := s.traceSteps(items)
+	if len(steps) == 0 {
+		return result, constants.ErrNotFound
+	}
+	result.ChainId = steps[0].ChainId
+	result.StartTs = steps[0].InTs
+	for _, step := range steps {
+		step.Offset = step.InTs - result.StartTs
+		if step.OutTs > result.EndTs {
+			result.EndTs = step.OutTs
+		}
+		if step.InTs > result.EndTs {
+			result.EndTs = step.InTs
+		}
+		if step.Err != "" {
+			result.ErrCount++
+		}
+	}
+	result.Duration = result.EndTs - result.StartTs
+	result.StepCount = len(steps)
+	result.Steps = nestTraceSteps(steps)
+	return result, nil
+}
+
+// TraceRun 根据执行ID还原消息的执行轨迹，执行ID可以是异步执行ID或者运行快照ID(即消息ID)
+func (s *RuleEngineService) TraceRun(runId string) (Trace, error) {
+	var msgId = runId
+	if RunServiceImpl != nil {
+		if run, err := RunServiceImpl.Get(s.username, runId); err == nil {
+			msgId = run.MsgId
+		}
+	}
+	result, err := s.Trace(msgId)
+	result.RunId = runId
+	return result, err
+}
+
+// traceSteps 把IN/OUT/Log调试数据按节点配对成执行步骤，调试数据需要按时间升序
+// 调试数据是异步记录的，同一个节点的OUT数据时间可能早于IN数据，所以同一个节点的第N个IN数据和第N个OUT数据配对，
+// 多出的OUT数据作为该节点最后一次执行的其他输出关系；同一毫秒开始的步骤按节点在规则链中的深度排列
+func (s *RuleEngineService) traceSteps(items []model.DebugData) []*TraceStep {
+	type nodeKey struct {
+		chainId string
+		nodeId  string
+	}
+	type nodeItems struct {
+		ins  []model.DebugData
+		outs []model.DebugData
+		logs []model.DebugData
+	}
+	var keys []nodeKey
+	var byKey = make(map[nodeKey]*nodeItems)
+	for _, item := range items {
+		key := nodeKey{chainId: item.ChainId, nodeId: item.NodeId}
+		group, ok := byKey[key]
+		if !ok {
+			group = &nodeItems{}
+			byKey[key] = group
+			keys = append(keys, key)
+		}
+		switch item.FlowType {
+		case types.In:
+			group.ins = append(group.ins, item)
+		case types.Out:
+			group.outs = append(group.outs, item)
+		case types.Log:
+			group.logs = append(group.logs, item)
+		}
+	}
+	var defs = make(map[string]*traceChainDef)
+	var steps []*TraceStep
+	for _, key := range keys {
+		group := byKey[key]
+		if len(group.ins) == 0 {
+			//IN数据已被清理
+			continue
+		}
+		def, ok := defs[key.chainId]
+		if !ok {
+			def = s.traceChainDef(key.chainId)
+			defs[key.chainId] = def
+		}
+		var nodeSteps = make([]*TraceStep, 0, len(group.ins))
+		for i, item := range group.ins {
+			step := &TraceStep{
+				ChainId:   item.ChainId,
+				NodeId:    item.NodeId,
+				InTs:      item.Ts,
+				InMsg:     item.Msg,
+				Mocked:    item.Mocked,
+				Err:       item.Err,
+				subChains: def.callers[item.NodeId],
+				depth:     def.depth[item.NodeId],
+			}
+			if node, ok := def.nodes[item.NodeId]; ok {
+				step.NodeType = node.Type
+				step.NodeName = node.Name
+			}
+			if i < len(group.outs) {
+				completeTraceStep(step, group.outs[i])
+			}
+			nodeSteps = append(nodeSteps, step)
+		}
+		lastStep := nodeSteps[len(nodeSteps)-1]
+		for _, item := range group.outs[min(len(group.ins), len(group.outs)):] {
+			lastStep.Relations = append(lastStep.Relations, item.RelationType)
+			if lastStep.Err == "" {
+				lastStep.Err = item.Err
+			}
+		}
+		for _, item := range group.logs {
+			//日志归属于在它之前最近开始的一次执行
+			step := nodeSteps[0]
+			for _, candidate := range nodeSteps {
+				if candidate.InTs <= item.Ts {
+					step = candidate
+				}
+			}
+			step.Logs = append(step.Logs, item.Msg.Data)
+		}
+		steps = append(steps, nodeSteps...)
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].InTs != steps[j].InTs {
+			return steps[i].InTs < steps[j].InTs
+		}
+		return steps[i].depth < steps[j].depth
+	})
+	return steps
+}
+
+// traceChainDef 还原轨迹需要的规则链定义信息
+type traceChainDef struct {
+	// 节点ID->节点定义
+	nodes map[string]*types.RuleNode
+	// 节点ID->该节点调用的子规则链ID
+	callers map[string]map[string]bool
+	// 节点ID->从第一个节点开始的最短深度
+	depth map[string]int
+}
+
+// traceChainDef 获取规则链定义信息，规则链不存在则返回空定义
+func (s *RuleEngineService) traceChainDef(chainId string) *traceChainDef {
+	var result = &traceChainDef{
+		nodes:   make(map[string]*types.RuleNode),
+		callers: make(map[string]map[string]bool),
+		depth:   make(map[string]int),
+	}
+	def, ok := s.Get(chainId)
+	if !ok {
+		return result
+	}
+	var addCaller = func(nodeId, targetId string) {
+		if result.callers[nodeId] == nil {
+			result.callers[nodeId] = make(map[string]bool)
+		}
+		result.callers[nodeId][targetId] = true
+	}
+	for _, node := range def.Metadata.Nodes {
+		result.nodes[node.Id] = node
+		if node.Type == flowNodeType {
+			if targetId, ok := node.Configuration[flowTargetId].(string); ok && targetId != "" {
+				addCaller(node.Id, targetId)
+			}
+		}
+	}
+	for _, conn := range def.Metadata.RuleChainConnections {
+		addCaller(conn.FromId, conn.ToId)
+	}
+	var nextNodes = make(map[string][]string)
+	for _, conn := range def.Metadata.Connections {
+		nextNodes[conn.FromId] = append(nextNodes[conn.FromId], conn.ToId)
+	}
+	if index := def.Metadata.FirstNodeIndex; index >= 0 && index < len(def.Metadata.Nodes) {
+		first := def.Metadata.Nodes[index].Id
+		result.depth[first] = 0
+		var queue = []string{first}
+		for len(queue) > 0 {
+			nodeId := queue[0]
+			queue = queue[1:]
+			for _, next := range nextNodes[nodeId] {
+				if _, ok := result.depth[next]; !ok {
+					result.depth[next] = result.depth[nodeId] + 1
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// completeTraceStep 使用OUT数据结束步骤，并比较输入输出消息
+func completeTraceStep(step *TraceStep, item model.DebugData) {
+	outMsg := item.Msg
+	//异步记录的OUT数据时间可能早于IN数据
+	step.OutTs = max(item.Ts, step.InTs)
+	step.Duration = step.OutTs - step.InTs
+	step.Completed = true
+	step.OutMsg = &outMsg
+	step.Relations = append(step.Relations, item.RelationType)
+	if item.Err != "" {
+		step.Err = item.Err
+	}
+	step.TypeChanged = step.InMsg.Type != outMsg.Type
+	step.Data = diff.JSON(step.InMsg.Data, outMsg.Data)
+	step.Metadata = diff.Map(diffMetadata(step.InMsg.Metadata), diffMetadata(outMsg.Metadata))
+}
+
+// nestTraceSteps 把子规则链的步骤嵌套到调用它的节点下，步骤需要按开始时间升序
+// 优先选择调用该子规则链、在该步骤之前最近开始的节点，调试数据时间有误差所以不要求调用节点的执行时间包含该步骤；
+// 找不到则选择其他规则链中执行时间包含该步骤开始时间的最近开始的节点。入口规则链的步骤和找不到调用节点的步骤作为顶层步骤
+func nestTraceSteps(steps []*TraceStep) []*TraceStep {
+	var roots = make([]*TraceStep, 0)
+	if len(steps) == 0 {
+		return roots
+	}
+	rootChainId := steps[0].ChainId
+	for _, step := range steps {
+		var parent, firstCaller, fallback *TraceStep
+		if step.ChainId != rootChainId {
+			for _, candidate := range steps {
+				if candidate.ChainId == step.ChainId {
+					continue
+				}
+				if candidate.subChains[step.ChainId] {
+					if firstCaller == nil {
+						firstCaller = candidate
+					}
+					if candidate.InTs <= step.InTs {
+						parent = candidate
+					}
+				} else if candidate.InTs <= step.InTs && (!candidate.Completed || candidate.OutTs >= step.InTs) {
+					fallback = candidate
+				}
+			}
+		}
+		if parent == nil {
+			parent = firstCaller
+		}
+		if parent == nil {
+			parent = fallback
+		}
+		if parent == nil {
+			roots = append(roots, step)
+		} else {
+			parent.Children = append(parent.Children, step)
+		}
+	}
+	return roots
+}
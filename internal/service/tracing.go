@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"ruleGoProject/config"
+	"strings"
+
+	"github.com/rulego/rulego"
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/components/external"
+	"github.com/rulego/rulego/utils/reflect"
+	"github.com/rulego/rulego/utils/str"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingImpl opentelemetry链路追踪，未开启时为nil
+var TracingImpl *Tracing
+
+// 链路追踪导出方式
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOtlp   = "otlp"
+)
+
+const (
+	// tracerName 追踪器名称
+	tracerName = "ruleGoProject"
+	// defaultTraceServiceName 默认服务名称
+	defaultTraceServiceName = "rulego-server"
+	// defaultTraceEndpoint 默认otlp http接收地址
+	defaultTraceEndpoint = "http://127.0.0.1:4318"
+	// otlpTracesPath otlp http接收路径
+	otlpTracesPath = "/v1/traces"
+	// restApiCallType 需要传递traceparent的http调用组件类型
+	restApiCallType = "restApiCall"
+)
+
+const (
+	// headerTraceParent w3c trace context 请求头
+	headerTraceParent = "traceparent"
+	// metadataTraceParent restApiCall节点请求时临时保存traceparent的元数据key，输出消息中会移除
+	metadataTraceParent = "_traceparent"
+)
+
+// 规则链相关的span属性
+const (
+	attrUser         = attribute.Key("rulego.user")
+	attrChainId      = attribute.Key("rulego.chain.id")
+	attrNodeId       = attribute.Key("rulego.node.id")
+	attrNodeType     = attribute.Key("rulego.node.type")
+	attrMsgId        = attribute.Key("rulego.msg.id")
+	attrMsgType      = attribute.Key("rulego.msg.type")
+	attrRelationType = attribute.Key("rulego.relation_type")
+	attrTopic        = attribute.Key("rulego.topic")
+)
+
+// Tracing opentelemetry链路追踪
+// 每个http请求、mqtt消息创建一个span，规则链每次执行和每个节点执行分别创建子span
+// 通过规则引擎上下文传递span，不修改消息元数据，restApiCall节点请求自动携带traceparent请求头
+type Tracing struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracing 根据配置创建链路追踪，没有配置导出方式则返回nil
+func NewTracing(config config.Config) (*Tracing, error) {
+	var exporter sdktrace.SpanExporter
+	switch config.TraceExporter {
+	case "", TraceExporterNone:
+		return nil, nil
+	case TraceExporterStdout:
+		if e, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout)); err != nil {
+			return nil, err
+		} else {
+			exporter = e
+		}
+	case TraceExporterOtlp:
+		if e, err := newOtlpExporter(config.TraceEndpoint); err != nil {
+			return nil, err
+		} else {
+			exporter = e
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter=%s", config.TraceExporter)
+	}
+	serviceName := config.TraceServiceName
+	if serviceName == "" {
+		serviceName = defaultTraceServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		//上游已经决定采样的，沿用上游的采样结果
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	//restApiCall节点请求携带traceparent请求头
+	if err := rulego.Registry.Unregister(restApiCallType); err == nil {
+		if err := rulego.Registry.Register(&traceRestApiCallNode{}); err != nil {
+			return nil, err
+		}
+	}
+	return &Tracing{
+		provider:   provider,
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}, nil
+}
+
+// Shutdown 导出剩余的span
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+// Tracer 追踪器
+func (t *Tracing) Tracer() trace.Tracer {
+	return t.tracer
+}
+
+// Extract 从http请求头读取上游的trace context
+func (t *Tracing) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// traceParent ctx中span的w3c traceparent，没有span返回空
+func (t *Tracing) traceParent(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	carrier := propagation.HeaderCarrier{}
+	t.propagator.Inject(ctx, carrier)
+	return carrier.Get(headerTraceParent)
+}
+
+// StartConsumer 创建接收消息的span，用于mqtt等没有http请求的接入端点，返回包含该span的上下文
+// 如果消息元数据已经携带traceparent，则作为父span。span在规则链执行结束时结束
+func (t *Tracing) StartConsumer(ctx context.Context, name string, msg *types.RuleMsg, attrs ...attribute.KeyValue) context.Context {
+	parent := t.propagator.Extract(ctx, metadataCarrier(msg.Metadata))
+	spanCtx, span := t.tracer.Start(parent, name, trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(append(attrs, attrMsgId.String(msg.Id), attrMsgType.String(msg.Type))...))
+	return context.WithValue(spanCtx, traceConsumerSpanKey{}, span)
+}
+
+// WithTopic mqtt主题span属性
+func WithTopic(topic string) attribute.KeyValue {
+	return attrTopic.String(topic)
+}
+
+// metadataCarrier 从消息元数据读取上游的trace context，只读
+// http请求头放入元数据时是规范化的key，例如：Traceparent，读取时兼容
+type metadataCarrier types.Metadata
+
+func (c metadataCarrier) Get(key string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	return c[http.CanonicalHeaderKey(key)]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// 接收消息span、规则链执行span和节点执行span在规则引擎上下文中的key
+type (
+	traceConsumerSpanKey struct{}
+	traceChainSpanKey    struct{}
+	traceNodeSpanKey     struct{}
+)
+
+// chainSpan 规则链执行span，consumer为接入端点接收消息的span，最外层规则链执行结束时一起结束，子规则链为nil
+type chainSpan struct {
+	span     trace.Span
+	consumer trace.Span
+}
+
+// tracingAspect 规则链执行和节点执行创建span的切面
+// 规则链执行span的父span是上下文中的span：http请求span、接收消息span或者调用子规则链的flow节点span
+// 节点执行span是规则链执行span的子span，保存在节点上下文中，供子规则链和restApiCall节点使用
+type tracingAspect struct {
+	username string
+}
+
+func (a *tracingAspect) Order() int {
+	return 20
+}
+
+func (a *tracingAspect) New() types.Aspect {
+	return &tracingAspect{username: a.username}
+}
+
+func (a *tracingAspect) PointCut(ctx types.RuleContext, msg types.RuleMsg, relationType string) bool {
+	return TracingImpl != nil
+}
+
+func (a *tracingAspect) Start(ctx types.RuleContext, msg types.RuleMsg) types.RuleMsg {
+	parent := ctx.GetContext()
+	if parent == nil {
+		parent = context.Background()
+	}
+	chainId := traceChainId(ctx)
+	spanCtx, span := TracingImpl.tracer.Start(parent, "chain "+chainId, trace.WithAttributes(
+		attrUser.String(a.username),
+		attrChainId.String(chainId),
+		attrMsgId.String(msg.Id),
+		attrMsgType.String(msg.Type),
+	))
+	current := &chainSpan{span: span}
+	//最外层规则链负责结束接收消息span
+	if _, isSubChain := parent.Value(traceChainSpanKey{}).(*chainSpan); !isSubChain {
+		current.consumer, _ = parent.Value(traceConsumerSpanKey{}).(trace.Span)
+	}
+	ctx.SetContext(context.WithValue(spanCtx, traceChainSpanKey{}, current))
+	return msg
+}
+
+func (a *tracingAspect) End(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) types.RuleMsg {
+	if current, ok := contextChainSpan(ctx); ok && err != nil {
+		current.span.RecordError(err)
+		current.span.SetStatus(codes.Error, err.Error())
+	}
+	return msg
+}
+
+func (a *tracingAspect) Completed(ctx types.RuleContext, msg types.RuleMsg) types.RuleMsg {
+	if current, ok := contextChainSpan(ctx); ok {
+		current.span.End()
+		if current.consumer != nil {
+			current.consumer.End()
+		}
+	}
+	return msg
+}
+
+func (a *tracingAspect) Before(ctx types.RuleContext, msg types.RuleMsg, relationType string) types.RuleMsg {
+	current, ok := contextChainSpan(ctx)
+	if !ok {
+		return msg
+	}
+	var nodeId, nodeType string
+	if self := ctx.Self(); self != nil {
+		nodeId = self.GetNodeId().Id
+		nodeType = self.Type()
+	}
+	spanCtx, span := TracingImpl.tracer.Start(trace.ContextWithSpan(ctx.GetContext(), current.span), "node "+nodeId, trace.WithAttributes(
+		attrUser.String(a.username),
+		attrChainId.String(traceChainId(ctx)),
+		attrNodeId.String(nodeId),
+		attrNodeType.String(nodeType),
+		attrMsgId.String(msg.Id),
+		attrRelationType.String(relationType),
+	))
+	ctx.SetContext(context.WithValue(spanCtx, traceNodeSpanKey{}, span))
+	return msg
+}
+
+func (a *tracingAspect) After(ctx types.RuleContext, msg types.RuleMsg, err error, relationType string) types.RuleMsg {
+	if span, ok := contextSpan(ctx, traceNodeSpanKey{}); ok {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		//同一个节点多次通知下一个节点时，只有第一次生效
+		span.End()
+	}
+	return msg
+}
+
+// contextSpan 获取规则引擎上下文中指定key的span
+func contextSpan(ctx types.RuleContext, key any) (trace.Span, bool) {
+	if ctx.GetContext() == nil {
+		return nil, false
+	}
+	span, ok := ctx.GetContext().Value(key).(trace.Span)
+	return span, ok
+}
+
+// contextChainSpan 获取规则引擎上下文中当前规则链执行span
+func contextChainSpan(ctx types.RuleContext) (*chainSpan, bool) {
+	if ctx.GetContext() == nil {
+		return nil, false
+	}
+	current, ok := ctx.GetContext().Value(traceChainSpanKey{}).(*chainSpan)
+	return current, ok
+}
+
+// traceChainId 当前执行的规则链ID
+func traceChainId(ctx types.RuleContext) string {
+	if chainCtx := ctx.RuleChain(); chainCtx != nil {
+		return chainCtx.GetNodeId().Id
+	}
+	return ""
+}
+
+// traceRestApiCallNode restApiCall组件，请求自动携带当前节点span的traceparent请求头，已配置的请求头不覆盖
+// 不修改节点配置，避免traceparent请求头被保存到规则链
+type traceRestApiCallNode struct {
+	*external.RestApiCallNode
+	//是否需要添加traceparent请求头
+	injectTraceParent bool
+}
+
+func (x *traceRestApiCallNode) New() types.Node {
+	return &traceRestApiCallNode{RestApiCallNode: (&external.RestApiCallNode{}).New().(*external.RestApiCallNode)}
+}
+
+// Def 使用原组件的表单定义
+func (x *traceRestApiCallNode) Def() types.ComponentForm {
+	return reflect.GetComponentForm((&external.RestApiCallNode{}).New())
+}
+
+func (x *traceRestApiCallNode) Init(ruleConfig types.Config, configuration types.Configuration) error {
+	headers := make(map[string]interface{})
+	if v, ok := configuration["headers"]; ok && v != nil {
+		for k, value := range str.ToStringMapString(v) {
+			if strings.EqualFold(k, headerTraceParent) {
+				return x.RestApiCallNode.Init(ruleConfig, configuration)
+			}
+			headers[k] = value
+		}
+	} else {
+		headers["Content-Type"] = "application/json"
+	}
+	headers[headerTraceParent] = "${metadata." + metadataTraceParent + "}"
+	x.injectTraceParent = true
+	var copied = make(types.Configuration, len(configuration)+1)
+	for k, v := range configuration {
+		copied[k] = v
+	}
+	copied["headers"] = headers
+	return x.RestApiCallNode.Init(ruleConfig, copied)
+}
+
+// OnMsg 在消息副本的元数据中写入当前节点span的traceparent，只用于生成请求头，输出消息中移除
+func (x *traceRestApiCallNode) OnMsg(ctx types.RuleContext, msg types.RuleMsg) {
+	var traceParent string
+	if x.injectTraceParent && TracingImpl != nil {
+		traceParent = TracingImpl.traceParent(ctx.GetContext())
+	}
+	if traceParent == "" {
+		x.RestApiCallNode.OnMsg(ctx, msg)
+		return
+	}
+	msg = msg.Copy()
+	msg.Metadata.PutValue(metadataTraceParent, traceParent)
+	x.RestApiCallNode.OnMsg(&traceRestApiCallContext{RuleContext: ctx}, msg)
+}
+
+// traceRestApiCallContext 通知下一个节点前移除临时保存的traceparent
+type traceRestApiCallContext struct {
+	types.RuleContext
+}
+
+func (c *traceRestApiCallContext) TellSuccess(msg types.RuleMsg) {
+	delete(msg.Metadata, metadataTraceParent)
+	c.RuleContext.TellSuccess(msg)
+}
+
+func (c *traceRestApiCallContext) TellFailure(msg types.RuleMsg, err error) {
+	delete(msg.Metadata, metadataTraceParent)
+	c.RuleContext.TellFailure(msg, err)
+}
+
+func (c *traceRestApiCallContext) TellNext(msg types.RuleMsg, relationTypes ...string) {
+	delete(msg.Metadata, metadataTraceParent)
+	c.RuleContext.TellNext(msg, relationTypes...)
+}
+
+// newOtlpExporter 创建otlp http/protobuf exporter，使用gzip压缩，失败自动重试，endpoint没有路径则使用/v1/traces
+func newOtlpExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	if endpoint == "" {
+		endpoint = defaultTraceEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid trace endpoint=%s", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(u.String()),
+		otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
+	)
+}
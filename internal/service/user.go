@@ -1,22 +1,207 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
 	"ruleGoProject/internal/dao"
+	"ruleGoProject/internal/model"
+	"ruleGoProject/internal/utils/jwt"
+	"time"
 )
 
 var UserServiceImpl *UserService
 
+// tokenVersionSize token中凭证版本的字节数
+const tokenVersionSize = 8
+
 type UserService struct {
 	UserDao *dao.UserDao
+	config  config.Config
+	//jwt签名秘钥
+	secretKey []byte
 }
 
 func NewUserService(config config.Config) (*UserService, error) {
 	if userDao, err := dao.NewUserDao(config); err != nil {
 		return nil, err
 	} else {
+		secretKey := []byte(config.JwtSecretKey)
+		if len(secretKey) == 0 {
+			//未配置秘钥，随机生成，重启后已签发的token失效
+			secretKey = make([]byte, 32)
+			if _, err := rand.Read(secretKey); err != nil {
+				return nil, err
+			}
+			logger.Logger.Println("jwt_secret_key is not configured, use a random key")
+		}
+		if config.JwtExpireTime <= 0 {
+			config.JwtExpireTime = 2 * time.Hour
+		}
+		if config.JwtRefreshExpireTime <= 0 {
+			config.JwtRefreshExpireTime = 7 * 24 * time.Hour
+		}
+		if err := initDefaultUser(userDao, config); err != nil {
+			return nil, err
+		}
 		return &UserService{
-			UserDao: userDao,
+			UserDao:   userDao,
+			config:    config,
+			secretKey: secretKey,
 		}, nil
 	}
 }
+
+// initDefaultUser 用户文件中没有默认用户时，使用配置的初始密码创建，已存在则不修改密码
+func initDefaultUser(userDao *dao.UserDao, c config.Config) error {
+	if c.DefaultUsername == "" || userDao.Exists(c.DefaultUsername) {
+		return nil
+	}
+	if c.AdminPassword == "" {
+		logger.Logger.Printf("default user %s does not exist, set admin_password or %s to create it", c.DefaultUsername, config.EnvAdminPassword)
+		return nil
+	}
+	return userDao.SavePassword(c.DefaultUsername, c.AdminPassword)
+}
+
+// Login 校验用户名密码，并签发token
+func (s *UserService) Login(username, password string) (model.Token, error) {
+	if username == "" {
+		return model.Token{}, constants.ErrUsernameEmpty
+	}
+	if !s.UserDao.ValidatePassword(username, password) {
+		return model.Token{}, constants.ErrUsernameOrPasswordErr
+	}
+	return s.createToken(username)
+}
+
+// RefreshToken 使用刷新token重新签发token
+func (s *UserService) RefreshToken(refreshToken string) (model.Token, error) {
+	claims, err := jwt.ParseToken(s.secretKey, s.config.JwtIssuer, refreshToken, jwt.TokenTypeRefresh)
+	if err != nil {
+		return model.Token{}, err
+	}
+	if !s.validClaims(claims) {
+		return model.Token{}, constants.ErrUnauthorized
+	}
+	return s.createToken(claims.Username)
+}
+
+// ParseToken 校验访问token，返回用户名
+// 用户已经被删除或者签发后修改过密码，token失效
+func (s *UserService) ParseToken(token string) (string, error) {
+	claims, err := jwt.ParseToken(s.secretKey, s.config.JwtIssuer, token, jwt.TokenTypeAccess)
+	if err != nil {
+		return "", err
+	}
+	if !s.validClaims(claims) {
+		return "", constants.ErrUnauthorized
+	}
+	return claims.Username, nil
+}
+
+// validClaims 用户是否存在并且凭证版本和签发时一致
+func (s *UserService) validClaims(claims *jwt.Claims) bool {
+	version := s.tokenVersion(claims.Username)
+	return version != "" && hmac.Equal([]byte(version), []byte(claims.Version))
+}
+
+// tokenVersion 用户凭证版本，使用秘钥对保存的密码哈希签名，密码哈希每次保存都会重新加盐，修改密码后版本改变
+// 用户不存在返回空
+func (s *UserService) tokenVersion(username string) string {
+	passwordHash := s.UserDao.PasswordHash(username)
+	if passwordHash == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte(passwordHash))
+	return hex.EncodeToString(mac.Sum(nil)[:tokenVersionSize])
+}
+
+// IsAdmin 是否是管理员，默认用户为管理员
+func (s *UserService) IsAdmin(username string) bool {
+	return username == s.config.DefaultUsername
+}
+
+// CreateUser 创建用户，只有管理员可以操作
+func (s *UserService) CreateUser(operator string, user model.User) error {
+	if !s.IsAdmin(operator) {
+		return constants.ErrForbidden
+	}
+	if user.Username == "" {
+		return constants.ErrUsernameEmpty
+	}
+	if user.Password == "" {
+		return constants.ErrPasswordEmpty
+	}
+	if s.UserDao.Exists(user.Username) {
+		return constants.ErrUserExists
+	}
+	return s.UserDao.CreateUser(user)
+}
+
+// DeleteUser 删除用户，只有管理员可以操作，不能删除自己
+func (s *UserService) DeleteUser(operator, username string) error {
+	if !s.IsAdmin(operator) || operator == username {
+		return constants.ErrForbidden
+	}
+	if !s.UserDao.Exists(username) {
+		return constants.ErrNotFound
+	}
+	if err := s.UserDao.Delete(username); err != nil {
+		return err
+	}
+	//停止并移除用户规则引擎池
+	if UserRuleEngineServiceImpl != nil {
+		UserRuleEngineServiceImpl.Remove(username)
+	}
+	return nil
+}
+
+// ListUsers 获取所有用户，只有管理员可以操作
+func (s *UserService) ListUsers(operator string) ([]model.User, error) {
+	if !s.IsAdmin(operator) {
+		return nil, constants.ErrForbidden
+	}
+	return s.UserDao.List(), nil
+}
+
+// ChangePassword 修改密码
+// 修改自己的密码需要校验旧密码，管理员可以直接重置其他用户密码
+func (s *UserService) ChangePassword(operator, username string, req model.ChangePassword) error {
+	if req.Password == "" {
+		return constants.ErrPasswordEmpty
+	}
+	if operator == username {
+		if !s.UserDao.ValidatePassword(username, req.OldPassword) {
+			return constants.ErrUsernameOrPasswordErr
+		}
+	} else if !s.IsAdmin(operator) {
+		return constants.ErrForbidden
+	} else if !s.UserDao.Exists(username) {
+		return constants.ErrNotFound
+	}
+	return s.UserDao.SavePassword(username, req.Password)
+}
+
+func (s *UserService) createToken(username string) (model.Token, error) {
+	var result model.Token
+	version := s.tokenVersion(username)
+	token, expireAt, err := jwt.CreateToken(s.secretKey, s.config.JwtIssuer, username, version, jwt.TokenTypeAccess, s.config.JwtExpireTime)
+	if err != nil {
+		return result, err
+	}
+	refreshToken, refreshExpireAt, err := jwt.CreateToken(s.secretKey, s.config.JwtIssuer, username, version, jwt.TokenTypeRefresh, s.config.JwtRefreshExpireTime)
+	if err != nil {
+		return result, err
+	}
+	result.Token = token
+	result.ExpireAt = expireAt.UnixMilli()
+	result.RefreshToken = refreshToken
+	result.RefreshExpireAt = refreshExpireAt.UnixMilli()
+	return result, nil
+}
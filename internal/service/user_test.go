@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log"
+	"ruleGoProject/config"
+	"ruleGoProject/config/logger"
+	"ruleGoProject/internal/constants"
+	"ruleGoProject/internal/model"
+	"testing"
+)
+
+// newTestUserService 创建使用临时目录保存用户的用户服务
+func newTestUserService(t *testing.T, adminPassword string) *UserService {
+	t.Helper()
+	if logger.Logger == nil {
+		logger.Set(log.New(io.Discard, "", 0))
+	}
+	s, err := NewUserService(config.Config{
+		DataDir:         t.TempDir(),
+		DefaultUsername: "admin",
+		AdminPassword:   adminPassword,
+		JwtSecretKey:    "secret",
+		JwtIssuer:       "rulego",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestInitDefaultUser(t *testing.T) {
+	s := newTestUserService(t, "admin123")
+	if _, err := s.Login("admin", "admin123"); err != nil {
+		t.Fatalf("login with admin password error: %v", err)
+	}
+
+	//已存在的默认用户不会被初始密码覆盖
+	if err := s.ChangePassword("admin", "admin", model.ChangePassword{OldPassword: "admin123", Password: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := initDefaultUser(s.UserDao, config.Config{DefaultUsername: "admin", AdminPassword: "admin123"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Login("admin", "changed"); err != nil {
+		t.Errorf("login with changed password error: %v", err)
+	}
+
+	if s := newTestUserService(t, ""); s.UserDao.Exists("admin") {
+		t.Error("default user created without admin password")
+	}
+}
+
+func TestTokenRevocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(s *UserService) error
+	}{
+		{
+			name: "change own password",
+			revoke: func(s *UserService) error {
+				return s.ChangePassword("u1", "u1", model.ChangePassword{OldPassword: "p1", Password: "p2"})
+			},
+		},
+		{
+			name: "admin reset password",
+			revoke: func(s *UserService) error {
+				return s.ChangePassword("admin", "u1", model.ChangePassword{Password: "p2"})
+			},
+		},
+		{
+			name: "delete user",
+			revoke: func(s *UserService) error {
+				return s.DeleteUser("admin", "u1")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUserService(t, "admin123")
+			if err := s.CreateUser("admin", model.User{Username: "u1", Password: "p1"}); err != nil {
+				t.Fatal(err)
+			}
+			token, err := s.Login("u1", "p1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if username, err := s.ParseToken(token.Token); err != nil || username != "u1" {
+				t.Fatalf("ParseToken() = %s, %v", username, err)
+			}
+			if err := tt.revoke(s); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.ParseToken(token.Token); !errors.Is(err, constants.ErrUnauthorized) {
+				t.Errorf("ParseToken() error = %v, want %v", err, constants.ErrUnauthorized)
+			}
+			if _, err := s.RefreshToken(token.RefreshToken); !errors.Is(err, constants.ErrUnauthorized) {
+				t.Errorf("RefreshToken() error = %v, want %v", err, constants.ErrUnauthorized)
+			}
+		})
+	}
+}
+
+func TestTokenType(t *testing.T) {
+	s := newTestUserService(t, "admin123")
+	token, err := s.Login("admin", "admin123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ParseToken(token.RefreshToken); err == nil {
+		t.Error("refresh token is accepted as access token")
+	}
+	if _, err := s.RefreshToken(token.Token); err == nil {
+		t.Error("access token is accepted as refresh token")
+	}
+	if _, err := s.Login("admin", "wrong"); !errors.Is(err, constants.ErrUsernameOrPasswordErr) {
+		t.Errorf("Login() error = %v, want %v", err, constants.ErrUsernameOrPasswordErr)
+	}
+}
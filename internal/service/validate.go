@@ -0,0 +1,244 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rulego/rulego"
+	"github.com/rulego/rulego/api/types"
+	"github.com/rulego/rulego/utils/json"
+)
+
+// 校验问题编码
+const (
+	IssueInvalidJson          = "INVALID_JSON"
+	IssueChainIdMismatch      = "CHAIN_ID_MISMATCH"
+	IssueNodeIdEmpty          = "NODE_ID_EMPTY"
+	IssueNodeIdDuplicate      = "NODE_ID_DUPLICATE"
+	IssueNodeTypeNotFound     = "NODE_TYPE_NOT_FOUND"
+	IssueFirstNodeIndex       = "FIRST_NODE_INDEX_OUT_OF_RANGE"
+	IssueConnectionNotFound   = "CONNECTION_NODE_NOT_FOUND"
+	IssueConnectionTypeEmpty  = "CONNECTION_TYPE_EMPTY"
+	IssueConnectionTypeWrong  = "CONNECTION_TYPE_NOT_SUPPORTED"
+	IssueSubChainNotFound     = "SUB_CHAIN_NOT_FOUND"
+	IssueUnreachableNode      = "UNREACHABLE_NODE"
+	IssueCycle                = "CYCLE"
+	IssueGlobalPropertyAbsent = "GLOBAL_PROPERTY_NOT_FOUND"
+	IssueScheduleInvalid      = "SCHEDULE_INVALID"
+	IssueRetentionInvalid     = "RETENTION_INVALID"
+	IssueMocksInvalid         = "MOCKS_INVALID"
+)
+
+// globalRefRegexp 匹配${global.xxx}引用
+var globalRefRegexp = regexp.MustCompile(`\$\{\s*global\.([\w.\-]+)\s*}`)
+
+// ValidateIssue 校验问题
+type ValidateIssue struct {
+	// Code 问题编码
+	Code string `json:"code"`
+	// NodeId 问题所在节点ID，规则链级别的问题为空
+	NodeId string `json:"nodeId,omitempty"`
+	// Message 问题描述
+	Message string `json:"message"`
+}
+
+// ValidateResult 规则链DSL校验结果
+type ValidateResult struct {
+	// Valid 是否没有错误，警告不影响
+	Valid bool `json:"valid"`
+	// Errors 错误列表，存在错误的规则链无法正常运行
+	Errors []ValidateIssue `json:"errors"`
+	// Warnings 警告列表
+	Warnings []ValidateIssue `json:"warnings"`
+}
+
+func (r *ValidateResult) addError(code, nodeId, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, ValidateIssue{Code: code, NodeId: nodeId, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidateResult) addWarning(code, nodeId, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, ValidateIssue{Code: code, NodeId: nodeId, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateDsl 校验规则链DSL，不会修改规则引擎池中正在运行的规则链
+func (s *RuleEngineService) ValidateDsl(chainId string, def []byte) ValidateResult {
+	var result = ValidateResult{Errors: []ValidateIssue{}, Warnings: []ValidateIssue{}}
+	var ruleChain types.RuleChain
+	if err := json.Unmarshal(def, &ruleChain); err != nil {
+		result.addError(IssueInvalidJson, "", "parse dsl error: %s", err.Error())
+		return result
+	}
+	if chainId != "" && ruleChain.RuleChain.ID != "" && ruleChain.RuleChain.ID != chainId {
+		result.addWarning(IssueChainIdMismatch, "", "ruleChain.id=%s is different from chainId=%s", ruleChain.RuleChain.ID, chainId)
+	}
+	nodes := ruleChain.Metadata.Nodes
+	nodeIds := make(map[string]bool)
+	forms := rulego.Registry.GetComponentForms()
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		if node.Id == "" {
+			result.addError(IssueNodeIdEmpty, "", "node id cannot empty, type=%s", node.Type)
+			continue
+		}
+		if nodeIds[node.Id] {
+			result.addError(IssueNodeIdDuplicate, node.Id, "node id=%s is duplicate", node.Id)
+		}
+		nodeIds[node.Id] = true
+		if _, ok := forms.GetComponent(node.Type); !ok {
+			result.addError(IssueNodeTypeNotFound, node.Id, "node type=%s is not registered", node.Type)
+		}
+	}
+	firstNodeValid := ruleChain.Metadata.FirstNodeIndex >= 0 && ruleChain.Metadata.FirstNodeIndex < len(nodes)
+	if len(nodes) > 0 && !firstNodeValid {
+		result.addError(IssueFirstNodeIndex, "", "firstNodeIndex=%d is out of range", ruleChain.Metadata.FirstNodeIndex)
+	}
+	//节点ID->下一个节点ID列表
+	var nextNodes = make(map[string][]string)
+	var nodeTypes = make(map[string]string)
+	for _, node := range nodes {
+		if node != nil {
+			nodeTypes[node.Id] = node.Type
+		}
+	}
+	for _, conn := range ruleChain.Metadata.Connections {
+		if !nodeIds[conn.FromId] {
+			result.addError(IssueConnectionNotFound, conn.FromId, "connection fromId=%s is not found", conn.FromId)
+		}
+		if !nodeIds[conn.ToId] {
+			result.addError(IssueConnectionNotFound, conn.ToId, "connection toId=%s is not found", conn.ToId)
+		}
+		if conn.Type == "" {
+			result.addError(IssueConnectionTypeEmpty, conn.FromId, "connection type from %s to %s cannot empty", conn.FromId, conn.ToId)
+		} else if form, ok := forms.GetComponent(nodeTypes[conn.FromId]); ok && form.RelationTypes != nil && len(*form.RelationTypes) > 0 {
+			if !containsString(*form.RelationTypes, conn.Type) {
+				result.addWarning(IssueConnectionTypeWrong, conn.FromId, "connection type=%s is not in %s", conn.Type, strings.Join(*form.RelationTypes, ","))
+			}
+		}
+		if nodeIds[conn.FromId] && nodeIds[conn.ToId] {
+			nextNodes[conn.FromId] = append(nextNodes[conn.FromId], conn.ToId)
+		}
+	}
+	for _, conn := range ruleChain.Metadata.RuleChainConnections {
+		if !nodeIds[conn.FromId] {
+			result.addError(IssueConnectionNotFound, conn.FromId, "ruleChainConnection fromId=%s is not found", conn.FromId)
+		}
+		if _, ok := s.Pool.Get(conn.ToId); !ok && conn.ToId != chainId {
+			result.addWarning(IssueSubChainNotFound, conn.FromId, "sub rule chain=%s is not found", conn.ToId)
+		}
+	}
+	if firstNodeValid {
+		s.validateReachable(ruleChain, nextNodes, &result)
+	}
+	validateCycle(nodes, nextNodes, &result)
+	s.validateGlobalRef(ruleChain, &result)
+	if _, err := ParseSchedules(ruleChain.RuleChain.Configuration); err != nil {
+		result.addError(IssueScheduleInvalid, "", "schedules invalid: %s", err.Error())
+	}
+	if _, err := ParseRetention(ruleChain.RuleChain.Configuration); err != nil {
+		result.addError(IssueRetentionInvalid, "", "retention invalid: %s", err.Error())
+	}
+	if _, err := ParseMocks(ruleChain.RuleChain.Configuration); err != nil {
+		result.addError(IssueMocksInvalid, "", "mocks invalid: %s", err.Error())
+	}
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// validateReachable 检查从第一个节点出发无法到达的节点
+func (s *RuleEngineService) validateReachable(ruleChain types.RuleChain, nextNodes map[string][]string, result *ValidateResult) {
+	nodes := ruleChain.Metadata.Nodes
+	first := nodes[ruleChain.Metadata.FirstNodeIndex]
+	if first == nil {
+		return
+	}
+	visited := map[string]bool{first.Id: true}
+	queue := []string{first.Id}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range nextNodes[id] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	for _, node := range nodes {
+		if node != nil && !visited[node.Id] {
+			result.addWarning(IssueUnreachableNode, node.Id, "node id=%s is unreachable from first node=%s", node.Id, first.Id)
+		}
+	}
+}
+
+// validateCycle 检查节点之间的环路，环路可能导致消息无限循环
+func validateCycle(nodes []*types.RuleNode, nextNodes map[string][]string, result *ValidateResult) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		path = append(path, id)
+		for _, next := range nextNodes[id] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				//找到环路起点
+				start := 0
+				for i, v := range path {
+					if v == next {
+						start = i
+						break
+					}
+				}
+				cycle := append(append([]string{}, path[start:]...), next)
+				result.addWarning(IssueCycle, next, "cycle detected: %s", strings.Join(cycle, "->"))
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+	}
+	for _, node := range nodes {
+		if node != nil && state[node.Id] == unvisited {
+			visit(node.Id)
+		}
+	}
+}
+
+// validateGlobalRef 检查${global.xxx}引用是否在全局配置中存在
+func (s *RuleEngineService) validateGlobalRef(ruleChain types.RuleChain, result *ValidateResult) {
+	check := func(nodeId string, configuration types.Configuration) {
+		if configuration == nil {
+			return
+		}
+		v, _ := json.Marshal(configuration)
+		for _, match := range globalRefRegexp.FindAllStringSubmatch(string(v), -1) {
+			if _, ok := s.config.Global[match[1]]; !ok {
+				result.addError(IssueGlobalPropertyAbsent, nodeId, "global property=%s is not found", match[1])
+			}
+		}
+	}
+	check("", ruleChain.RuleChain.Configuration)
+	for _, node := range ruleChain.Metadata.Nodes {
+		if node != nil {
+			check(node.Id, node.Configuration)
+		}
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
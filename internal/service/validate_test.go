@@ -0,0 +1,187 @@
+package service
+
+import (
+	"reflect"
+	"ruleGoProject/config"
+	"testing"
+
+	"github.com/rulego/rulego/api/types"
+)
+
+// testNodes 按节点ID创建节点列表，空字符串表示nil节点
+func testNodes(ids ...string) []*types.RuleNode {
+	var nodes []*types.RuleNode
+	for _, id := range ids {
+		if id == "" {
+			nodes = append(nodes, nil)
+		} else {
+			nodes = append(nodes, &types.RuleNode{Id: id, Type: "log"})
+		}
+	}
+	return nodes
+}
+
+func TestValidateCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     []*types.RuleNode
+		nextNodes map[string][]string
+		want      []ValidateIssue
+	}{
+		{name: "no connections", nodes: testNodes("a", "b"), nextNodes: nil, want: nil},
+		{
+			name:      "linear",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b"}, "b": {"c"}},
+			want:      nil,
+		},
+		{
+			name:      "diamond",
+			nodes:     testNodes("a", "b", "c", "d"),
+			nextNodes: map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}},
+			want:      nil,
+		},
+		{
+			name:      "self loop",
+			nodes:     testNodes("a"),
+			nextNodes: map[string][]string{"a": {"a"}},
+			want:      []ValidateIssue{{Code: IssueCycle, NodeId: "a", Message: "cycle detected: a->a"}},
+		},
+		{
+			name:      "cycle back to first node",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			want:      []ValidateIssue{{Code: IssueCycle, NodeId: "a", Message: "cycle detected: a->b->c->a"}},
+		},
+		{
+			name:      "cycle after first node",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}},
+			want:      []ValidateIssue{{Code: IssueCycle, NodeId: "b", Message: "cycle detected: b->c->b"}},
+		},
+		{
+			name:      "multiple cycles",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b"}, "b": {"a"}, "c": {"c"}},
+			want: []ValidateIssue{
+				{Code: IssueCycle, NodeId: "a", Message: "cycle detected: a->b->a"},
+				{Code: IssueCycle, NodeId: "c", Message: "cycle detected: c->c"},
+			},
+		},
+		{
+			name:      "nil node skipped",
+			nodes:     testNodes("", "a"),
+			nextNodes: map[string][]string{"a": {"a"}},
+			want:      []ValidateIssue{{Code: IssueCycle, NodeId: "a", Message: "cycle detected: a->a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result ValidateResult
+			validateCycle(tt.nodes, tt.nextNodes, &result)
+			if !reflect.DeepEqual(result.Warnings, tt.want) {
+				t.Errorf("warnings = %+v, want %+v", result.Warnings, tt.want)
+			}
+			if len(result.Errors) > 0 {
+				t.Errorf("errors = %+v, want none", result.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateReachable(t *testing.T) {
+	tests := []struct {
+		name           string
+		nodes          []*types.RuleNode
+		firstNodeIndex int
+		nextNodes      map[string][]string
+		want           []ValidateIssue
+	}{
+		{
+			name:      "all reachable",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b", "c"}},
+			want:      nil,
+		},
+		{
+			name:      "reachable through cycle",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b"}, "b": {"a", "c"}},
+			want:      nil,
+		},
+		{
+			name:      "unreachable node",
+			nodes:     testNodes("a", "b", "c"),
+			nextNodes: map[string][]string{"a": {"b"}, "c": {"b"}},
+			want:      []ValidateIssue{{Code: IssueUnreachableNode, NodeId: "c", Message: "node id=c is unreachable from first node=a"}},
+		},
+		{
+			name:           "first node is not index 0",
+			nodes:          testNodes("a", "b"),
+			firstNodeIndex: 1,
+			nextNodes:      map[string][]string{"a": {"b"}},
+			want:           []ValidateIssue{{Code: IssueUnreachableNode, NodeId: "a", Message: "node id=a is unreachable from first node=b"}},
+		},
+		{
+			name:      "first node is nil",
+			nodes:     testNodes("", "a"),
+			nextNodes: nil,
+			want:      nil,
+		},
+	}
+	s := &RuleEngineService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result ValidateResult
+			ruleChain := types.RuleChain{Metadata: types.RuleMetadata{FirstNodeIndex: tt.firstNodeIndex, Nodes: tt.nodes}}
+			s.validateReachable(ruleChain, tt.nextNodes, &result)
+			if !reflect.DeepEqual(result.Warnings, tt.want) {
+				t.Errorf("warnings = %+v, want %+v", result.Warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGlobalRef(t *testing.T) {
+	tests := []struct {
+		name        string
+		chainConfig types.Configuration
+		nodeConfig  types.Configuration
+		want        []ValidateIssue
+	}{
+		{name: "nil configuration", want: nil},
+		{name: "no reference", nodeConfig: types.Configuration{"url": "http://127.0.0.1"}, want: nil},
+		{name: "existing property", nodeConfig: types.Configuration{"url": "http://${global.host}/api"}, want: nil},
+		{name: "existing property with spaces and dots", nodeConfig: types.Configuration{"db": "${ global.db.name }"}, want: nil},
+		{name: "not global reference", nodeConfig: types.Configuration{"v": "${metadata.port} ${vars.port}"}, want: nil},
+		{
+			name:       "missing property in node",
+			nodeConfig: types.Configuration{"url": "http://${global.host}:${global.port}"},
+			want:       []ValidateIssue{{Code: IssueGlobalPropertyAbsent, NodeId: "n1", Message: "global property=port is not found"}},
+		},
+		{
+			name:       "missing property in nested configuration",
+			nodeConfig: types.Configuration{"headers": map[string]interface{}{"token": "${global.token}"}},
+			want:       []ValidateIssue{{Code: IssueGlobalPropertyAbsent, NodeId: "n1", Message: "global property=token is not found"}},
+		},
+		{
+			name:        "missing property in chain",
+			chainConfig: types.Configuration{"vars": map[string]interface{}{"v": "${global.missing-key}"}},
+			want:        []ValidateIssue{{Code: IssueGlobalPropertyAbsent, NodeId: "", Message: "global property=missing-key is not found"}},
+		},
+	}
+	s := &RuleEngineService{config: config.Config{Global: types.Metadata{"host": "127.0.0.1", "db.name": "rulego"}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result ValidateResult
+			ruleChain := types.RuleChain{
+				RuleChain: types.RuleChainBaseInfo{Configuration: tt.chainConfig},
+				Metadata:  types.RuleMetadata{Nodes: []*types.RuleNode{{Id: "n1", Type: "restApiCall", Configuration: tt.nodeConfig}}},
+			}
+			s.validateGlobalRef(ruleChain, &result)
+			if !reflect.DeepEqual(result.Errors, tt.want) {
+				t.Errorf("errors = %+v, want %+v", result.Errors, tt.want)
+			}
+		})
+	}
+}
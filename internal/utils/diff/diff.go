@@ -0,0 +1,120 @@
+package diff
+
+import "strings"
+
+// 行差异类型
+const (
+	OpEqual  = "="
+	OpInsert = "+"
+	OpDelete = "-"
+)
+
+// maxMatrixSize 最长公共子序列矩阵最大大小，超过则把差异部分整体视为删除+新增
+const maxMatrixSize = 4 * 1024 * 1024
+
+// Line 行差异
+type Line struct {
+	// Op 差异类型 =/+/-
+	Op string `json:"op"`
+	// OldLine 旧文本行号，从1开始，新增行为0
+	OldLine int `json:"oldLine"`
+	// NewLine 新文本行号，从1开始，删除行为0
+	NewLine int `json:"newLine"`
+	// Text 行内容
+	Text string `json:"text"`
+}
+
+// Lines 按行比较两个文本，返回逐行差异
+func Lines(oldText, newText string) []Line {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	//去掉相同的前缀和后缀，减少比较范围
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var result []Line
+	for i := 0; i < prefix; i++ {
+		result = append(result, Line{Op: OpEqual, OldLine: i + 1, NewLine: i + 1, Text: a[i]})
+	}
+	result = append(result, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		oldIndex := len(a) - suffix + i
+		newIndex := len(b) - suffix + i
+		result = append(result, Line{Op: OpEqual, OldLine: oldIndex + 1, NewLine: newIndex + 1, Text: a[oldIndex]})
+	}
+	return result
+}
+
+// HasChange 是否有差异
+func HasChange(lines []Line) bool {
+	for _, item := range lines {
+		if item.Op != OpEqual {
+			return true
+		}
+	}
+	return false
+}
+
+// diffMiddle 使用最长公共子序列比较
+func diffMiddle(a, b []string, oldOffset, newOffset int) []Line {
+	var result []Line
+	n, m := len(a), len(b)
+	if n*m > maxMatrixSize {
+		for i, text := range a {
+			result = append(result, Line{Op: OpDelete, OldLine: oldOffset + i + 1, Text: text})
+		}
+		for j, text := range b {
+			result = append(result, Line{Op: OpInsert, NewLine: newOffset + j + 1, Text: text})
+		}
+		return result
+	}
+	// lcs[i][j] 表示 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		if a[i] == b[j] {
+			result = append(result, Line{Op: OpEqual, OldLine: oldOffset + i + 1, NewLine: newOffset + j + 1, Text: a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			result = append(result, Line{Op: OpDelete, OldLine: oldOffset + i + 1, Text: a[i]})
+			i++
+		} else {
+			result = append(result, Line{Op: OpInsert, NewLine: newOffset + j + 1, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: OpDelete, OldLine: oldOffset + i + 1, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: OpInsert, NewLine: newOffset + j + 1, Text: b[j]})
+	}
+	return result
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,120 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    []Line
+	}{
+		{name: "both empty", oldText: "", newText: "", want: nil},
+		{
+			name:    "equal",
+			oldText: "a\nb",
+			newText: "a\nb",
+			want: []Line{
+				{Op: OpEqual, OldLine: 1, NewLine: 1, Text: "a"},
+				{Op: OpEqual, OldLine: 2, NewLine: 2, Text: "b"},
+			},
+		},
+		{
+			name:    "trailing newline ignored",
+			oldText: "a\n",
+			newText: "a",
+			want:    []Line{{Op: OpEqual, OldLine: 1, NewLine: 1, Text: "a"}},
+		},
+		{
+			name:    "insert from empty",
+			oldText: "",
+			newText: "a\nb",
+			want: []Line{
+				{Op: OpInsert, NewLine: 1, Text: "a"},
+				{Op: OpInsert, NewLine: 2, Text: "b"},
+			},
+		},
+		{
+			name:    "delete all",
+			oldText: "a\nb",
+			newText: "",
+			want: []Line{
+				{Op: OpDelete, OldLine: 1, Text: "a"},
+				{Op: OpDelete, OldLine: 2, Text: "b"},
+			},
+		},
+		{
+			name:    "insert in middle",
+			oldText: "a\nc",
+			newText: "a\nb\nc",
+			want: []Line{
+				{Op: OpEqual, OldLine: 1, NewLine: 1, Text: "a"},
+				{Op: OpInsert, NewLine: 2, Text: "b"},
+				{Op: OpEqual, OldLine: 2, NewLine: 3, Text: "c"},
+			},
+		},
+		{
+			name:    "delete in middle",
+			oldText: "a\nb\nc",
+			newText: "a\nc",
+			want: []Line{
+				{Op: OpEqual, OldLine: 1, NewLine: 1, Text: "a"},
+				{Op: OpDelete, OldLine: 2, Text: "b"},
+				{Op: OpEqual, OldLine: 3, NewLine: 2, Text: "c"},
+			},
+		},
+		{
+			name:    "replace line",
+			oldText: "a\nb",
+			newText: "a\nx",
+			want: []Line{
+				{Op: OpEqual, OldLine: 1, NewLine: 1, Text: "a"},
+				{Op: OpDelete, OldLine: 2, Text: "b"},
+				{Op: OpInsert, NewLine: 2, Text: "x"},
+			},
+		},
+		{
+			name:    "longest common subsequence",
+			oldText: "a\nb\nc\nd",
+			newText: "b\nx\nd",
+			want: []Line{
+				{Op: OpDelete, OldLine: 1, Text: "a"},
+				{Op: OpEqual, OldLine: 2, NewLine: 1, Text: "b"},
+				{Op: OpDelete, OldLine: 3, Text: "c"},
+				{Op: OpInsert, NewLine: 2, Text: "x"},
+				{Op: OpEqual, OldLine: 4, NewLine: 3, Text: "d"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.oldText, tt.newText); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    bool
+	}{
+		{name: "both empty", oldText: "", newText: "", want: false},
+		{name: "equal", oldText: "a\nb", newText: "a\nb", want: false},
+		{name: "insert", oldText: "a", newText: "a\nb", want: true},
+		{name: "delete", oldText: "a\nb", newText: "a", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasChange(Lines(tt.oldText, tt.newText)); got != tt.want {
+				t.Errorf("HasChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
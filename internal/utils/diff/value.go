@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// OpChange 值修改
+const OpChange = "~"
+
+// Value 值差异
+type Value struct {
+	// Path 差异路径，例如：a.b[0]，空表示整个值
+	Path string `json:"path"`
+	// Op 差异类型 +/-/~
+	Op string `json:"op"`
+	// From 旧值，新增为空
+	From interface{} `json:"from,omitempty"`
+	// To 新值，删除为空
+	To interface{} `json:"to,omitempty"`
+}
+
+// JSON 比较两个json文本，返回字段级差异，任意一个不是合法json则按文本整体比较
+func JSON(oldText, newText string) []Value {
+	if oldText == newText {
+		return nil
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(oldText), &a) != nil || json.Unmarshal([]byte(newText), &b) != nil {
+		return []Value{{Op: OpChange, From: oldText, To: newText}}
+	}
+	return Values(a, b)
+}
+
+// Map 比较两个字符串map，返回key级差异
+func Map(a, b map[string]string) []Value {
+	var result []Value
+	for _, k := range sortedKeys(a, b) {
+		oldV, oldOk := a[k]
+		newV, newOk := b[k]
+		switch {
+		case !newOk:
+			result = append(result, Value{Path: k, Op: OpDelete, From: oldV})
+		case !oldOk:
+			result = append(result, Value{Path: k, Op: OpInsert, To: newV})
+		case oldV != newV:
+			result = append(result, Value{Path: k, Op: OpChange, From: oldV, To: newV})
+		}
+	}
+	return result
+}
+
+// Values 递归比较两个json反序列化后的值
+func Values(a, b interface{}) []Value {
+	return values("", a, b, nil)
+}
+
+func values(path string, a, b interface{}, result []Value) []Value {
+	switch oldV := a.(type) {
+	case map[string]interface{}:
+		if newV, ok := b.(map[string]interface{}); ok {
+			for _, k := range sortedKeys(oldV, newV) {
+				childPath := k
+				if path != "" {
+					childPath = path + "." + k
+				}
+				oldChild, oldOk := oldV[k]
+				newChild, newOk := newV[k]
+				switch {
+				case !newOk:
+					result = append(result, Value{Path: childPath, Op: OpDelete, From: oldChild})
+				case !oldOk:
+					result = append(result, Value{Path: childPath, Op: OpInsert, To: newChild})
+				default:
+					result = values(childPath, oldChild, newChild, result)
+				}
+			}
+			return result
+		}
+	case []interface{}:
+		if newV, ok := b.([]interface{}); ok {
+			for i := 0; i < len(oldV) || i < len(newV); i++ {
+				childPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(newV):
+					result = append(result, Value{Path: childPath, Op: OpDelete, From: oldV[i]})
+				case i >= len(oldV):
+					result = append(result, Value{Path: childPath, Op: OpInsert, To: newV[i]})
+				default:
+					result = values(childPath, oldV[i], newV[i], result)
+				}
+			}
+			return result
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		result = append(result, Value{Path: path, Op: OpChange, From: a, To: b})
+	}
+	return result
+}
+
+// sortedKeys 两个map所有key，升序
+func sortedKeys[V any](a, b map[string]V) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
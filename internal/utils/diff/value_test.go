@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    []Value
+	}{
+		{name: "same text", oldText: `{"a":1}`, newText: `{"a":1}`, want: nil},
+		{name: "same value different format", oldText: `{"a":1,"b":2}`, newText: `{ "b":2, "a":1 }`, want: nil},
+		{
+			name:    "invalid json",
+			oldText: `abc`,
+			newText: `{"a":1}`,
+			want:    []Value{{Op: OpChange, From: `abc`, To: `{"a":1}`}},
+		},
+		{
+			name:    "field changed",
+			oldText: `{"a":1,"b":"x"}`,
+			newText: `{"a":2,"b":"x"}`,
+			want:    []Value{{Path: "a", Op: OpChange, From: float64(1), To: float64(2)}},
+		},
+		{
+			name:    "field inserted and deleted",
+			oldText: `{"a":1}`,
+			newText: `{"b":true}`,
+			want: []Value{
+				{Path: "a", Op: OpDelete, From: float64(1)},
+				{Path: "b", Op: OpInsert, To: true},
+			},
+		},
+		{
+			name:    "nested field",
+			oldText: `{"a":{"b":{"c":"x"}}}`,
+			newText: `{"a":{"b":{"c":"y"}}}`,
+			want:    []Value{{Path: "a.b.c", Op: OpChange, From: "x", To: "y"}},
+		},
+		{
+			name:    "array items",
+			oldText: `{"a":[1,2,3]}`,
+			newText: `{"a":[1,4]}`,
+			want: []Value{
+				{Path: "a[1]", Op: OpChange, From: float64(2), To: float64(4)},
+				{Path: "a[2]", Op: OpDelete, From: float64(3)},
+			},
+		},
+		{
+			name:    "array of objects",
+			oldText: `[{"a":1}]`,
+			newText: `[{"a":1},{"b":2}]`,
+			want:    []Value{{Path: "[1]", Op: OpInsert, To: map[string]interface{}{"b": float64(2)}}},
+		},
+		{
+			name:    "type changed",
+			oldText: `{"a":{"b":1}}`,
+			newText: `{"a":"b"}`,
+			want:    []Value{{Path: "a", Op: OpChange, From: map[string]interface{}{"b": float64(1)}, To: "b"}},
+		},
+		{
+			name:    "root value changed",
+			oldText: `1`,
+			newText: `"1"`,
+			want:    []Value{{Op: OpChange, From: float64(1), To: "1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JSON(tt.oldText, tt.newText); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		name string
+		a    map[string]string
+		b    map[string]string
+		want []Value
+	}{
+		{name: "both nil", a: nil, b: nil, want: nil},
+		{name: "equal", a: map[string]string{"k": "v"}, b: map[string]string{"k": "v"}, want: nil},
+		{
+			name: "changes sorted by key",
+			a:    map[string]string{"c": "1", "a": "1", "b": "1"},
+			b:    map[string]string{"b": "2", "a": "1", "d": "1"},
+			want: []Value{
+				{Path: "b", Op: OpChange, From: "1", To: "2"},
+				{Path: "c", Op: OpDelete, From: "1"},
+				{Path: "d", Op: OpInsert, To: "1"},
+			},
+		},
+		{
+			name: "insert into nil",
+			a:    nil,
+			b:    map[string]string{"k": "v"},
+			want: []Value{{Path: "k", Op: OpInsert, To: "v"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Map(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
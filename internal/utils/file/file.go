@@ -1,6 +1,7 @@
 package file
 
 import (
+	"fmt"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -33,7 +34,11 @@ func parseTimestampFromFilename(filename string) (time.Time, error) {
 	// 使用filepath.Base获取文件名
 	lastPart := filepath.Base(filename)
 	timestampStr := strings.Split(lastPart, "_")[0]
-	return time.Parse("20060102150405000", timestampStr)
+	//{秒}{毫秒}，例如：20240601120000123
+	if len(timestampStr) != len("20060102150405000") {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %s", timestampStr)
+	}
+	return time.Parse("20060102150405.000", timestampStr[:14]+"."+timestampStr[14:])
 }
 
 // ByTimestamp 实现 sort.Interface 接口
@@ -0,0 +1,101 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath jsonPath表达式不合法
+var ErrInvalidPath = errors.New("invalid json path")
+
+// step 路径中的一段，key为空表示数组下标
+type step struct {
+	key   string
+	index int
+}
+
+// Path 编译后的jsonPath表达式
+// 支持：$、.key、['key']、[0]，不支持通配符、过滤器以及递归查找
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// Compile 编译jsonPath表达式，例如：$.items[0].name、$['a.b']
+func Compile(expr string) (*Path, error) {
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("%w: %s must start with $", ErrInvalidPath, expr)
+	}
+	s = s[1:]
+	var steps []step
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPath, expr)
+			}
+			steps = append(steps, step{key: s[:end], index: -1})
+			s = s[end:]
+		case '[':
+			//带引号的key可能包含]，从结束引号之后查找
+			start := 1
+			if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+				if quote := strings.IndexByte(s[2:], s[1]); quote >= 0 {
+					start = quote + 3
+				}
+			}
+			end := strings.IndexByte(s[start:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPath, expr)
+			}
+			end += start
+			token := s[1:end]
+			s = s[end+1:]
+			if len(token) >= 2 && (token[0] == '\'' || token[0] == '"') && token[len(token)-1] == token[0] {
+				steps = append(steps, step{key: token[1 : len(token)-1], index: -1})
+			} else if i, err := strconv.Atoi(token); err == nil && i >= 0 {
+				steps = append(steps, step{index: i})
+			} else {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPath, expr)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPath, expr)
+		}
+	}
+	return &Path{expr: expr, steps: steps}, nil
+}
+
+// Get 从json反序列化后的值中获取路径对应的值，路径不存在返回false
+func (p *Path) Get(v interface{}) (interface{}, bool) {
+	for _, item := range p.steps {
+		if item.index < 0 {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[item.key]; !ok {
+				return nil, false
+			}
+		} else {
+			list, ok := v.([]interface{})
+			if !ok || item.index >= len(list) {
+				return nil, false
+			}
+			v = list[item.index]
+		}
+	}
+	return v, true
+}
+
+// String 原始表达式
+func (p *Path) String() string {
+	return p.expr
+}
@@ -0,0 +1,97 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    []step
+		wantErr bool
+	}{
+		{name: "root", expr: "$", want: nil},
+		{name: "root with spaces", expr: " $.a ", want: []step{{key: "a", index: -1}}},
+		{name: "dot keys", expr: "$.a.b", want: []step{{key: "a", index: -1}, {key: "b", index: -1}}},
+		{name: "index", expr: "$.items[0]", want: []step{{key: "items", index: -1}, {index: 0}}},
+		{name: "nested index", expr: "$[1][2]", want: []step{{index: 1}, {index: 2}}},
+		{name: "single quoted key", expr: "$['a.b']", want: []step{{key: "a.b", index: -1}}},
+		{name: "double quoted key", expr: `$["a[0]"].c`, want: []step{{key: "a[0]", index: -1}, {key: "c", index: -1}}},
+		{name: "missing root", expr: "a.b", wantErr: true},
+		{name: "empty", expr: "", wantErr: true},
+		{name: "empty key", expr: "$..a", wantErr: true},
+		{name: "trailing dot", expr: "$.a.", wantErr: true},
+		{name: "unclosed bracket", expr: "$.a[0", wantErr: true},
+		{name: "unclosed quote", expr: "$['a]", wantErr: true},
+		{name: "text after closing quote", expr: "$['a'b]", wantErr: true},
+		{name: "negative index", expr: "$.a[-1]", wantErr: true},
+		{name: "wildcard", expr: "$.a[*]", wantErr: true},
+		{name: "unquoted key in brackets", expr: "$[a]", wantErr: true},
+		{name: "invalid char after root", expr: "$a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPath) {
+					t.Errorf("Compile(%q) error = %v, want ErrInvalidPath", tt.expr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(p.steps, tt.want) {
+				t.Errorf("Compile(%q) steps = %+v, want %+v", tt.expr, p.steps, tt.want)
+			}
+			if p.String() != tt.expr {
+				t.Errorf("String() = %q, want %q", p.String(), tt.expr)
+			}
+		})
+	}
+}
+
+func TestPathGet(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{"a":{"b":1},"items":[{"name":"x"},{"name":"y"}],"a.b":true,"n":null}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		expr   string
+		want   interface{}
+		wantOk bool
+	}{
+		{name: "root", expr: "$", want: data, wantOk: true},
+		{name: "nested key", expr: "$.a.b", want: float64(1), wantOk: true},
+		{name: "object", expr: "$.a", want: map[string]interface{}{"b": float64(1)}, wantOk: true},
+		{name: "array item", expr: "$.items[1].name", want: "y", wantOk: true},
+		{name: "quoted key with dot", expr: "$['a.b']", want: true, wantOk: true},
+		{name: "null value", expr: "$.n", want: nil, wantOk: true},
+		{name: "missing key", expr: "$.c", wantOk: false},
+		{name: "missing nested key", expr: "$.a.c", wantOk: false},
+		{name: "index out of range", expr: "$.items[2]", wantOk: false},
+		{name: "index on object", expr: "$.a[0]", wantOk: false},
+		{name: "key on array", expr: "$.items.name", wantOk: false},
+		{name: "key on scalar", expr: "$.a.b.c", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.expr, err)
+			}
+			got, ok := p.Get(data)
+			if ok != tt.wantOk {
+				t.Fatalf("Get(%q) ok = %v, want %v", tt.expr, ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+
+	jwtLib "github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// TokenTypeAccess 访问token
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh 刷新token
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrTokenInvalid   = errors.New("token is invalid")
+	ErrTokenTypeWrong = errors.New("token type is wrong")
+)
+
+// Claims 自定义声明
+type Claims struct {
+	// Username 用户名
+	Username string `json:"username"`
+	// TokenType token类型 access/refresh
+	TokenType string `json:"tokenType"`
+	// Version 签发时的用户凭证版本，修改密码或者删除用户后失效
+	Version string `json:"ver,omitempty"`
+	jwtLib.RegisteredClaims
+}
+
+// CreateToken 签发token，返回token和过期时间
+func CreateToken(secretKey []byte, issuer, username, version, tokenType string, expire time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expireAt := now.Add(expire)
+	claims := Claims{
+		Username:  username,
+		TokenType: tokenType,
+		Version:   version,
+		RegisteredClaims: jwtLib.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   username,
+			IssuedAt:  jwtLib.NewNumericDate(now),
+			NotBefore: jwtLib.NewNumericDate(now),
+			ExpiresAt: jwtLib.NewNumericDate(expireAt),
+		},
+	}
+	token, err := jwtLib.NewWithClaims(jwtLib.SigningMethodHS256, claims).SignedString(secretKey)
+	return token, expireAt, err
+}
+
+// ParseToken 校验token签名、有效期以及类型，返回声明
+func ParseToken(secretKey []byte, issuer, tokenStr, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwtLib.ParseWithClaims(tokenStr, claims, func(token *jwtLib.Token) (interface{}, error) {
+		return secretKey, nil
+	}, jwtLib.WithValidMethods([]string{jwtLib.SigningMethodHS256.Alg()}), jwtLib.WithIssuer(issuer))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.Username == "" {
+		return nil, ErrTokenInvalid
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrTokenTypeWrong
+	}
+	return claims, nil
+}
@@ -0,0 +1,30 @@
+-- 消息去重表，dedupe_store = sql 时使用，服务启动时也会自动创建
+create sequence msg_dedupe_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."msg_dedupe" (
+    "id" bigint NOT NULL DEFAULT nextval('msg_dedupe_seq'::regclass),
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "msg_id" varchar(128) COLLATE "pg_catalog"."default" NOT NULL,
+    "status" varchar(16) COLLATE "pg_catalog"."default",
+    "result" text DEFAULT null,
+    "err" text DEFAULT null,
+    "expire_at" timestamptz(6),
+    "created_at" timestamptz(6) NOT NULL DEFAULT now(),
+    CONSTRAINT "msg_dedupe_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."msg_dedupe" IS '消息去重表';
+
+CREATE UNIQUE INDEX msg_dedupe_owner_chain_msg_unique_idx ON msg_dedupe(owner, rule_chain_id, msg_id);
+CREATE INDEX idx_msg_dedupe_expire_at ON msg_dedupe(expire_at);
+
+COMMENT ON COLUMN "public"."msg_dedupe"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."msg_dedupe"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."msg_dedupe"."rule_chain_id" IS '规则ID';
+COMMENT ON COLUMN "public"."msg_dedupe"."msg_id" IS '消息ID';
+COMMENT ON COLUMN "public"."msg_dedupe"."status" IS '状态 processing/done';
+COMMENT ON COLUMN "public"."msg_dedupe"."result" IS '处理结果';
+COMMENT ON COLUMN "public"."msg_dedupe"."err" IS '错误信息';
+COMMENT ON COLUMN "public"."msg_dedupe"."expire_at" IS '过期时间';
+COMMENT ON COLUMN "public"."msg_dedupe"."created_at" IS '创建时间';
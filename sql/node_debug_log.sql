@@ -0,0 +1,34 @@
+-- 节点调试数据表，debug_store = sql 时使用，服务启动时也会自动创建
+create sequence node_debug_log_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."node_debug_log" (
+    "id" bigint NOT NULL DEFAULT nextval('node_debug_log_seq'::regclass),
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "node_id" varchar(64) COLLATE "pg_catalog"."default",
+    "msg_id" varchar(128) COLLATE "pg_catalog"."default",
+    "flow_type" varchar(16) COLLATE "pg_catalog"."default",
+    "ts" bigint,
+    "relation_type" varchar(128) COLLATE "pg_catalog"."default",
+    "err" text DEFAULT null,
+    "mocked" boolean NOT NULL DEFAULT false,
+    "msg" text DEFAULT null,
+    CONSTRAINT "node_debug_log_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."node_debug_log" IS '节点调试数据表';
+
+CREATE INDEX node_debug_log_owner_chain_ts_idx ON node_debug_log(owner, rule_chain_id, ts);
+CREATE INDEX node_debug_log_msg_id_idx ON node_debug_log(msg_id);
+
+COMMENT ON COLUMN "public"."node_debug_log"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."node_debug_log"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."node_debug_log"."rule_chain_id" IS '规则ID';
+COMMENT ON COLUMN "public"."node_debug_log"."node_id" IS '节点ID';
+COMMENT ON COLUMN "public"."node_debug_log"."msg_id" IS '消息ID';
+COMMENT ON COLUMN "public"."node_debug_log"."flow_type" IS '流向 IN/OUT/Log';
+COMMENT ON COLUMN "public"."node_debug_log"."ts" IS '发生时间，毫秒';
+COMMENT ON COLUMN "public"."node_debug_log"."relation_type" IS '关系';
+COMMENT ON COLUMN "public"."node_debug_log"."err" IS '错误信息';
+COMMENT ON COLUMN "public"."node_debug_log"."mocked" IS '是否模拟节点';
+COMMENT ON COLUMN "public"."node_debug_log"."msg" IS '消息json';
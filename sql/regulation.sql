@@ -3,6 +3,7 @@ create sequence regulation_seq increment by 1 minvalue 1 no maxvalue start with
 
 CREATE TABLE "public"."regulation" (
     "id" bigint NOT NULL DEFAULT nextval('regulation_seq'::regclass),
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL DEFAULT '',
     "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default",
     "rule_config" text DEFAULT null,
     "created_at" timestamptz(6) NOT NULL DEFAULT now(),
@@ -15,9 +16,10 @@ CREATE TABLE "public"."regulation" (
 COMMENT ON TABLE "public"."regulation" IS '规则配置表';
 
 -- CREATE INDEX "idx_regulation_rule_chain_idd" ON "public"."regulation" ("rule_chain_id");
-CREATE UNIQUE INDEX regulation_rule_chain_id_unique_idx ON regulation(rule_chain_id);
+CREATE UNIQUE INDEX regulation_owner_rule_chain_id_unique_idx ON regulation(owner, rule_chain_id);
 
 COMMENT ON COLUMN "public"."regulation"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."regulation"."owner" IS '所属用户';
 COMMENT ON COLUMN "public"."regulation"."rule_chain_id" IS '规则ID';
 COMMENT ON COLUMN "public"."regulation"."rule_config" IS '规则配置信息';
 COMMENT ON COLUMN "public"."regulation"."created_at" IS '创建时间';
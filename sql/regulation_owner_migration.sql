@@ -0,0 +1,11 @@
+-- 规则链增加所属用户，已有数据库执行该脚本升级
+-- 历史数据的owner为空，服务启动时会自动迁移到默认用户(default_username)
+ALTER TABLE "public"."regulation" ADD COLUMN IF NOT EXISTS "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "public"."regulation"."owner" IS '所属用户';
+
+-- 唯一索引改为(owner, rule_chain_id)
+DROP INDEX IF EXISTS regulation_rule_chain_id_unique_idx;
+-- 清理软删除的记录，否则会占用唯一索引
+DELETE FROM "public"."regulation" WHERE "deleted_at" IS NOT NULL;
+CREATE UNIQUE INDEX regulation_owner_rule_chain_id_unique_idx ON regulation(owner, rule_chain_id);
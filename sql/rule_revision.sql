@@ -0,0 +1,27 @@
+-- 规则链历史版本表，服务启动时也会自动创建
+create sequence rule_revision_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."rule_revision" (
+    "id" bigint NOT NULL DEFAULT nextval('rule_revision_seq'::regclass),
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "version" bigint NOT NULL,
+    "rule_config" text DEFAULT null,
+    "author" varchar(64) COLLATE "pg_catalog"."default",
+    "message" varchar(512) COLLATE "pg_catalog"."default",
+    "created_at" timestamptz(6) NOT NULL DEFAULT now(),
+    CONSTRAINT "rule_revision_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."rule_revision" IS '规则链历史版本表';
+
+CREATE UNIQUE INDEX rule_revision_owner_chain_version_unique_idx ON rule_revision(owner, rule_chain_id, version);
+
+COMMENT ON COLUMN "public"."rule_revision"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."rule_revision"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."rule_revision"."rule_chain_id" IS '规则ID';
+COMMENT ON COLUMN "public"."rule_revision"."version" IS '版本号';
+COMMENT ON COLUMN "public"."rule_revision"."rule_config" IS '规则配置信息';
+COMMENT ON COLUMN "public"."rule_revision"."author" IS '修改人';
+COMMENT ON COLUMN "public"."rule_revision"."message" IS '修改说明';
+COMMENT ON COLUMN "public"."rule_revision"."created_at" IS '创建时间';
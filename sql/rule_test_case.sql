@@ -0,0 +1,33 @@
+-- 规则链测试用例表，服务启动时也会自动创建
+create sequence rule_test_case_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."rule_test_case" (
+    "id" bigint NOT NULL DEFAULT nextval('rule_test_case_seq'::regclass),
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "name" varchar(128) COLLATE "pg_catalog"."default" NOT NULL,
+    "msg_type" varchar(128) COLLATE "pg_catalog"."default",
+    "data" text DEFAULT null,
+    "metadata" text DEFAULT null,
+    "mocks" text DEFAULT null,
+    "expect" text DEFAULT null,
+    "timeout" bigint,
+    "updated_at" timestamptz(6),
+    CONSTRAINT "rule_test_case_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."rule_test_case" IS '规则链测试用例表';
+
+CREATE UNIQUE INDEX rule_test_case_owner_chain_name_unique_idx ON rule_test_case(owner, rule_chain_id, name);
+
+COMMENT ON COLUMN "public"."rule_test_case"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."rule_test_case"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."rule_test_case"."rule_chain_id" IS '规则ID';
+COMMENT ON COLUMN "public"."rule_test_case"."name" IS '用例名称';
+COMMENT ON COLUMN "public"."rule_test_case"."msg_type" IS '输入消息类型';
+COMMENT ON COLUMN "public"."rule_test_case"."data" IS '输入消息内容';
+COMMENT ON COLUMN "public"."rule_test_case"."metadata" IS '输入消息元数据，json';
+COMMENT ON COLUMN "public"."rule_test_case"."mocks" IS '模拟节点，json';
+COMMENT ON COLUMN "public"."rule_test_case"."expect" IS '期望结果，json';
+COMMENT ON COLUMN "public"."rule_test_case"."timeout" IS '超时时间，毫秒';
+COMMENT ON COLUMN "public"."rule_test_case"."updated_at" IS '更新时间';
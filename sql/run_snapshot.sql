@@ -0,0 +1,65 @@
+-- 规则链运行快照表，snapshot_store = sql 时使用，服务启动时也会自动创建
+create sequence run_snapshot_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."run_snapshot" (
+    "id" bigint NOT NULL DEFAULT nextval('run_snapshot_seq'::regclass),
+    "snapshot_id" varchar(128) COLLATE "pg_catalog"."default" NOT NULL,
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "msg_id" varchar(128) COLLATE "pg_catalog"."default",
+    "msg_type" varchar(128) COLLATE "pg_catalog"."default",
+    "status" varchar(16) COLLATE "pg_catalog"."default",
+    "start_ts" bigint,
+    "end_ts" bigint,
+    "replay_of" varchar(128) COLLATE "pg_catalog"."default",
+    "msg_text" text DEFAULT null,
+    "data" text DEFAULT null,
+    "size" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz(6) NOT NULL DEFAULT now(),
+    CONSTRAINT "run_snapshot_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."run_snapshot" IS '规则链运行快照表';
+
+CREATE UNIQUE INDEX run_snapshot_snapshot_id_unique_idx ON run_snapshot(snapshot_id);
+CREATE INDEX run_snapshot_owner_chain_start_idx ON run_snapshot(owner, rule_chain_id, start_ts);
+CREATE INDEX run_snapshot_owner_start_idx ON run_snapshot(owner, start_ts);
+CREATE INDEX run_snapshot_msg_id_idx ON run_snapshot(msg_id);
+CREATE INDEX run_snapshot_status_idx ON run_snapshot(status);
+CREATE INDEX run_snapshot_end_ts_idx ON run_snapshot(end_ts);
+CREATE INDEX run_snapshot_replay_of_idx ON run_snapshot(replay_of);
+
+COMMENT ON COLUMN "public"."run_snapshot"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."run_snapshot"."snapshot_id" IS '快照ID';
+COMMENT ON COLUMN "public"."run_snapshot"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."run_snapshot"."rule_chain_id" IS '规则ID';
+COMMENT ON COLUMN "public"."run_snapshot"."msg_id" IS '消息ID';
+COMMENT ON COLUMN "public"."run_snapshot"."msg_type" IS '消息类型';
+COMMENT ON COLUMN "public"."run_snapshot"."status" IS '状态 succeeded/failed';
+COMMENT ON COLUMN "public"."run_snapshot"."start_ts" IS '开始时间，毫秒';
+COMMENT ON COLUMN "public"."run_snapshot"."end_ts" IS '结束时间，毫秒';
+COMMENT ON COLUMN "public"."run_snapshot"."replay_of" IS '重放的原运行快照ID';
+COMMENT ON COLUMN "public"."run_snapshot"."msg_text" IS '各节点输入输出消息内容和元数据，用于关键字查询';
+COMMENT ON COLUMN "public"."run_snapshot"."data" IS '快照内容json';
+COMMENT ON COLUMN "public"."run_snapshot"."size" IS '快照内容字节数';
+COMMENT ON COLUMN "public"."run_snapshot"."created_at" IS '创建时间';
+
+-- 运行快照执行过的节点表，用于按节点ID查询运行快照
+create sequence run_snapshot_node_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."run_snapshot_node" (
+    "id" bigint NOT NULL DEFAULT nextval('run_snapshot_node_seq'::regclass),
+    "snapshot_id" varchar(128) COLLATE "pg_catalog"."default" NOT NULL,
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "node_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    CONSTRAINT "run_snapshot_node_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."run_snapshot_node" IS '运行快照执行过的节点表';
+
+CREATE INDEX run_snapshot_node_snapshot_id_idx ON run_snapshot_node(snapshot_id);
+CREATE INDEX run_snapshot_node_owner_node_idx ON run_snapshot_node(owner, node_id);
+
+COMMENT ON COLUMN "public"."run_snapshot_node"."snapshot_id" IS '快照ID';
+COMMENT ON COLUMN "public"."run_snapshot_node"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."run_snapshot_node"."node_id" IS '节点ID';
@@ -0,0 +1,6 @@
+-- 运行快照增加重放的原运行快照ID，已有数据库执行该脚本升级
+ALTER TABLE "public"."run_snapshot" ADD COLUMN IF NOT EXISTS "replay_of" varchar(128) COLLATE "pg_catalog"."default";
+
+COMMENT ON COLUMN "public"."run_snapshot"."replay_of" IS '重放的原运行快照ID';
+
+CREATE INDEX IF NOT EXISTS run_snapshot_replay_of_idx ON run_snapshot(replay_of);
@@ -0,0 +1,7 @@
+-- 运行快照增加字节数，用于按字节数清理，已有数据库执行该脚本升级
+-- 服务启动时也会自动计算历史数据的字节数
+ALTER TABLE "public"."run_snapshot" ADD COLUMN IF NOT EXISTS "size" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "public"."run_snapshot"."size" IS '快照内容字节数';
+
+UPDATE "public"."run_snapshot" SET "size" = OCTET_LENGTH("data") WHERE "size" = 0 AND "data" IS NOT NULL;
@@ -0,0 +1,31 @@
+-- 定时任务触发记录表，服务启动时也会自动创建
+create sequence schedule_firing_seq increment by 1 minvalue 1 no maxvalue start with 1;
+
+CREATE TABLE "public"."schedule_firing" (
+    "id" bigint NOT NULL DEFAULT nextval('schedule_firing_seq'::regclass),
+    "owner" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "rule_chain_id" varchar(64) COLLATE "pg_catalog"."default" NOT NULL,
+    "schedule_id" varchar(64) COLLATE "pg_catalog"."default",
+    "cron" varchar(128) COLLATE "pg_catalog"."default",
+    "msg_id" varchar(64) COLLATE "pg_catalog"."default",
+    "status" varchar(16) COLLATE "pg_catalog"."default",
+    "err" text DEFAULT null,
+    "fire_time" timestamptz(6),
+    "end_time" timestamptz(6),
+    CONSTRAINT "schedule_firing_pkey" PRIMARY KEY ("id")
+);
+
+COMMENT ON TABLE "public"."schedule_firing" IS '定时任务触发记录表';
+
+CREATE INDEX schedule_firing_owner_chain_idx ON schedule_firing(owner, rule_chain_id);
+
+COMMENT ON COLUMN "public"."schedule_firing"."id" IS '主键ID';
+COMMENT ON COLUMN "public"."schedule_firing"."owner" IS '所属用户';
+COMMENT ON COLUMN "public"."schedule_firing"."rule_chain_id" IS '规则ID';
+COMMENT ON COLUMN "public"."schedule_firing"."schedule_id" IS '定时任务ID';
+COMMENT ON COLUMN "public"."schedule_firing"."cron" IS 'cron表达式';
+COMMENT ON COLUMN "public"."schedule_firing"."msg_id" IS '触发消息ID';
+COMMENT ON COLUMN "public"."schedule_firing"."status" IS '状态 running/succeeded/failed';
+COMMENT ON COLUMN "public"."schedule_firing"."err" IS '错误信息';
+COMMENT ON COLUMN "public"."schedule_firing"."fire_time" IS '触发时间';
+COMMENT ON COLUMN "public"."schedule_firing"."end_time" IS '执行结束时间';